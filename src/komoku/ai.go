@@ -11,6 +11,9 @@ package komoku
 import (
     "runtime"
     "fmt"
+    "math"
+    "os"
+    "rand"
     "time"
 )
 
@@ -47,6 +50,25 @@ func init() {
     averageGameLength[25] = 791
 }
 
+// Returns the average length of a random game on a board with 'width' columns and 'height' rows. For
+// rectangular boards and sizes which are not covered by averageGameLength, a rough estimate is returned.
+func expectedGameLength(width, height int) int {
+    if l, ok := averageGameLength[width]; ok && width == height {
+        return l
+    }
+    return width*height*3/2
+}
+
+// ################################################################################
+// ########################### mercy rule #########################################
+// ################################################################################
+
+// These constants determine what the mercy rule compares.
+const (
+    MercyStoneDifference = iota // compare the number of stones on the board
+    MercyCaptureDifference // compare the number of captured stones
+)
+
 // ################################################################################
 // ########################### AI struct ##########################################
 // ################################################################################
@@ -56,6 +78,33 @@ type AI struct {
     numThinkers int // number of thinking goroutines
     runThinkers bool
     thinkerFinished[]chan bool // the thinkers answer here when they are finished
+    mercyThreshold int // a playout is decided as soon as one color leads by this many stones. 0 disables the mercy rule
+    mercyMode int // one of Mercy{Stone,Capture}Difference
+    moveCapFactor float // playouts end after moveCapFactor*expectedGameLength moves. 0 disables the move cap
+    discardCappedPlayouts bool // if true, playouts which hit the move cap are discarded, otherwise they are scored
+    stats *SearchStatistics
+    printStatistics bool // if true, the statistics are written to stderr as JSON after each generated move
+    patterns *PatternWeights // if not nil, playouts and priors are based on these weights
+    policy *patternPolicy // the playout policy of patterns, which keeps its strengths from one playout move to the next
+    heavyLadders bool // if true, playouts answer ataris and capture ladders, see Board.ladderReply
+    fastPlayouts bool // if true, playouts run on a FastBoard, see playoutBoard
+    playout *Board // the board which is filled again for every playout, see playoutBoard
+    fastPlayout *FastBoard // the same for fast playouts
+    solveLifeAndDeath bool // if true, the life-and-death solver overrides the search around the last move
+    selectionTemperature float // if > 0, the first temperatureMoves moves are drawn at random, see selectMove
+    temperatureMoves int
+    selectionEpsilon float // if > 0, moves within this winning percentage of the best one are drawn at random
+    rand *rand.Rand
+    symmetries []int // the symmetries of the position at symmetryNode on symmetryBoard with symmetryHash after
+                     // symmetryMoves moves, see rootSymmetries
+    symmetryNode *TreeNode
+    symmetryBoard *Board
+    symmetryHash uint64
+    symmetryMoves int
+    status []StoneStatus // the status of the stones on statusBoard with statusHash after statusMoves moves, see FinalStatus
+    statusBoard *Board
+    statusHash uint64
+    statusMoves int
 }
 
 // ##################### AI methods ##########################
@@ -118,32 +167,140 @@ func (a *AI) dbgGenMove(color Color, timeToThink int64) Vertex {
 }
 
 // Determines the best move for the player 'color 'on the current board based on the current statistics. 
-// Returns its pos and its winning percentage.
+// Returns its pos and its winning percentage. Symmetric moves share the child of their canonical representative
+// (see runSimulation), which is a move on the current board as well, so it can be played without transforming it.
+// Its subtree stays valid then.
 func (a *AI) findBestMove(color Color) (bestPos int, winPercentage float) {
     winPercentage  = -1.0
     bestPos = -1
+    bestRank := -1
+    board := a.environment.Game.Board
+    // drops the duplicates of symmetric moves if no simulation has run since the top node changed
+    a.rootSymmetries()
+    var meanStrength float
+    if a.patterns != nil {
+        meanStrength = a.patterns.meanMoveStrength(board, color)
+    }
     for pos, childNode := range a.topNode.children {
         // We want to discard moves whose 'pos' is not legal. It is possible that topNode has a child node
         // pointing to a now illegal move (this move might have been legal when the simulation creating it was
-        // run), but we surely do not want to consider these moves for playing...
-        if a.environment.Game.Board.IsLegalMove(pos, color) {
-            simulations := childNode.NodeInfo.simulations
-            var wonByColor int
-            if color == Black {
-                wonByColor = childNode.NodeInfo.wonByBlack
-            } else {
-                wonByColor = childNode.NodeInfo.wonByWhite
-            }
-            var p float = float(wonByColor)/float(simulations)
-            if p > winPercentage {
+        // run), but we surely do not want to consider these moves for playing... Passes (pos -1) are not
+        // considered either.
+        if pos >= 0 && board.IsLegalMove(pos, color) {
+            p, rank := a.moveValue(board, pos, childNode, color, meanStrength)
+            if rank > bestRank || (rank == bestRank && p > winPercentage) {
                 winPercentage = p
                 bestPos = pos
+                bestRank = rank
             }
         }
     }
+    if a.solveLifeAndDeath && bestRank < 2 {
+        if pos, found := a.lifeAndDeathMove(color); found {
+            bestPos = pos
+        }
+    }
     return
 }
 
+// Returns the winning percentage of the move of 'color' at 'pos', whose statistics are in 'childNode', including
+// its prior. 'rank' is 2 for proven wins, 0 for proven losses and 1 otherwise: proven wins are always preferred,
+// proven losses are only played if there is nothing else.
+func (a *AI) moveValue(board *Board, pos int, childNode *TreeNode, color Color, meanStrength float) (p float, rank int) {
+    simulations := float(childNode.NodeInfo.simulations)
+    var wonByColor float
+    if color == Black {
+        wonByColor = float(childNode.NodeInfo.wonByBlack)
+    } else {
+        wonByColor = float(childNode.NodeInfo.wonByWhite)
+    }
+    priorWins, priorSimulations := a.movePrior(board, pos, color, meanStrength)
+    wonByColor += priorWins
+    simulations += priorSimulations
+    p = wonByColor/simulations
+    rank = 1
+    if childNode.proven {
+        if childNode.provenWinner == color {
+            rank = 2
+        } else {
+            rank = 0
+        }
+    }
+    return
+}
+
+// Chooses the move which 'color' plays. This is the best move (see findBestMove) unless a randomized selection
+// is enabled by SetMoveSelection: during the first moves of the game, a move is drawn with a probability
+// proportional to (simulations/maxSimulations)^(1/temperature), where maxSimulations is the number of simulations
+// of the most simulated move, later uniformly. If epsilon is positive, only the moves whose
+// winning percentage is within epsilon of the best one are drawn, also during the first moves. Proven wins and
+// losses are never subject to chance. Returns -1 if there is no move.
+func (a *AI) selectMove(color Color) int {
+    bestPos, bestP := a.findBestMove(color)
+    if bestPos < 0 {
+        return bestPos
+    }
+    if child, ok := a.topNode.children[bestPos]; ok && child.proven {
+        return bestPos
+    }
+    useTemperature := a.selectionTemperature > 0 && len(a.environment.Game.sequence) < a.temperatureMoves
+    if !useTemperature && a.selectionEpsilon <= 0 {
+        return bestPos
+    }
+
+    board := a.environment.Game.Board
+    var meanStrength float
+    if a.patterns != nil {
+        meanStrength = a.patterns.meanMoveStrength(board, color)
+    }
+    candidates := make([]int, 0, len(a.topNode.children))
+    weights := make([]float64, 0, len(a.topNode.children))
+    maxSimulations := 0
+    for _, childNode := range a.topNode.children {
+        if childNode.NodeInfo.simulations > maxSimulations {
+            maxSimulations = childNode.NodeInfo.simulations
+        }
+    }
+    total := 0.0
+    for pos, childNode := range a.topNode.children {
+        if pos < 0 || !board.IsLegalMove(pos, color) {
+            continue
+        }
+        p, rank := a.moveValue(board, pos, childNode, color, meanStrength)
+        if rank == 0 {
+            continue
+        }
+        if a.selectionEpsilon > 0 && p < bestP - a.selectionEpsilon {
+            continue
+        }
+        weight := 1.0
+        if useTemperature {
+            // relative to the most simulated move, so that low temperatures do not overflow
+            weight = math.Pow(float64(childNode.NodeInfo.simulations) / float64(maxSimulations),
+                              1.0/float64(a.selectionTemperature))
+        }
+        if weight <= 0.0 {
+            continue
+        }
+        candidates = candidates[0:len(candidates)+1]
+        candidates[len(candidates)-1] = pos
+        weights = weights[0:len(weights)+1]
+        weights[len(weights)-1] = weight
+        total += weight
+    }
+    if total <= 0.0 {
+        return bestPos
+    }
+    r := a.rand.Float64() * total
+    for i, weight := range weights {
+        r -= weight
+        if r < 0.0 {
+            return candidates[i]
+        }
+    }
+    return candidates[len(candidates)-1]
+}
+
 // Generate a move using the current statistics as a guide to the best move
 // and play this move.
 func (a *AI) GenMove(color Color) Vertex {
@@ -162,7 +319,7 @@ func (a *AI) genMove(color Color, timeToThink int64) Vertex {
 
 
     // find the best move
-    bestPos, _ := a.findBestMove(color)
+    bestPos := a.selectMove(color)
 
     // remove all nodes belonging to the other moves (i.e. not the best move)
     for pos, _ := range a.topNode.children {
@@ -172,26 +329,89 @@ func (a *AI) genMove(color Color, timeToThink int64) Vertex {
         }
     }
 
-    // play the best move
+    // play the best move, or pass if there is none
+    if bestPos < 0 {
+        a.PlayPass(color)
+        a.reportStatistics()
+        runtime.GC()
+        return *NewVertexByInts(0, 0, true)
+    }
     bestX, bestY := a.environment.Game.Board.posToXY(bestPos)
     a.PlayMove(bestX, bestY, color)
+    a.reportStatistics()
 
 
     runtime.GC()
 
-    // TODO: this never plays pass yet
     return *NewVertexByInts(bestX, bestY, false)
 }
 
+// Runs exactly 'simulations' simulations in the calling goroutine, then plays the best move for 'color' and
+// returns it. If no legal move has been simulated, 'color' passes. Unlike genMove, the result does not depend
+// on the speed of the machine, so this is used for self-play, e.g. by the Tuner.
+func (a *AI) genMoveBySimulations(color Color, simulations int) Vertex {
+    defer a.startThinking(a.stopThinking())
+    for i := 0; i < simulations; i++ {
+        a.runSimulation()
+    }
+    bestPos := a.selectMove(color)
+    for pos, _ := range a.topNode.children {
+        if pos != bestPos {
+            a.topNode.children[pos].Clear()
+            a.topNode.children[pos] = nil, false
+        }
+    }
+    if bestPos < 0 {
+        a.playPass(color)
+        a.reportStatistics()
+        return *NewVertexByInts(0, 0, true)
+    }
+    bestX, bestY := a.environment.Game.Board.posToXY(bestPos)
+    a.playMove(bestX, bestY, color)
+    a.reportStatistics()
+    return *NewVertexByInts(bestX, bestY, false)
+}
+
+// Looks for an urgent life-and-death move of 'color' around the last move of the opponent: a move which saves
+// an own group that would die otherwise, or a move which kills the group of the last move. The regions are
+// read by Board.SolveLifeAndDeath. 'found' is false if there is no such move.
+func (a *AI) lifeAndDeathMove(color Color) (pos int, found bool) {
+    board := a.environment.Game.Board
+    last, _ := board.LastMoves()
+    if last < 0 || board.fields[last] == nil || board.fields[last].Color == color {
+        return 0, false
+    }
+    maxNodes := paramSolverNodes.Int()
+    // first the own groups which touch the last move...
+    candidates := board.adjacentEnemyGroups(board.fields[last])
+    // ...then the group of the last move itself
+    candidates.Push(board.fields[last])
+    for _, grp := range candidates {
+        target := grp.FirstStone()
+        // the move is urgent if the opponent would win the fight when moving first
+        if result, _, _ := board.SolveLifeAndDeath(target, !color, maxNodes); result != SolveWin {
+            continue
+        }
+        result, line, _ := board.SolveLifeAndDeath(target, color, maxNodes)
+        if result == SolveWin && len(line) > 0 && line[0] >= 0 && board.IsLegalMove(line[0], color) {
+            return line[0], true
+        }
+    }
+    return 0, false
+}
 
 // Thinks until a.runThinker is false, and sends true to a.thinkerFinished[index] when finished
 func (a *AI) makeThinker(index int) {
     //simuls := 0
+    var busy int64
     for a.runThinkers {
+        start := time.Nanoseconds()
         a.runSimulation()
+        busy += time.Nanoseconds() - start
         //simuls++
     }
     //fmt.Printf("%d simuls in one thinker\n", simuls)
+    a.stats.thinkerBusyNs += busy
     a.thinkerFinished[index] <- true
 }
 
@@ -226,56 +446,543 @@ func (a *AI) playMove(x,y int, color Color) (err Error) {
     return
 }
 
+// Play a pass on the board
+func (a *AI) PlayPass(color Color) {
+    defer a.startThinking(a.stopThinking())
+    a.playPass(color)
+}
+
+// As playMove, for a pass.
+func (a *AI) playPass(color Color) {
+    a.environment.Game.PlayPass(color)
+    a.topNode = a.topNode.ChildNode(-1)
+}
+
+// Takes back the last move or pass of the game, see Game.Undo. The search tree starts afresh, because it only
+// holds statistics for the position after the move, and the cached symmetries and stone statuses are dropped.
+func (a *AI) Undo() (err Error) {
+    defer a.startThinking(a.stopThinking())
+    if err = a.environment.Game.Undo(); err != nil {
+        return err
+    }
+    a.clearTree()
+    return nil
+}
+
+// Starts a new game on 'b', see Environment.SetBoard. The search tree and the cached symmetries and stone
+// statuses are dropped, like by Undo.
+func (a *AI) SetBoard(b *Board) {
+    defer a.startThinking(a.stopThinking())
+    a.environment.SetBoard(b)
+    a.clearTree()
+}
+
+// Starts the search tree afresh and drops the cached symmetries and stone statuses. The thinkers must not be
+// running.
+func (a *AI) clearTree() {
+    a.topNode = NewTreeNode(nil)
+    a.symmetryNode, a.symmetryBoard = nil, nil
+    a.status, a.statusBoard = nil, nil
+}
+
+// Returns the lead of black which the mercy rule compares, see mercyMode. 'stones{Black,White}' are the
+// numbers of stones on the board.
+func (a *AI) mercyDifference(board GoBoard, stonesBlack, stonesWhite int) int {
+    if a.mercyMode == MercyCaptureDifference {
+        prisonersBlack, prisonersWhite := board.numberOfPrisoners()
+        // black captured the white prisoners and vice versa
+        return prisonersWhite - prisonersBlack
+    }
+    return stonesBlack - stonesWhite
+}
+
+// Returns the winner of a playout according to the mercy rule. 'decided' is false if the
+// mercy rule does not apply (yet). 'stones{Black,White}' are the numbers of stones on the board and
+// 'rootDifference' is the mercyDifference at the start of the playout, so that only the lead gained
+// in the playout counts, not handicap stones or earlier captures.
+func (a *AI) mercyWinner(board GoBoard, stonesBlack, stonesWhite, rootDifference int) (winner Color, decided bool) {
+    if a.mercyThreshold <= 0 {
+        return Black, false
+    }
+    diff := a.mercyDifference(board, stonesBlack, stonesWhite) - rootDifference
+    if diff >= a.mercyThreshold {
+        return Black, true
+    }
+    if -diff >= a.mercyThreshold {
+        return White, true
+    }
+    return Black, false
+}
+
+// Returns the prior of a move of 'color' at 'pos' on 'board' as a number of virtual wins and simulations.
+// 'meanStrength' is the average strength of the moves according to a.patterns, if there are patterns.
+func (a *AI) movePrior(board *Board, pos int, color Color, meanStrength float) (wins, simulations float) {
+    if a.patterns != nil {
+        // The prior counts as paramPriorEquivalence simulations. A move as strong as the average
+        // move gets a prior win rate of 50%.
+        strength := a.patterns.MoveStrength(board, pos, color)
+        priorEquivalence := paramPriorEquivalence.Float()
+        simulations += priorEquivalence
+        wins += priorEquivalence * strength / (strength + meanStrength)
+    }
+    if ladderPrior := paramLadderPrior.Float(); ladderPrior > 0 {
+        // don't extend from a dead ladder, but capture in ladders
+        if board.isDeadLadderExtension(pos, color) {
+            simulations += ladderPrior
+        } else if board.isLadderCapture(pos, color) {
+            wins += ladderPrior
+            simulations += ladderPrior
+        }
+    }
+    return
+}
+
+// Plays one move of a playout on 'playoutBoard': a ladder reply if heavy ladders are enabled and there is one,
+// otherwise the vital point of a nakade shape, a move of the pattern policy or a uniformly random move. Ladders,
+// nakade and patterns need a Board, other boards only play their own random moves.
+func (a *AI) playPlayoutMove(playoutBoard GoBoard, color Color) Vertex {
+    board, ok := playoutBoard.(*Board)
+    if !ok {
+        return playoutBoard.PlayRandomMove(color)
+    }
+    if a.heavyLadders {
+        if pos, found := board.ladderReply(color); found && board.playMoveByPos(pos, color) == nil {
+            x, y := board.posToXY(pos)
+            return *NewVertexByInts(x, y, false)
+        }
+    }
+    if a.patterns != nil {
+        // PlayRandomMove looks for nakade itself
+        if pos, found := board.nakadeMove(color); found && board.playMoveByPos(pos, color) == nil {
+            x, y := board.posToXY(pos)
+            return *NewVertexByInts(x, y, false)
+        }
+        return a.policy.PlayMove(board, color)
+    }
+    return board.PlayRandomMove(color)
+}
+
+// Returns a copy of the current board to play a playout on: a FastBoard if fast playouts are enabled,
+// otherwise a Board which checks superko only in the window of the last paramSuperkoPlayoutWindow positions.
+// The board is the same for every playout, it is only filled again with the current position, see copyInto
+// and FastBoard.fill. The FastBoard draws its moves from the random generator of the AI. This relies on a
+// single thinker, see NewAI.
+func (a *AI) playoutBoard() GoBoard {
+    root := a.environment.Game.Board
+    if a.fastPlayouts {
+        if a.fastPlayout == nil || a.fastPlayout.width != root.width || a.fastPlayout.height != root.height {
+            a.fastPlayout = NewFastBoard(root)
+            a.fastPlayout.rand = a.rand
+        } else {
+            a.fastPlayout.fill(root)
+        }
+        return a.fastPlayout
+    }
+    if a.playout == nil || a.playout.width != root.width || a.playout.height != root.height {
+        a.playout = root.Copy()
+    } else {
+        root.copyInto(a.playout)
+    }
+    a.playout.SetSuperko(a.playout.Superko(), paramSuperkoPlayoutWindow.Int())
+    return a.playout
+}
+
+// Returns the number of moves (counted from the start of the playout) after which a playout
+// is stopped. 0 means that playouts are not capped. Playouts on a FastBoard are always capped, by
+// paramFastPlayoutMoveCap if the move cap is disabled, because they could cycle forever without superko.
+func (a *AI) playoutMoveCap() int {
+    factor := a.moveCapFactor
+    if factor <= 0 {
+        if !a.fastPlayouts {
+            return 0
+        }
+        factor = paramFastPlayoutMoveCap.Float()
+    }
+    board := a.environment.Game.Board
+    return int(factor * float(expectedGameLength(board.Width(), board.Height())))
+}
+
+// Propagates the proof of 'node', which is reached from the top node by 'moves' (-1 denotes a pass), towards
+// the top node by the minimax rules: a position is won for the player to move if one of its moves is proven
+// to win, and it is lost if all of its moves are proven to lose.
+func (a *AI) propagateProof(node *TreeNode, moves []int) {
+    rootColor := a.environment.Game.Board.ColorOfNextPlay()
+    for depth := len(moves); depth > 0 && node != a.topNode; depth-- {
+        parent := node.parent
+        if parent.proven {
+            return
+        }
+        // the player to move in the position of 'parent' played moves[depth-1]
+        toMove := rootColor
+        if (depth-1) % 2 == 1 {
+            toMove = !rootColor
+        }
+        if node.provenWinner != toMove {
+            if parent.numMoves == 0 {
+                board := a.boardAfter(moves[0:depth-1])
+                posses := board.listLegalPosses(toMove)
+                numMoves := len(posses)
+                if parent == a.topNode {
+                    // symmetric moves share one child of the top node, see runSimulation
+                    numMoves = board.numCanonicalMoves(posses, a.rootSymmetries())
+                }
+                parent.numMoves = numMoves + 1
+            }
+            if !parent.allChildrenProvenFor(!toMove) {
+                return
+            }
+        }
+        parent.setProven(node.provenWinner)
+        node = parent
+    }
+}
+
+// Returns a copy of the current board on which 'moves' (-1 denotes a pass) have been played.
+func (a *AI) boardAfter(moves []int) *Board {
+    board := a.environment.Game.Board.Copy()
+    for _, pos := range moves {
+        if pos < 0 {
+            board.PlayPass(board.ColorOfNextPlay())
+        } else {
+            board.playMoveByPos(pos, board.ColorOfNextPlay())
+        }
+    }
+    return board
+}
+
+// Removes 'node' and its ancestors from the tree as long as they have no simulations, i.e. the nodes
+// which have been created by a discarded playout.
+func (a *AI) pruneUnscored(node *TreeNode) {
+    for node != a.topNode && node.parent != nil && node.NodeInfo.simulations == 0 {
+        parent := node.parent
+        for pos, child := range parent.children {
+            if child == node {
+                parent.children[pos] = nil, false
+                break
+            }
+        }
+        node = parent
+    }
+}
+
+// Returns the symmetries of the current position, see Board.symmetries. They are only recomputed when the top
+// node or the position has changed, which may also happen by playing on the game directly, e.g. by GTP play.
+// The top node is then adapted to them, see canonicalizeTopNode.
+func (a *AI) rootSymmetries() []int {
+    board := a.environment.Game.Board
+    moves := len(a.environment.Game.sequence)
+    if a.symmetryNode != a.topNode || a.symmetryBoard != board || a.symmetryHash != board.Hash() ||
+       a.symmetryMoves != moves {
+        a.symmetryNode, a.symmetryBoard, a.symmetryHash, a.symmetryMoves = a.topNode, board, board.Hash(), moves
+        a.symmetries = board.symmetries()
+        a.canonicalizeTopNode(a.symmetries)
+    }
+    return a.symmetries
+}
+
+// Drops the children of the top node whose moves are not canonical under 'syms', see Board.canonicalMove. They
+// are left over from the time when the top node was an inner node of the tree, whose children are not merged,
+// and the simulations only continue the canonical ones. The number of moves of the top node is counted again,
+// see propagateProof.
+func (a *AI) canonicalizeTopNode(syms []int) {
+    a.topNode.numMoves = 0
+    if len(syms) == 1 {
+        return
+    }
+    board := a.environment.Game.Board
+    for pos, child := range a.topNode.children {
+        if canonical, _ := board.canonicalMove(pos, syms); canonical != pos {
+            if child.proven {
+                a.topNode.provenChildren--
+            }
+            child.Clear()
+            a.topNode.children[pos] = nil, false
+        }
+    }
+}
+
+// Writes the statistics as JSON to stderr if this is enabled, see SetPrintStatistics. The thinkers must
+// not be running.
+func (a *AI) reportStatistics() {
+    if a.printStatistics {
+        fmt.Fprintf(os.Stderr, "%s\n", a.statistics().JSON())
+    }
+}
 
 // Runs one simulation originating from the current state in a. This func also scores in the game tree.
 func (a *AI) runSimulation() {
-    board := a.environment.Game.Board.Copy()
+    start := time.Nanoseconds()
+    root := a.environment.Game.Board
+    board := a.playoutBoard()
+    a.stats.playouts++
+    trace := newSimulationTrace()
 
-    // play random games until both players pass in a row
+    // play random games until both players pass in a row, the mercy rule decides the game or
+    // the move cap is hit
     lastPass := false
     currentNode := a.topNode
+    moveCap := a.playoutMoveCap()
+    // the cap counts from the start of the playout, otherwise a long game would end every playout at once
+    movesPlayed := 0
+    stonesBlack, stonesWhite := board.numberOfStones()
+    prisonersBlack, prisonersWhite := board.numberOfPrisoners()
+    rootDifference := a.mercyDifference(board, stonesBlack, stonesWhite)
+    decided := false // true if the result is known without counting the final position
+    var winner Color
+    moves := make([]int, 0, 2*expectedGameLength(board.Width(), board.Height()))
+    finished := false // true if the playout ended with two passes in a row
+    selected := int64(0) // when the simulation created its first new node and left the tree
+    // Symmetric moves at the top node share one child: the first move is replaced by its canonical
+    // representative, and all later moves are transformed by the same symmetry. The tree therefore
+    // records an equivalent game, and 'moves' are the moves of this game.
+    syms := a.rootSymmetries()
+    sym := 0
+    // the key of the child of the current node which a move at 'pos' leads to
+    keyOf := func(pos int) int {
+        if len(moves) == 0 && len(syms) > 1 {
+            key, _ := root.canonicalMove(pos, syms)
+            return key
+        }
+        return root.transformPos(pos, sym)
+    }
     for {
-        v := board.PlayRandomMove(board.ColorOfNextPlay())
+        color := board.ColorOfNextPlay()
+        var v Vertex
+        if currentNode.provenChildren > 0 {
+            board, v = a.playUnprovenMove(board, color, currentNode, keyOf)
+        } else {
+            v = a.playPlayoutMove(board, color)
+        }
+        movesPlayed++
+        playedPos := -1
+        if !v.Pass {
+            playedPos = board.xyToPos(v.X, v.Y)
+        }
+        key := root.transformPos(playedPos, sym)
+        if len(moves) == 0 && len(syms) > 1 {
+            key, sym = root.canonicalMove(playedPos, syms)
+        }
+        if len(moves) == cap(moves) {
+            newMoves := make([]int, len(moves), 2*cap(moves))
+            copy(newMoves, moves)
+            moves = newMoves
+        }
+        moves = moves[0:len(moves)+1]
+        moves[len(moves)-1] = key
+        currentNode = trace.step(currentNode, key)
+        if selected == 0 && !trace.inTree {
+            selected = time.Nanoseconds()
+        }
         if v.Pass {
             if lastPass {
+                finished = true
                 break
             } else {
                 lastPass = true
-                currentNode = currentNode.ChildNode(-1)
             }
         } else {
             lastPass = false
-            playedPos := board.xyToPos(v.X, v.Y)
-            currentNode = currentNode.ChildNode(playedPos)
+            // keep track of the stones on the board without counting them again
+            if color == Black {
+                stonesBlack++
+            } else {
+                stonesWhite++
+            }
+            newPrisonersBlack, newPrisonersWhite := board.numberOfPrisoners()
+            stonesBlack -= newPrisonersBlack - prisonersBlack
+            stonesWhite -= newPrisonersWhite - prisonersWhite
+            prisonersBlack, prisonersWhite = newPrisonersBlack, newPrisonersWhite
+        }
+        // there is no need to play out a position whose result is proven
+        if currentNode.proven {
+            winner, decided = currentNode.provenWinner, true
+            break
+        }
+        if winner, decided = a.mercyWinner(board, stonesBlack, stonesWhite, rootDifference); decided {
+            a.stats.mercyTerminations++
+            break
+        }
+        if moveCap > 0 && movesPlayed >= moveCap {
+            if a.discardCappedPlayouts {
+                a.stats.cappedDiscarded++
+                playedOut := time.Nanoseconds()
+                if selected == 0 {
+                    selected = playedOut
+                }
+                a.pruneUnscored(currentNode)
+                a.stats.recordSimulation(trace, selected - start, playedOut - selected, time.Nanoseconds() - playedOut)
+                return
+            }
+            a.stats.cappedScored++
+            break
         }
     }
 
-    // the game is finished, now calculate who won or if its a jigo
-    prisonersBlack, prisonersWhite := board.numberOfPrisoners()
-    stonesBlack, stonesWhite := board.numberOfStones()
-    areaBlack, areaWhite := board.getArea()
-    scoreBlack := float(prisonersBlack + stonesBlack + areaBlack)
-    scoreWhite := float(prisonersWhite + stonesWhite + areaWhite) + a.environment.komi
     var wonBlack, wonWhite, jigo int
-    if scoreBlack > scoreWhite {
-        wonBlack = 1
-        wonWhite = 0
-        jigo = 0
-    } else if scoreBlack < scoreWhite {
-        wonBlack = 0
-        wonWhite = 1
-        jigo = 0
-    } else if scoreBlack == scoreWhite {
-        wonBlack = 0
-        wonWhite = 0
-        jigo = 1
+    if decided {
+        if winner == Black {
+            wonBlack = 1
+        } else {
+            wonWhite = 1
+        }
+    } else {
+        wonBlack, wonWhite, jigo = a.scoreFinalPosition(board)
+        // the end of the game is a proven result
+        if finished && jigo == 0 {
+            currentNode.setProven(wonBlack == 0)
+            a.propagateProof(currentNode, moves)
+        }
     }
 
     // the game is finished, now score in the game tree
+    playedOut := time.Nanoseconds()
+    // a simulation which ends at a proven node without leaving the tree only selects
+    if selected == 0 {
+        selected = playedOut
+    }
     for currentNode != nil {
         currentNode.IncrementScore(1, wonBlack, wonWhite, jigo)
         currentNode = currentNode.parent
     }
+    a.stats.recordSimulation(trace, selected - start, playedOut - selected, time.Nanoseconds() - playedOut)
+}
+
+// Plays a playout move of 'color' on 'board' at 'node' which does not lead to a proven child of 'node', so that
+// the simulations are spent on the moves whose results are still open. 'keyOf' returns the key of the child
+// which a move at a pos leads to. The playout policy is tried a few times on copies of 'board', then a legal
+// move with an unproven child is drawn at random. Returns the board on which the move has been played.
+func (a *AI) playUnprovenMove(board GoBoard, color Color, node *TreeNode, keyOf func(pos int) int) (GoBoard, Vertex) {
+    for try := 0; try < 4; try++ {
+        cpy := board.Clone()
+        v := a.playPlayoutMove(cpy, color)
+        pos := -1
+        if !v.Pass {
+            pos = cpy.xyToPos(v.X, v.Y)
+        }
+        if child, ok := node.children[keyOf(pos)]; !ok || !child.proven {
+            return cpy, v
+        }
+    }
+    candidates := board.listLegalPosses(color)
+    n := 0
+    for _, pos := range candidates {
+        if child, ok := node.children[keyOf(pos)]; !ok || !child.proven {
+            candidates[n] = pos
+            n++
+        }
+    }
+    if n == 0 {
+        if child, ok := node.children[keyOf(-1)]; ok && child.proven {
+            // every move is proven, so the policy may as well choose
+            return board, a.playPlayoutMove(board, color)
+        }
+        board.PlayPass(color)
+        return board, *NewVertexByInts(0, 0, true)
+    }
+    pos := candidates[a.rand.Intn(n)]
+    board.playMoveByPos(pos, color)
+    x, y := board.posToXY(pos)
+    return board, *NewVertexByInts(x, y, false)
+}
+
+// Counts the final position of a playout on 'board' according to the ruleset and returns who won, or
+// if its a jigo.
+func (a *AI) scoreFinalPosition(board GoBoard) (wonBlack, wonWhite, jigo int) {
+    score := a.environment.ScoreDifference(board)
+    if score > 0 {
+        wonBlack = 1
+    } else if score < 0 {
+        wonWhite = 1
+    } else {
+        jigo = 1
+    }
+    return
+}
+
+// Enables or disables the ladder replies in playouts, see Board.ladderReply.
+func (a *AI) SetHeavyLadders(heavy bool) {
+    defer a.startThinking(a.stopThinking())
+    a.heavyLadders = heavy
+}
+
+// Enables or disables playouts on a FastBoard, which are faster than playouts on a Board, but ignore
+// superko, ladders, nakade, patterns and the seki avoidance of isUnwantedPlayoutMove. Since they can
+// repeat a position, they are always capped, see playoutMoveCap.
+func (a *AI) SetFastPlayouts(fast bool) {
+    defer a.startThinking(a.stopThinking())
+    a.fastPlayouts = fast
+}
+
+// Enables or disables the life-and-death solver, which overrides the search if it finds an urgent move
+// around the last move.
+func (a *AI) SetLifeAndDeathSolving(solve bool) {
+    defer a.startThinking(a.stopThinking())
+    a.solveLifeAndDeath = solve
+}
+
+// Enables or disables writing the search statistics as JSON to stderr after each generated move.
+func (a *AI) SetPrintStatistics(enable bool) {
+    a.printStatistics = enable
+}
+
+// Enables randomized move selection, see selectMove: the first 'moves' moves of the game are drawn with
+// 'temperature', later moves uniformly among those within 'epsilon' of the best winning percentage. A positive
+// epsilon restricts the moves which are drawn with the temperature as well. A temperature or an epsilon of 0
+// disables the respective mode.
+func (a *AI) SetMoveSelection(temperature float, moves int, epsilon float) {
+    defer a.startThinking(a.stopThinking())
+    a.selectionTemperature = temperature
+    a.temperatureMoves = moves
+    a.selectionEpsilon = epsilon
+}
+
+// Sets the mercy rule. A playout is won by a color as soon as it has gained a lead of 'threshold' stones
+// over the position the playout starts from, where 'mode' (one of Mercy{Stone,Capture}Difference) decides
+// which stones are counted. A threshold of 0 disables the mercy rule.
+func (a *AI) SetMercyRule(threshold, mode int) {
+    defer a.startThinking(a.stopThinking())
+    a.mercyThreshold = threshold
+    a.mercyMode = mode
+}
+
+// Caps playouts at 'factor' times the average length of a random game on the current board size. 
+// If 'discard' is true, capped playouts are thrown away, otherwise the position at the cap is scored.
+// A factor of 0 disables the cap.
+func (a *AI) SetMoveCap(factor float, discard bool) {
+    defer a.startThinking(a.stopThinking())
+    a.moveCapFactor = factor
+    a.discardCappedPlayouts = discard
+}
+
+// Plays and scores under the ruleset 'r' from now on.
+func (a *AI) SetRules(r *Ruleset) {
+    defer a.startThinking(a.stopThinking())
+    a.environment.SetRules(r)
+}
+
+// Uses 'w' for playouts and priors. nil switches back to uniformly random playouts without priors.
+func (a *AI) SetPatterns(w *PatternWeights) {
+    defer a.startThinking(a.stopThinking())
+    a.patterns = w
+    a.policy = nil
+    if w != nil {
+        a.policy = newPatternPolicy(w)
+    }
+}
+
+// Returns a snapshot of the search statistics, including the current size of the tree. The thinkers
+// must not be running.
+func (a *AI) statistics() *SearchStatistics {
+    snapshot := *a.stats
+    snapshot.treeSize = a.topNode.Size() - 1
+    snapshot.moveNumber = len(a.environment.Game.sequence)
+    return &snapshot
+}
+
+// Returns a snapshot of the search statistics.
+func (a *AI) Statistics() *SearchStatistics {
+    defer a.startThinking(a.stopThinking())
+    return a.statistics()
 }
 
 // Only starts thinking if think == true - so this can be used as a sort of 
@@ -287,6 +994,7 @@ func (a *AI) startThinking(think bool) {
     }
 
     a.runThinkers = true
+    a.stats.startThinking(time.Nanoseconds())
     for i := 0; i < a.numThinkers; i++ {
         go a.makeThinker(i)
     }
@@ -304,6 +1012,7 @@ func (a *AI) stopThinking() bool {
     for i := 0; i < a.numThinkers; i++ {
         <-a.thinkerFinished[i]
     }
+    a.stats.stopThinking(time.Nanoseconds(), a.numThinkers)
 
     return true
 }
@@ -312,12 +1021,19 @@ func (a *AI) stopThinking() bool {
 func NewAI(boardsize int) *AI {
     // numThinkers := runtime.GOMAXPROCS(0)
     // TODO: numThinkers = 1 seems to be the fastest, but why??
+    // There must be only one thinker: the simulations share the playout board, see playoutBoard, which shares
+    // the random generator and the ko with the board of the game, and they update the tree without locks. The
+    // board of the game is only used while they are stopped, see GTPObject.ExecuteCommand.
     numThinkers := 1
+    sec, nsec, _ := os.Time()
     a := &AI{
         numThinkers: numThinkers,
         topNode: NewTreeNode(nil),
         environment: NewEnvironment(boardsize),
         thinkerFinished: make([]chan bool, numThinkers),
+        mercyMode: MercyStoneDifference,
+        stats: &SearchStatistics{},
+        rand: rand.New(rand.NewSource(sec+nsec)),
     }
     for i := 0; i < numThinkers; i++ {
          a.thinkerFinished[i] = make(chan bool)
@@ -53,9 +53,152 @@ func BenchmarkRandomGameByPlayRandomMove(b *testing.B) {
     DbgHistogram.PrintSorted()
 }
 
+// Returns a board of size 'size' on which 'moves' random moves have been played.
+func randomPosition(size, moves int) *Board {
+    board := NewBoard(size)
+    for i := 0; i < moves; i++ {
+        board.PlayRandomMove(board.ColorOfNextPlay())
+    }
+    return board
+}
+
+// Returns the largest group on 'board'.
+func largestGroup(board *Board) *Group {
+    var largest *Group
+    for _, grp := range board.fields {
+        if grp != nil && (largest == nil || grp.NumStones() > largest.NumStones()) {
+            largest = grp
+        }
+    }
+    return largest
+}
+
+// The group which BenchmarkGroupCopy copies into. It is kept alive, so that the compiler cannot drop the copies.
+// The group benchmarks play random positions, so the size of the copied group varies from run to run.
+var groupCopySink Group
+
+func BenchmarkGroupCopy(b *testing.B) {
+    b.StopTimer()
+    grp := largestGroup(randomPosition(boardsize, 60))
+    b.StartTimer()
+    for i := 0; i < b.N; i++ {
+        grp.Copy(&groupCopySink)
+    }
+}
+
+func BenchmarkUpdateGroupLiberties(b *testing.B) {
+    b.StopTimer()
+    board := randomPosition(boardsize, 60)
+    board.SetJournaling(false)
+    grp := largestGroup(board)
+    b.StartTimer()
+    for i := 0; i < b.N; i++ {
+        board.updateGroupLiberties(grp)
+    }
+}
+
+// Joins two groups of three stones and removes the joined group.
+func BenchmarkJoinAndRemoveGroups(b *testing.B) {
+    b.StopTimer()
+    board := NewBoard(boardsize)
+    for x := 0; x < 3; x++ {
+        board.PlayMove(x, 2, Black)
+        board.PlayMove(x, 6, White)
+        board.PlayMove(x+4, 2, Black)
+        board.PlayMove(x+4, 6, White)
+    }
+    b.StartTimer()
+    for i := 0; i < b.N; i++ {
+        b.StopTimer()
+        cpy := board.Copy()
+        into, from := cpy.fields[cpy.xyToPos(0, 2)], cpy.fields[cpy.xyToPos(4, 2)]
+        b.StartTimer()
+        cpy.joinGroups(into, from)
+        cpy.removeGroup(into)
+    }
+}
+
+// Copies a 19x19 middle game position, as every simulation does.
+func BenchmarkCopy19(b *testing.B) {
+    b.StopTimer()
+    board := randomPosition(19, 150)
+    b.StartTimer()
+    for i := 0; i < b.N; i++ {
+        board.Copy()
+    }
+}
+
+// Fills one board again and again with a 19x19 middle game position, as the playouts do, see AI.playoutBoard.
+// Compare with BenchmarkCopy19.
+func BenchmarkCopyInto19(b *testing.B) {
+    b.StopTimer()
+    board := randomPosition(19, 150)
+    playout := board.Copy()
+    b.StartTimer()
+    for i := 0; i < b.N; i++ {
+        board.copyInto(playout)
+    }
+}
+
+// Copies a 19x19 middle game position and plays 50 random moves on the copy.
+func BenchmarkCopyAndPlay19(b *testing.B) {
+    b.StopTimer()
+    board := randomPosition(19, 150)
+    b.StartTimer()
+    for i := 0; i < b.N; i++ {
+        cpy := board.Copy()
+        for n := 0; n < 50; n++ {
+            cpy.PlayRandomMove(cpy.ColorOfNextPlay())
+        }
+    }
+}
+
+// Plays random moves on 'board' until both players pass in a row, at most 1000 moves, since the default rules
+// allow cycles.
+func playToEnd(board *Board) {
+    passes := 0
+    for n := 0; n < 1000 && passes < 2; n++ {
+        if board.PlayRandomMove(board.ColorOfNextPlay()).Pass {
+            passes++
+        } else {
+            passes = 0
+        }
+    }
+}
+
+// Copies a 19x19 middle game position and plays it out on the copy, like a simulation without the tree.
+func BenchmarkCopyAndPlayout19(b *testing.B) {
+    b.StopTimer()
+    board := randomPosition(19, 150)
+    b.StartTimer()
+    for i := 0; i < b.N; i++ {
+        playToEnd(board.Copy())
+    }
+}
+
+// Like BenchmarkCopyAndPlayout19, but fills one board again for every playout, as AI.playoutBoard does.
+func BenchmarkCopyIntoAndPlayout19(b *testing.B) {
+    b.StopTimer()
+    board := randomPosition(19, 150)
+    cpy := board.Copy()
+    b.StartTimer()
+    for i := 0; i < b.N; i++ {
+        board.copyInto(cpy)
+        playToEnd(cpy)
+    }
+}
+
 func Benchmarks() []testing.InternalBenchmark {
     return []testing.InternalBenchmark {
         testing.InternalBenchmark{"BenchmarkRandomGameByListLegalPoints", BenchmarkRandomGameByListLegalPoints},
         testing.InternalBenchmark{"BenchmarkRandomGameByPlayRandomMove", BenchmarkRandomGameByPlayRandomMove},
+        testing.InternalBenchmark{"BenchmarkGroupCopy", BenchmarkGroupCopy},
+        testing.InternalBenchmark{"BenchmarkUpdateGroupLiberties", BenchmarkUpdateGroupLiberties},
+        testing.InternalBenchmark{"BenchmarkJoinAndRemoveGroups", BenchmarkJoinAndRemoveGroups},
+        testing.InternalBenchmark{"BenchmarkCopy19", BenchmarkCopy19},
+        testing.InternalBenchmark{"BenchmarkCopyInto19", BenchmarkCopyInto19},
+        testing.InternalBenchmark{"BenchmarkCopyAndPlay19", BenchmarkCopyAndPlay19},
+        testing.InternalBenchmark{"BenchmarkCopyAndPlayout19", BenchmarkCopyAndPlayout19},
+        testing.InternalBenchmark{"BenchmarkCopyIntoAndPlayout19", BenchmarkCopyIntoAndPlayout19},
     }
 }
@@ -71,6 +71,36 @@ func BenchmarkJoinUnique(b *testing.B) {
     }
 }
 
+// The liberties of a Group are a bitset instead of an IntList, compare with Benchmark10AppendUniques and
+// Benchmark10Removes.
+func Benchmark10AddLiberties(b *testing.B) {
+    g := NewGroup(Black)
+    g.liberties = newBitset(81)
+    for i := 0; i < b.N; i++ {
+        for l := 0; l < 10; l++ {
+            g.addLiberty(l)
+        }
+        b.StopTimer()
+        g.clearLiberties()
+        b.StartTimer()
+    }
+}
+
+func Benchmark10RemoveLiberties(b *testing.B) {
+    g := NewGroup(Black)
+    g.liberties = newBitset(81)
+    for i := 0; i < b.N; i++ {
+        b.StopTimer()
+        for l := 0; l < 10; l++ {
+            g.addLiberty(l)
+        }
+        b.StartTimer()
+        for l := 0; l < 10; l++ {
+            g.removeLiberty(l)
+        }
+    }
+}
+
 func Benchmarks() []testing.InternalBenchmark {
     return []testing.InternalBenchmark { 
         testing.InternalBenchmark{"BenchmarkIntListCreation", BenchmarkIntListCreation},
@@ -78,5 +108,7 @@ func Benchmarks() []testing.InternalBenchmark {
         testing.InternalBenchmark{"Benchmark10Removes", Benchmark10Removes},
         testing.InternalBenchmark{"Benchmark10AppendUniques", Benchmark10AppendUniques},
         testing.InternalBenchmark{"BenchmarkJoinUnique", BenchmarkJoinUnique},
+        testing.InternalBenchmark{"Benchmark10AddLiberties", Benchmark10AddLiberties},
+        testing.InternalBenchmark{"Benchmark10RemoveLiberties", Benchmark10RemoveLiberties},
     }
 }
@@ -11,6 +11,7 @@ import (
     "fmt"
     "rand"
     "os"
+    "sync"
 )
 
 /*
@@ -21,35 +22,21 @@ import (
 // ################################################################################
 // ########################### global variables ###################################
 // ################################################################################
-var neighbourCache []([]([]int))
+// The largest number of columns and rows of a board. GTP only supports boards up to 25x25, see gtpboardsize.
+const MaxBoardSize = 52
 
-// ################################################################################
-// ########################### init func ##########################################
-// ################################################################################
-func init() {
-    // Initialize the neighbourCache. We only support boards of sizes between 3 and 25
-    neighbourCache = make([]([]([]int)), 30)
-    for boardSize := 3; boardSize < 26; boardSize++ {
-        neighbourCache[boardSize] = make([]([]int), boardSize*boardSize)
-        for pos := 0; pos < boardSize*boardSize; pos++ {
-            x, y := posToXY(pos, boardSize)
-            nbourPoints := calculateNeighbours(x,y,boardSize)
-            l := len(nbourPoints)
-            neighbourCache[boardSize][pos] = make([]int, l)
-            for i, p := range nbourPoints {
-                nbourPos := xyToPos(p.X, p.Y, boardSize)
-                neighbourCache[boardSize][pos][i] = nbourPos
-            }
-        }
-    }
-}
+// The neighbours of each pos for every board dimension which is in use, see neighbourTable. The key is
+// width<<16 | height.
+var neighbourCache = make(map[int]([]([]int)))
+var neighbourCacheLock sync.Mutex
 
 // ################################################################################
 // ########################### actionFunc #########################################
 // ################################################################################
 
 // Context is implicit in closures. We use this to make a part of the context needed by an 
-// actionFunc explicit, because we need to change it later, when we copy a board.
+// actionFunc explicit, because a copy of a board shares the actions and needs its own groups,
+// see actionFunc.Call.
 type boardBoundFuncContext struct {
     enemiesInAtari, enemiesNotInAtari, adjSameColor, adjOtherColor GroupSlice
 }
@@ -58,24 +45,34 @@ type boardBoundFuncContext struct {
 type actionFuncClosure func(boardPtr *Board, c *boardBoundFuncContext) (blackUpdated, whiteUpdated bool)
 
 // An actionFunc represents the action performed by playing at one field. Use .Call() to
-// execute the action.
+// execute the action. Actions are never changed, so copies of a board share them.
 type actionFunc struct {
     context *boardBoundFuncContext
-    boardPtr *Board
+    boardPtr *Board // the board on which the action has been computed
+    pos int
+    color Color
     f actionFuncClosure
 }
 
+// Executes the action on 'b', which is the board of the action or a copy of it. The context of the
+// action refers to the groups of its own board, so a copy looks up its own groups, see Board.ownContext.
 // The return values are only relevant if we assume that the legalities for all fields have been 
 // calculated before. In this case, {black,white}Updated indicates that all legalities are valid 
 // afterwards.
-func (a *actionFunc) Call() (blackUpdated, whiteUpdated bool) {
-    return a.f(a.boardPtr, a.context)
+func (a *actionFunc) Call(b *Board) (blackUpdated, whiteUpdated bool) {
+    context := a.context
+    if b != a.boardPtr && context != nil {
+        context = b.ownContext(context)
+    }
+    return a.f(b, context)
 }
 
-func NewActionFunc(board *Board, context *boardBoundFuncContext, f actionFuncClosure) *actionFunc {
+func NewActionFunc(board *Board, pos int, color Color, context *boardBoundFuncContext, f actionFuncClosure) *actionFunc {
     return &actionFunc{
         context: context,
         boardPtr: board,
+        pos: pos,
+        color: color,
         f: f,
     }
 }
@@ -96,6 +93,30 @@ func NewKoLock(pos int, color Color) *koLock {
                   }
 }
 
+// ################################################################################
+// ########################### GoBoard ############################################
+// ################################################################################
+
+// The operations of a board which the AI needs to play out and score a game. Board and FastBoard
+// implement it.
+type GoBoard interface {
+    Width() int
+    Height() int
+    ColorOfNextPlay() Color
+    Hash() uint64
+    IsLegalMove(pos int, color Color) bool
+    listLegalPosses(color Color) []int
+    playMoveByPos(pos int, color Color) Error
+    PlayPass(color Color)
+    PlayRandomMove(color Color) Vertex
+    AreaScore() (black, white int)
+    numberOfPrisoners() (nblack, nwhite int)
+    numberOfStones() (nblack, nwhite int)
+    xyToPos(x, y int) int
+    posToXY(pos int) (x, y int)
+    Clone() GoBoard
+}
+
 // ################################################################################
 // ########################### Board struct #######################################
 // ################################################################################
@@ -103,24 +124,50 @@ func NewKoLock(pos int, color Color) *koLock {
 // This object is responsible for recording a current state of a game.
 type Board struct {
     fields []*Group // Stores pointers to the groups. nil denotes an empty field
+    groups []Group // the group whose first stone is at pos is groups[pos], see CreateGroup
+    liberties bitset // the liberties of all groups, see libertiesAt
+    nextStone []int // the next stone of the group of each stone. The stones of a group form a circular chain
     ko *koLock // nil means that there is no ko
     actionOnNextBlackMove []*actionFunc // This stores the appropriate code which has to be run if a black move is played on a field
     actionOnNextWhiteMove []*actionFunc // see the obvious analogue
     colorOfNextPlay Color
-    boardSize int
+    width, height int // the number of columns and rows
+    neighbours []([]int) // the neighbours of each pos, shared by all boards of the same dimensions
     currentSequence uint32 // Represents the state of the board. Everything flagged with a different sequence has to be updated.
     fieldSequencesBlack []uint32
     fieldSequencesWhite []uint32
     rand *rand.Rand
     prisonersBlack int // number of black prisoners
     prisonersWhite int // number of white prisoners
+    lastMove int // pos of the last move, -1 if it was a pass or if there is none
+    secondLastMove int // pos of the move before the last move, -1 if it was a pass or if there is none
+    hash uint64 // the Zobrist hash of the position, see Hash
+    history []uint64 // the positionHash of every position of the game, see recordPosition
+    superko SuperkoRule
+    superkoWindow int // compare only the last superkoWindow positions if positive, see SetSuperko
+    passStones bool // a pass hands a stone to the opponent as a prisoner, see Ruleset
+    suicide bool // the suicide of more than one stone is legal, see Ruleset
+    journal []*journalEntry // the changes of the moves which can be taken back, see Undo
+    journaling bool
+    removedNeighbours GroupSlice // the groups next to the group which removeGroup removes
+    copyContext boardBoundFuncContext // the context of an action of another board, see ownContext
 }
 
 // ##################### Board methods ##########################
 
-// Returns the board size.
+// Returns the board size of a square board. Rectangular boards are described by Width and Height.
 func (b *Board) BoardSize() int {
-    return b.boardSize
+    return b.width
+}
+
+// Returns the number of columns.
+func (b *Board) Width() int {
+    return b.width
+}
+
+// Returns the number of rows.
+func (b *Board) Height() int {
+    return b.height
 }
 
 // Calculates if a move of 'color' at 'pos' is legal. Does not use 
@@ -161,15 +208,15 @@ func (b *Board) calculateIfLegal(pos int, color Color) (isLegal bool, action *ac
                 // But first we have to check if this move is a ko play. If we capture exactly one
                 // group consisting of exactly one stone, than it's a ko.
                 firstGroup := context.enemiesInAtari[0]
-                if len(context.enemiesInAtari) == 1 && firstGroup.Fields.Length() == 1 {
+                if len(context.enemiesInAtari) == 1 && firstGroup.NumStones() == 1 {
                     // It's a ko, so remove the group, play the stone, update the liberties
                     // and set b.ko to the right point.
-                    koPos := firstGroup.Fields.First().Value()
-                    action = NewActionFunc(b, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
+                    koPos := firstGroup.FirstStone()
+                    action = NewActionFunc(b, pos, color, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
                         //printDbgMsgf("Board.calculateIfLegal: sameColLen == nFree == 0, removeGroups = true, ko case.\n") // <DBG>
                         //DbgHistogram.Score() // </DBG>
 
-                        alreadyUpdated := make([]bool, boardPtr.boardSize*boardPtr.boardSize)
+                        alreadyUpdated := make([]bool, len(boardPtr.fields))
                         boardPtr.ko = nil
                         boardPtr.removeGroup(c.enemiesInAtari[0]) // Remove the enemy stone
                         boardPtr.CreateGroup(pos,color) // Create the new group
@@ -184,11 +231,14 @@ func (b *Board) calculateIfLegal(pos int, color Color) (isLegal bool, action *ac
                             _, _, adjToKoSameColor = boardPtr.GetEnvironment(koPos)
                         }
                         for _, grp := range adjToKoSameColor {
-                            grp.Liberties.AppendUnique(koPos)
+                            boardPtr.journalGroup(grp)
+                            boardPtr.journalLiberty(grp, koPos)
+                            grp.addLiberty(koPos)
                             boardPtr.updateLegalityForLibertiesOfExcept(grp, koPos, boardPtr.currentSequence + 1, alreadyUpdated)
                         }
                         // The player who took the ko may fill it, so make it legal for the player 'color' for the next round.
                         // TODO: this is sort of an evil hack... or is it?
+                        boardPtr.journalLegality(koPos, color)
                         if color == Black {
                             _, boardPtr.actionOnNextBlackMove[koPos] = boardPtr.calculateIfLegal(koPos, Black)
                             boardPtr.fieldSequencesBlack[koPos] = boardPtr.currentSequence + 1
@@ -207,7 +257,7 @@ func (b *Board) calculateIfLegal(pos int, color Color) (isLegal bool, action *ac
                     })
                 } else {
                     // It's not a ko
-                    action = NewActionFunc(b, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
+                    action = NewActionFunc(b, pos, color, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
                         //printDbgMsgf("Board.calculateIfLegal: sameColLen == nFree == 0, removeGroups = true, not ko case.\n") // <DBG>
                         //DbgHistogram.Score() // </DBG>
                         for _, grp := range c.enemiesInAtari {
@@ -228,7 +278,7 @@ func (b *Board) calculateIfLegal(pos int, color Color) (isLegal bool, action *ac
             // There are no adjacent friendly groups, but free neighbour fields, so this move
             // is always legal. Remove adjacent enemy groups if necessary and create a new group.
             if removeGroups {
-                action = NewActionFunc(b, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
+                action = NewActionFunc(b, pos, color, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
                     //printDbgMsgf("Board.calculateIfLegal: sameColLen == 0, nFree > 0, removeGroups = true.\n") // <DBG>
                     //DbgHistogram.Score() // </DBG>
                     for _, grp := range c.enemiesInAtari {
@@ -242,12 +292,12 @@ func (b *Board) calculateIfLegal(pos int, color Color) (isLegal bool, action *ac
                     return false, false
                 })
             } else {
-                action = NewActionFunc(b, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
+                action = NewActionFunc(b, pos, color, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
                     // Experiments show that this case is run the most often
 
                     //printDbgMsgf("Board.calculateIfLegal: sameColLen == 0, nFree > 0, removeGroups = false.\n") // <DBG>
                     //DbgHistogram.Score() // </DBG>
-                    alreadyUpdated := make([]bool, boardPtr.boardSize*boardPtr.boardSize)
+                    alreadyUpdated := make([]bool, len(boardPtr.fields))
                     boardPtr.ko = nil
                     boardPtr.CreateGroup(pos,color)
                     boardPtr.dropLibertyFromEach(pos, c.adjOtherColor)
@@ -264,7 +314,7 @@ func (b *Board) calculateIfLegal(pos int, color Color) (isLegal bool, action *ac
             if removeGroups {
                 // This move captures stones and thus produces empty fields, so it is legal. Capture
                 // the stones first and then join the adjacent groups of the same color.
-                action = NewActionFunc(b, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
+                action = NewActionFunc(b, pos, color, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
                     //printDbgMsgf("Board.calculateIfLegal: sameColLen > 0, nFree == 0, removeGroups = true.\n") // <DBG>
                     //DbgHistogram.Score() // </DBG>
                     for _, grp := range c.enemiesInAtari {
@@ -285,18 +335,7 @@ func (b *Board) calculateIfLegal(pos int, color Color) (isLegal bool, action *ac
                 // has at least two liberties.
                 oneHasTwo := false
                 for _, g := range context.adjSameColor {
-                    if g.Liberties.Length() > 1 {
-                        /*fX, fY := b.posToXY(g.Fields.First().Value())
-                        grpVertex, _ := pointToGTPVertex(*NewPoint(fX, fY))
-                        Libs := g.Liberties
-                        libs := ""
-                        libEnd := Libs.Last()
-                        for it := Libs.First(); it != libEnd; it = it.Next() {
-                            lX, lY := b.posToXY(it.Value())
-                            ver, _ := pointToGTPVertex(*NewPoint(lX, lY))
-                            libs += " " + ver
-                        }
-                        printDbgMsgf("group around %s has many libs: %s\n", grpVertex, libs)*/
+                    if g.NumLiberties() > 1 {
                         oneHasTwo = true
                         break
                     }
@@ -304,12 +343,12 @@ func (b *Board) calculateIfLegal(pos int, color Color) (isLegal bool, action *ac
                 if oneHasTwo {
                     // If we join the groups, the resulting group has at least one liberty, so this move is legal.
                     // Since there are no groups to capture, simply join the adjacient groups of color 'color'.
-                    action = NewActionFunc(b, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
+                    action = NewActionFunc(b, pos, color, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
                         // Experiments show that this case is run the 3rd most often
 
                         //printDbgMsgf("Board.calculateIfLegal: sameColLen > 0, nFree == 0, removeGroups = false, oneHasTwo = true.\n") // <DBG>
                         //DbgHistogram.Score() // </DBG>
-                        alreadyUpdated := make([]bool, boardPtr.boardSize*boardPtr.boardSize)
+                        alreadyUpdated := make([]bool, len(boardPtr.fields))
                         boardPtr.ko = nil
                         boardPtr.joinGroupsByPlayAt(pos, c.adjSameColor)
                         boardPtr.dropLibertyFromEach(pos, c.adjOtherColor)
@@ -320,19 +359,30 @@ func (b *Board) calculateIfLegal(pos int, color Color) (isLegal bool, action *ac
                     })
                     //printDbgMsgf("returned from Board.calculateIfLegal(%d, %d, %v)\n", x, y, color) // <DBG/>
                     return true, action
-                } else {
+                } else if !b.suicide {
                     // There is no groups to remove and every adjacient group of the same color has only one liberty,
                     // which must be the field (x,y) we want to play at, so this move is illegal.
 
                     //printDbgMsgf("returned from Board.calculateIfLegal(%d, %d, %v)\n", x, y, color) // <DBG/>
                     return false, nil
+                } else {
+                    // This move is a suicide of more than one stone, which the ruleset allows. The joined group
+                    // is removed again and its stones become prisoners. The liberties of the adjacent enemy groups
+                    // change, so no legality is up to date afterwards.
+                    action = NewActionFunc(b, pos, color, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
+                        boardPtr.joinGroupsByPlayAt(pos, c.adjSameColor)
+                        boardPtr.removeGroup(boardPtr.fields[pos])
+                        boardPtr.ko = nil
+                        return false, false
+                    })
+                    return true, action
                 }
             }
         } else {
             // There are free neighbour fields, so this move is always legal. Capture adjacent enemy groups if necessary, 
             // then join groups and update liberties
             if removeGroups {
-                action = NewActionFunc(b, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
+                action = NewActionFunc(b, pos, color, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
                     //printDbgMsgf("Board.calculateIfLegal: sameColLen > 0, nFree > 0, removeGroups = true.\n") // <DBG>
                     //DbgHistogram.Score() // </DBG>
                     for _, grp := range c.enemiesInAtari {
@@ -346,12 +396,12 @@ func (b *Board) calculateIfLegal(pos int, color Color) (isLegal bool, action *ac
                     return false, false
                 })
             } else {
-                action = NewActionFunc(b, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
+                action = NewActionFunc(b, pos, color, context, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
                     // Experiments show that this case is run the 2nd most often
 
                     //printDbgMsgf("Board.calculateIfLegal: sameColLen > 0, nFree > 0, removeGroups = false.\n") // <DBG>
                     //DbgHistogram.Score() // </DBG>
-                    alreadyUpdated := make([]bool, boardPtr.boardSize*boardPtr.boardSize)
+                    alreadyUpdated := make([]bool, len(boardPtr.fields))
                     boardPtr.ko = nil
 
                     /*printDbgMsgf("len(adjSameColor): %d\n", len(c.adjSameColor))
@@ -393,8 +443,8 @@ func (b *Board) chooseRandomFavorableMove(candidatePos []int, color Color, alrea
         randomPos := candidatePos[randomIndex]
         if !alreadyConsidered[randomPos] {
             if b.IsLegalMove(randomPos, color) {
-                // Do we want to play this move? This move is not played if one it only fills eyes.
-                if b.isEyeFillingMove(randomPos, color) {
+                // Do we want to play this move? This move is not played if one it only fills eyes or breaks a seki.
+                if b.isUnwantedPlayoutMove(randomPos, color) {
                     alreadyConsidered[randomPos] = true
                 } else {
                     // yes, we want to play this move
@@ -409,87 +459,118 @@ func (b *Board) chooseRandomFavorableMove(candidatePos []int, color Color, alrea
     return
 }
 
+// Returns the pos's of the last and the second last move. -1 denotes a pass or that there is no such move.
+func (b *Board) LastMoves() (last, secondLast int) {
+    return b.lastMove, b.secondLastMove
+}
+
+// Like Copy, but returns the copy as a GoBoard.
+func (b *Board) Clone() GoBoard {
+    return b.Copy()
+}
+
 // Returns the color of the player who plays the next turn
 func (b *Board) ColorOfNextPlay() Color {
     return b.colorOfNextPlay
 }
 
-// returns an equivalent but completlely independent copy of b
+// returns an equivalent but completlely independent copy of b. The copy has no journal, so its moves
+// cannot be taken back unless it is enabled by SetJournaling. Copying is cheap, see copyInto.
 func (b *Board) Copy() *Board {
-    sec, nsec, _ := os.Time()
-    l := b.boardSize*b.boardSize
+    l := len(b.fields)
     cpy := &Board{
         fields: make([]*Group, l),
-        colorOfNextPlay: b.colorOfNextPlay,
-        boardSize: b.boardSize,
-        currentSequence: b.currentSequence,
+        groups: make([]Group, l),
+        liberties: make(bitset, len(b.liberties)),
+        nextStone: make([]int, l),
         actionOnNextBlackMove: make([]*actionFunc, l),
         actionOnNextWhiteMove: make([]*actionFunc, l),
         fieldSequencesBlack: make([]uint32, l),
         fieldSequencesWhite: make([]uint32, l),
-        rand: rand.New(rand.NewSource(sec+nsec)),
-        prisonersBlack: b.prisonersBlack,
-        prisonersWhite: b.prisonersWhite,
-    }
-    if b.ko != nil {
-        cpy.ko = &koLock{
-            Pos: b.ko.Pos,
-            Color: b.ko.Color,
-        }
-    }
-    copy(cpy.fieldSequencesBlack, b.fieldSequencesBlack)
-    copy(cpy.fieldSequencesWhite, b.fieldSequencesWhite)
-
-    // Copy .fields in a seperate loop first. The next loop, in which we set .actionOnNext{Black,White}Move
-    // depends upon correcty and already completely set .fields. Remember to copy each group only once!
-    copiedGroups := make(map[*Group]bool)
-    for i := 0; i < l; i++ {
-        grp := b.fields[i]
-        _, present := copiedGroups[grp]
-        if !present && grp != nil  {
-            gcpy := grp.Copy()
-            last := gcpy.Fields.Last()
-            for it := gcpy.Fields.First(); it != last; it = it.Next() {
-                cpy.fields[it.Value()] = gcpy
-            }
-            // remember that we already copied grp
-            copiedGroups[grp] = true
-        }
+        history: make([]uint64, 0, cap(b.history)),
     }
+    b.copyInto(cpy)
+    return cpy
+}
 
-    for i := 0; i < l; i++ {
-        // copy .actionOnNextBlackMove and adjust its context
-        if f := b.actionOnNextBlackMove[i]; f != nil {
-            cpy.actionOnNextBlackMove[i] = NewActionFunc(cpy, nil, f.f)
-            _, cpy.actionOnNextBlackMove[i].context = cpy.getEnvironmentAndContext(i, Black)
+// Makes 'dst', a board of the same dimensions as 'b', an equivalent copy of b without a journal. Nothing is
+// allocated unless the position history of 'dst' is too short, so a board which plays one playout after the
+// other is filled again by this. The groups and their liberties are flat arrays indexed by the first stone of
+// each group, so they are copied like the other arrays, and 'dst' shares the neighbours and the random generator
+// with 'b', as well as the ko. So 'b' must not be used while 'dst' plays, which is why GTP commands stop the
+// thinkers.
+func (b *Board) copyInto(dst *Board) {
+    dst.ko = b.ko
+    dst.colorOfNextPlay = b.colorOfNextPlay
+    dst.width, dst.height = b.width, b.height
+    dst.neighbours = b.neighbours
+    dst.currentSequence = b.currentSequence
+    dst.rand = b.rand
+    dst.prisonersBlack, dst.prisonersWhite = b.prisonersBlack, b.prisonersWhite
+    dst.lastMove, dst.secondLastMove = b.lastMove, b.secondLastMove
+    dst.hash = b.hash
+    dst.superko, dst.superkoWindow = b.superko, b.superkoWindow
+    dst.passStones, dst.suicide = b.passStones, b.suicide
+    dst.journaling = false
+    dst.journal = dst.journal[0:0]
+    if cap(dst.history) < len(b.history) {
+        dst.history = make([]uint64, 0, cap(b.history))
+    }
+    dst.history = dst.history[0:len(b.history)]
+    copy(dst.history, b.history)
+    copy(dst.actionOnNextBlackMove, b.actionOnNextBlackMove)
+    copy(dst.actionOnNextWhiteMove, b.actionOnNextWhiteMove)
+    copy(dst.fieldSequencesBlack, b.fieldSequencesBlack)
+    copy(dst.fieldSequencesWhite, b.fieldSequencesWhite)
+    copy(dst.nextStone, b.nextStone)
+    copy(dst.liberties, b.liberties)
+    // The groups are copied at their first stones, but they keep the stone chain and the liberties of 'dst'.
+    for pos, grp := range b.fields {
+        if grp == nil {
+            dst.fields[pos] = nil
+            continue
         }
-        // copy .actionOnNextWhiteMove and adjust its context
-        if f := b.actionOnNextWhiteMove[i]; f != nil {
-            cpy.actionOnNextWhiteMove[i] = NewActionFunc(cpy, nil, f.f)
-            _, cpy.actionOnNextWhiteMove[i].context = cpy.getEnvironmentAndContext(i, White)
+        dst.fields[pos] = &dst.groups[grp.first]
+        if grp.first == pos {
+            dst.groups[pos] = *grp
+            dst.groups[pos].next, dst.groups[pos].liberties = dst.nextStone, dst.libertiesAt(pos)
         }
     }
-
-    return cpy
 }
 
 // Create a new, one-stone-group of 'color' at 'pos' and sets its liberties appropriately. 
 // This method does not perform any legality checks or liberty updates for other groups.
 func (b *Board) CreateGroup(pos int, color Color) {
 // TODO: unexport this?
-    newGroup := NewGroup(color)
-    newGroup.Fields.Append(pos)
+    // 'pos' stays the first stone of the group until it is removed, so groups[pos] belongs to no other group.
+    // It may belong to a removed group which Undo puts back, so it is journaled.
+    newGroup := &b.groups[pos]
+    b.journalGroup(newGroup)
+    newGroup.liberties = b.libertiesAt(pos)
+    b.journalLiberties(newGroup)
+    *newGroup = Group{ Color: color, first: pos, numStones: 1, next: b.nextStone, liberties: newGroup.liberties }
+    newGroup.clearLiberties()
     nbours := b.neighboursByPos(pos)
     for _, npos := range nbours {
         if b.fields[npos] == nil {
-            newGroup.Liberties.Append(npos)
+            newGroup.addLiberty(npos)
         }
     }
+    b.journalField(pos)
+    b.nextStone[pos] = pos
     b.fields[pos] = newGroup
+    b.journalLegality(pos, Black)
+    b.journalLegality(pos, White)
     b.actionOnNextBlackMove[pos] = nil
     b.actionOnNextWhiteMove[pos] = nil
 }
 
+// Returns the liberties of the group whose first stone is at 'pos', see Board.groups.
+func (b *Board) libertiesAt(pos int) bitset {
+    words := (len(b.fields)+63)/64 // the length of a liberty bitset, see newBitset
+    return b.liberties[pos*words:(pos+1)*words]
+}
+
 // as CreateGroup
 func (b *Board) CreateGroupByPoint(x, y int, color Color) {
     pos := b.xyToPos(x,y)
@@ -503,7 +584,7 @@ func (b *Board) determineGroupsAtariStatus(groups GroupSlice) (inAtari, notinAta
     inAtari = NewGroupSlice()
     notinAtari = NewGroupSlice()
     for _, group := range groups {
-        if group.Liberties.Length() == 1 {
+        if group.NumLiberties() == 1 {
             inAtari.Push(group)
         } else {
             notinAtari.Push(group)
@@ -516,21 +597,41 @@ func (b *Board) determineGroupsAtariStatus(groups GroupSlice) (inAtari, notinAta
 // the liberty posToXY(libertyPos) from it
 func (b *Board) dropLibertyFromEach(libertyPos int, adjGroups GroupSlice) {
     for _, grp := range adjGroups {
-        grp.Liberties.Remove(libertyPos)
+        b.journalGroup(grp)
+        b.journalLiberty(grp, libertyPos)
+        grp.removeLiberty(libertyPos)
     }
 }
 
-// This is used to cound the area surrounded by black and white.
-// This func assumes that the connected, free fields all have size one.
-func (b *Board) getArea() (black, white int) {
-    black, white = 0, 0
-    for pos := 0; pos < b.boardSize*b.boardSize; pos++ {
-        if b.fields[pos] == nil {
-            _, adjBlack, adjWhite := b.GetEnvironment(pos)
-            blackLen, whiteLen := len(adjBlack), len(adjWhite)
-            if blackLen == 0 && whiteLen > 0 {
+// Returns the area of black and white by Tromp-Taylor rules: the stones of each color and the empty regions
+// which only border on stones of that color. Each empty region is flood filled, so a territory of any size
+// counts for its owner, while the regions bordering on both colors, like the shared liberties of a seki,
+// count for nobody. All stones on the board are considered alive.
+func (b *Board) AreaScore() (black, white int) {
+    stonesBlack, stonesWhite, regionsBlack, regionsWhite := b.countArea(nil, nil)
+    return stonesBlack + regionsBlack, stonesWhite + regionsWhite
+}
+
+// Returns the score of black and white by territory rules: the empty regions which only border on stones of
+// one color, the prisoners and the stones in 'dead', which are removed from the board before counting.
+// The positions in 'dead' which hold no stone are ignored. Unless 'sekiTerritory' is true, the regions which
+// border on a group in seki count for nobody, see sekiStoneSet.
+func (b *Board) TerritoryScore(dead []int, sekiTerritory bool) (black, white int) {
+    deadSet := b.deadStoneSet(dead)
+    var sekiSet []bool
+    if !sekiTerritory {
+        sekiSet = b.sekiStoneSet()
+    }
+    _, _, black, white = b.countArea(deadSet, sekiSet)
+    // black has captured the white prisoners and vice versa
+    prisonersBlack, prisonersWhite := b.numberOfPrisoners()
+    black += prisonersWhite
+    white += prisonersBlack
+    for pos, isDead := range deadSet {
+        if isDead {
+            if b.fields[pos].Color == Black {
                 white++
-            } else if blackLen > 0 && whiteLen == 0 {
+            } else {
                 black++
             }
         }
@@ -538,6 +639,105 @@ func (b *Board) getArea() (black, white int) {
     return
 }
 
+// Returns the positions of 'dead' which hold a stone as a set indexed by the position, nil if there is none.
+func (b *Board) deadStoneSet(dead []int) []bool {
+    var deadSet []bool
+    for _, pos := range dead {
+        if pos >= 0 && pos < len(b.fields) && b.fields[pos] != nil {
+            if deadSet == nil {
+                deadSet = make([]bool, len(b.fields))
+            }
+            deadSet[pos] = true
+        }
+    }
+    return deadSet
+}
+
+// Returns the stones of the groups next to a seki point (see isSekiPoint) as a set indexed by the position,
+// nil if there is none.
+func (b *Board) sekiStoneSet() []bool {
+    var sekiSet []bool
+    for pos := 0; pos < len(b.fields); pos++ {
+        if !b.isSekiPoint(pos) {
+            continue
+        }
+        if sekiSet == nil {
+            sekiSet = make([]bool, len(b.fields))
+        }
+        for _, npos := range b.neighboursByPos(pos) {
+            if grp := b.fields[npos]; grp != nil {
+                grp.DoStones(func(p int) {
+                    sekiSet[p] = true
+                })
+            }
+        }
+    }
+    return sekiSet
+}
+
+// Helper for AreaScore and TerritoryScore. Counts the stones of each color which are not in 'deadSet' and
+// the empty regions which only border on stones of one color, where the dead stones count as empty. Regions
+// which border on a stone in 'sekiSet' count for nobody. Each empty region is flood filled. 'deadSet' and
+// 'sekiSet' are indexed by the position and may be nil.
+func (b *Board) countArea(deadSet, sekiSet []bool) (stonesBlack, stonesWhite, regionsBlack, regionsWhite int) {
+    size := len(b.fields)
+    isEmpty := func(pos int) bool {
+        return b.fields[pos] == nil || (deadSet != nil && deadSet[pos])
+    }
+    visited := make([]bool, size)
+    stack := make([]int, 0, size)
+    for pos := 0; pos < size; pos++ {
+        if !isEmpty(pos) {
+            if b.fields[pos].Color == Black {
+                stonesBlack++
+            } else {
+                stonesWhite++
+            }
+            continue
+        }
+        if visited[pos] {
+            continue
+        }
+        // flood fill the empty region of pos
+        regionSize := 0
+        touchesBlack, touchesWhite, touchesSeki := false, false, false
+        stack = stack[0:1]
+        stack[0] = pos
+        visited[pos] = true
+        for len(stack) > 0 {
+            p := stack[len(stack)-1]
+            stack = stack[0:len(stack)-1]
+            regionSize++
+            for _, npos := range b.neighboursByPos(p) {
+                switch {
+                    case isEmpty(npos):
+                        if !visited[npos] {
+                            visited[npos] = true
+                            stack = stack[0:len(stack)+1]
+                            stack[len(stack)-1] = npos
+                        }
+                    case b.fields[npos].Color == Black:
+                        touchesBlack = true
+                    default:
+                        touchesWhite = true
+                }
+                if sekiSet != nil && sekiSet[npos] && !isEmpty(npos) {
+                    touchesSeki = true
+                }
+            }
+        }
+        if touchesSeki {
+            continue
+        }
+        if touchesBlack && !touchesWhite {
+            regionsBlack += regionSize
+        } else if touchesWhite && !touchesBlack {
+            regionsWhite += regionSize
+        }
+    }
+    return
+}
+
 // Returns the `environment` of 'pos', i.e. the number 'nFree' of free neighbours
 // and GroupSlices 'adj{Black,White}' containing the adjacent {black,white} groups.
 func (b *Board) GetEnvironment(pos int) (nFree int, adjBlack, adjWhite GroupSlice) {
@@ -577,6 +777,26 @@ func (b *Board) getEnvironmentAndContext(pos int, color Color) (int, *boardBound
     return nFree, context
 }
 
+// Returns the context 'c' of an action of another board with the groups of 'b' which have the same first
+// stones. It is only valid until the next call.
+func (b *Board) ownContext(c *boardBoundFuncContext) *boardBoundFuncContext {
+    own := &b.copyContext
+    own.enemiesInAtari = b.ownGroups(own.enemiesInAtari, c.enemiesInAtari)
+    own.enemiesNotInAtari = b.ownGroups(own.enemiesNotInAtari, c.enemiesNotInAtari)
+    own.adjSameColor = b.ownGroups(own.adjSameColor, c.adjSameColor)
+    own.adjOtherColor = b.ownGroups(own.adjOtherColor, c.adjOtherColor)
+    return own
+}
+
+// Helper for ownContext. Replaces the groups in 'own' by the groups of 'b' which correspond to 'groups'.
+func (b *Board) ownGroups(own, groups GroupSlice) GroupSlice {
+    own = own[0:0]
+    for _, grp := range groups {
+        own.Push(&b.groups[grp.first])
+    }
+    return own
+}
+
 // Returs a pointer to the group which occupies (x,y). Nil means that this 
 // field is empty.
 func (b *Board) GetGroupByPoint(x,y int) *Group {
@@ -587,7 +807,7 @@ func (b *Board) GetGroupByPoint(x,y int) *Group {
 // Returns the action which is performed when a stone of the designated color is played at pos on
 // an empty board
 func (b *Board) initialActionGenerator(pos int, color Color) *actionFunc {
-    return NewActionFunc(b, nil, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
+    return NewActionFunc(b, pos, color, nil, func(boardPtr *Board, c *boardBoundFuncContext) (blackUpToDate, whiteUpToDate bool) {
     //return func() (updateBlack, updateWhite bool) {
         boardPtr.CreateGroup(pos, color)
         boardPtr.colorOfNextPlay = !boardPtr.colorOfNextPlay
@@ -605,29 +825,32 @@ func (b *Board) isEyeFillingMove(pos int, color Color) bool {
     } else {
         nFree, adjOtherColor, adjSameColor = b.GetEnvironment(pos)
     }
-    return nFree == 0 && len(adjSameColor) == 1 && len(adjOtherColor) == 0
+    if nFree != 0 || len(adjSameColor) != 1 || len(adjOtherColor) != 0 {
+        return false
+    }
+    // The vital point of a nakade shape is never an eye, even if it looks like one
+    return !b.isNakadeVitalPoint(pos)
 }
 
 // Is it legal to play a stone of color 'color' at 'pos'?
 func (b *Board) IsLegalMove(pos int, color Color) bool {
+    return b.isLegalIgnoringSuperko(pos, color) && !b.violatesSuperko(pos, color)
+}
+
+// Is it legal to play a stone of color 'color' at 'pos' if the superko rule is not taken into account?
+func (b *Board) isLegalIgnoringSuperko(pos int, color Color) bool {
     /*printDbgMsgf("IsLegalMove(%d, %d, %s): fieldSeqW[pos]: %d, fieldSeqB[pos]: %d, currSeq: %d\n", x,y,color, b.fieldSequencesWhite[pos], b.fieldSequencesBlack[pos],
                 b.currentSequence)*/
     if color == Black {
         if b.fieldSequencesBlack[pos] != b.currentSequence {
             b.updateLegalityForBlack(pos, b.currentSequence)
         }
-        if b.actionOnNextBlackMove[pos] != nil {
-            return true
-        }
-    } else {
-        if b.fieldSequencesWhite[pos] != b.currentSequence {
-            b.updateLegalityForWhite(pos, b.currentSequence)
-        }
-        if b.actionOnNextWhiteMove[pos] != nil {
-            return true
-        }
+        return b.actionOnNextBlackMove[pos] != nil
+    }
+    if b.fieldSequencesWhite[pos] != b.currentSequence {
+        b.updateLegalityForWhite(pos, b.currentSequence)
     }
-    return false
+    return b.actionOnNextWhiteMove[pos] != nil
 }
 
 // Joins the groups 'into' and 'from'. The stones from 'from' become stones of
@@ -635,12 +858,17 @@ func (b *Board) IsLegalMove(pos int, color Color) bool {
 // as `` into += from ''. Note that this method does not update 'into's liberties
 // afterwards, you'll have to do this manually if you want it.
 func (b *Board) joinGroups(into, from *Group) {
-    lastFrom := from.Fields.Last()
-    for fromIt := from.Fields.First(); fromIt != lastFrom; fromIt = fromIt.Next() {
-        fpos := fromIt.Value()
+    fpos := from.first
+    for n := 0; n < from.numStones; n++ {
+        b.journalField(fpos)
         b.fields[fpos] = into
+        fpos = b.nextStone[fpos]
     }
-    into.Fields.JoinUnique(from.Fields)
+    b.journalGroup(into)
+    // splice the chain of 'from' into the chain of 'into'
+    b.journalField(into.first)
+    b.nextStone[into.first], b.nextStone[from.first] = b.nextStone[from.first], b.nextStone[into.first]
+    into.numStones += from.numStones
 }
 
 // This is a helper function for Board.calculateIfLegal. It joint the adjacent
@@ -653,8 +881,15 @@ func (b *Board) joinGroupsByPlayAt(playPos int, adjSameColor GroupSlice) {
 
     firstGroup := adjSameColor[0]
     // Add the stone at posToXY(playPos) to the first group.
-    firstGroup.Fields.Append(playPos)
+    b.journalGroup(firstGroup)
+    b.journalField(playPos)
+    b.journalField(firstGroup.first)
+    b.nextStone[playPos] = b.nextStone[firstGroup.first]
+    b.nextStone[firstGroup.first] = playPos
+    firstGroup.numStones++
     b.fields[playPos] = firstGroup
+    b.journalLegality(playPos, Black)
+    b.journalLegality(playPos, White)
     b.actionOnNextBlackMove[playPos] = nil
     b.actionOnNextWhiteMove[playPos] = nil
 
@@ -668,9 +903,9 @@ func (b *Board) joinGroupsByPlayAt(playPos int, adjSameColor GroupSlice) {
 
 // Returns a slice containing the empty fields of b.
 func (b *Board) ListEmptyFields() []*Point {
-    ret := make([]*Point, b.boardSize*b.boardSize)
+    ret := make([]*Point, len(b.fields))
     index := 0
-    for i := 0; i < b.boardSize*b.boardSize; i++ {
+    for i := 0; i < len(b.fields); i++ {
         if b.fields[i] == nil {
             x, y := b.posToXY(i)
             ret[index] = NewPoint(x,y)
@@ -691,7 +926,7 @@ func (b *Board) ListLegalPoints(color Color) []Point {
     return ret
 }
 
-// Returns a slice of legal moves for color 'color' as posses
+// Returns a slice of legal moves for color 'color' as posses, without the moves forbidden by the superko rule
 func (b *Board) listLegalPosses(color Color) []int {
     b.updateLegalMoves(color)
 
@@ -701,10 +936,10 @@ func (b *Board) listLegalPosses(color Color) []int {
     } else {
         actions = b.actionOnNextWhiteMove
     }
-    ret := make([]int, b.boardSize*b.boardSize)
+    ret := make([]int, len(b.fields))
     index := 0
-    for i := 0; i < b.boardSize*b.boardSize; i++ {
-        if actions[i] != nil {
+    for i := 0; i < len(b.fields); i++ {
+        if actions[i] != nil && !b.violatesSuperko(i, color) {
             ret[index] = i
             index++
         }
@@ -715,7 +950,7 @@ func (b *Board) listLegalPosses(color Color) []int {
 // Returns the neighbours of 'pos' as a pos
 func (b *Board) neighboursByPos(pos int) []int {
     // TODO: remove the ..ByPos in the name
-    return neighbourCache[b.boardSize][pos]
+    return b.neighbours[pos]
 }
 
 // Returns the number of {black,white} groups in 'n{black,white}'
@@ -759,6 +994,9 @@ func (b *Board) numberOfStones() (nblack, nwhite int) {
 
 // Play a move of color 'color' at (x,y)
 func (b *Board) PlayMove(x,y int, color Color) (err Error) {
+    if x < 0 || y < 0 || x >= b.width || y >= b.height {
+        return NewIllegalMoveError(x,y, color)
+    }
     pos := b.xyToPos(x,y)
     return b.playMoveByPos(pos, color)
 }
@@ -776,9 +1014,23 @@ func (b *Board) playMoveByPos(pos int, color Color) (err Error) {
     } else {
         action = b.actionOnNextBlackMove[pos]
     }
-    blackUpToDate, whiteUpToDate := action.Call()
+    b.beginJournalEntry()
+    // the captures of the action update the hash themselves, see removeGroup
+    b.hash ^= b.stateHash()
+    ko := b.ko
+    blackUpToDate, whiteUpToDate := action.Call(b)
+    // The ko of the previous move is over, but the actions don't know that the ko point was illegal only
+    // because of the ko, so its legality is updated here.
+    if ko != nil && b.fields[ko.Pos] == nil {
+        if ko.Color == Black {
+            b.updateLegalityForBlack(ko.Pos, b.currentSequence + 1)
+        } else {
+            b.updateLegalityForWhite(ko.Pos, b.currentSequence + 1)
+        }
+    }
     if blackUpToDate || whiteUpToDate {
-        for i := 0; i < b.BoardSize()*b.BoardSize(); i++ {
+        b.journalBump(blackUpToDate, whiteUpToDate)
+        for i := 0; i < len(b.fields); i++ {
             //pX, pY := b.posToXY(i)
             //v, _ := pointToGTPVertex(*NewPoint(pX, pY))
             /*printDbgMsgf("PlayMove after action at %s, bSeq(pos): %d, wSeq(pos): %d, currSeq: %d\n", v, b.fieldSequencesBlack[i], b.fieldSequencesWhite[i],
@@ -795,26 +1047,49 @@ func (b *Board) playMoveByPos(pos int, color Color) (err Error) {
     }
     // Clear the appropriate actionOnNextMove array. 
     b.colorOfNextPlay = !color
+    // a suicide has removed the stone again and has already taken it out of the hash, see removeGroup
+    b.hash ^= zobristStone(pos, color)
+    b.hash ^= b.stateHash()
     b.currentSequence++
+    b.secondLastMove, b.lastMove = b.lastMove, pos
+    b.recordPosition()
 
     return nil
 }
 
 // The player of color 'color' plays a pass.
 func (b *Board) PlayPass(color Color) {
-    b.colorOfNextPlay = !color
+    b.beginJournalEntry()
+    if b.passStones {
+        if color == Black {
+            b.prisonersBlack++
+        } else {
+            b.prisonersWhite++
+        }
+    }
+    b.setColorOfNextPlay(!color)
     b.currentSequence++
+    b.secondLastMove, b.lastMove = b.lastMove, -1
+    b.recordPosition()
 }
 
 // Plays a random move for player 'color' and returns the played vertex.
 func (b *Board) PlayRandomMove(color Color) Vertex {
-    // FIXME: what about sekis and the like????
+    // Moves which fill own eyes or break a seki are never played, see isUnwantedPlayoutMove.
+
+    // The vital point of a nakade shape next to the last move is played first
+    if pos, found := b.nakadeMove(color); found {
+        x, y := b.posToXY(pos)
+        if b.PlayMove(x,y,color) == nil {
+            return *NewVertexByInts(x,y,false)
+        }
+    }
 
     // Collect empty fields
-    emptyPos := make([]int, b.boardSize*b.boardSize)
-    alreadyConsidered := make([]bool, b.boardSize*b.boardSize)
+    emptyPos := make([]int, len(b.fields))
+    alreadyConsidered := make([]bool, len(b.fields))
     index := 0
-    for i := 0; i < b.boardSize*b.boardSize; i++ {
+    for i := 0; i < len(b.fields); i++ {
         if b.fields[i] == nil {
             emptyPos[index] = i
             index++
@@ -824,33 +1099,39 @@ func (b *Board) PlayRandomMove(color Color) Vertex {
     // Now pick a random move and play it if it is legal. randomTries how often only random
     // moves should be picked. If we pick illegal moves more often than randomTries, we determine
     // all legal moves and pick one of them
-    const randomTries = 4
+    randomTries := paramRandomTries.Int()
     if found, pos := b.chooseRandomFavorableMove(emptyPos, color, alreadyConsidered, randomTries); found {
         x, y := b.posToXY(pos)
-        b.PlayMove(x,y,color)
-        return *NewVertexByInts(x,y,false)
+        if b.PlayMove(x,y,color) == nil {
+            return *NewVertexByInts(x,y,false)
+        }
+        alreadyConsidered[pos] = true
     }
     // this didn't work, so we have to look at all legal moves
     legalMoves := b.listLegalPosses(color)
     if len(legalMoves) == 0 {
-        // TODO: panic here? There should be some legal moves....
+        b.PlayPass(color)
         return *NewVertexByInts(0,0,true)
     }
     if found, pos := b.chooseRandomFavorableMove(legalMoves, color, alreadyConsidered, randomTries); found {
         x, y := b.posToXY(pos)
-        b.PlayMove(x,y,color)
-        return *NewVertexByInts(x,y,false)
+        if b.PlayMove(x,y,color) == nil {
+            return *NewVertexByInts(x,y,false)
+        }
+        alreadyConsidered[pos] = true
     }
     // Guessing inside the legal moves didn't yield a favorable one, so we have to go through these systematically
     for _, pos := range legalMoves {
         if !alreadyConsidered[pos] {
-            if b.isEyeFillingMove(pos, color) {
+            if b.isUnwantedPlayoutMove(pos, color) {
                 alreadyConsidered[pos] = true
             } else {
                 // yey! We want to play this move
                 x, y := b.posToXY(pos)
-                b.PlayMove(x,y,color)
-                return *NewVertexByInts(x,y,false)
+                if b.PlayMove(x,y,color) == nil {
+                    return *NewVertexByInts(x,y,false)
+                }
+                alreadyConsidered[pos] = true
             }
         }
     }
@@ -873,7 +1154,7 @@ func (b *Board) playSequence(seq []Move) {
 }
 
 func (b *Board) posToXY(pos int) (x, y int) {
-    return posToXY(pos, b.boardSize)
+    return posToXY(pos, b.width)
 }
 
 // Removes the group which occupies 'pos', if there is any, and updates b.emptyFields.
@@ -890,17 +1171,17 @@ func (b *Board) RemoveGroupByPos(pos int) {
 // because of the removal of 'group'.
 // This method does not alter legalities for any fields.
 func (b *Board) removeGroup(group *Group) {
-    last := group.Fields.Last()
-    adjGroups := NewGroupSlice()
+    adjGroups := b.removedNeighbours[0:0]
     var prisoners *int
     if group.Color == Black {
         prisoners = &b.prisonersBlack
     } else {
         prisoners = &b.prisonersWhite
     }
-    for e := group.Fields.First(); e != last; e = e.Next() {
+    // the chain of the group stays intact while its stones are removed
+    pos := group.first
+    for n := 0; n < group.numStones; n++ {
         // Collect adjacend groups so that we can update their liberties later
-        pos := e.Value()
         //x, y := b.posToXY(pos)
         nbours := b.neighboursByPos(pos)
         for _, npos := range nbours {
@@ -908,14 +1189,18 @@ func (b *Board) removeGroup(group *Group) {
                 adjGroups.PushUnique(grp)
             }
         }
+        b.journalField(pos)
         b.fields[pos] = nil
+        b.hash ^= zobristStone(pos, group.Color)
         // count prisoners
         *prisoners++
+        pos = b.nextStone[pos]
     }
     // Update the liberties of the adjacent groups.
     for _, grp := range adjGroups {
         b.updateGroupLiberties(grp)
     }
+    b.removedNeighbours = adjGroups
 }
 
 // Resets the board. The state is the same as after creating a new instance.
@@ -927,14 +1212,24 @@ func (b *Board) Reset() {
     b.currentSequence = 0
     b.prisonersWhite = 0
     b.prisonersBlack = 0
-    for i := 0; i < b.boardSize*b.boardSize; i++ {
+    b.lastMove = -1
+    b.secondLastMove = -1
+    for i := 0; i < len(b.fields); i++ {
         b.fields[i] = nil
-        b.actionOnNextBlackMove[i] = b.initialActionGenerator(i, Black)
-        b.actionOnNextWhiteMove[i] = b.initialActionGenerator(i, White)
+        if len(b.neighbours[i]) == 0 {
+            // the only field of a 1x1 board, where a stone never has a liberty
+            b.actionOnNextBlackMove[i], b.actionOnNextWhiteMove[i] = nil, nil
+        } else {
+            b.actionOnNextBlackMove[i] = b.initialActionGenerator(i, Black)
+            b.actionOnNextWhiteMove[i] = b.initialActionGenerator(i, White)
+        }
         b.fieldSequencesBlack[i] = 0
         b.fieldSequencesWhite[i] = 0
     }
-
+    b.hash = b.computeHash()
+    b.journal = b.journal[0:0]
+    b.history = b.history[0:0]
+    b.recordPosition()
 }
 
 // The player whose turn it is plays a stone (x,y). If an error occurs (such as that 
@@ -954,17 +1249,18 @@ func (b *Board) TurnPlayPass() {
 // fields.
 func (b *Board) updateGroupLiberties(group *Group) {
     // this is expensive... TODO(David): can this be made faster?
-    group.Liberties.Clear()
-    last := group.Fields.Last()
-    for it := group.Fields.First(); it != last; it = it.Next() {
-        //x, y := b.posToXY(it.Value())
-        nbours := b.neighboursByPos(it.Value())
+    b.journalGroup(group)
+    b.journalLiberties(group)
+    group.clearLiberties()
+    pos := group.first
+    for n := 0; n < group.numStones; n++ {
+        nbours := b.neighboursByPos(pos)
         for _, npos := range nbours {
-            //npos := b.xyToPos(p.X, p.Y)
             if b.fields[npos] == nil {
-                group.Liberties.AppendUnique(npos)
+                group.addLiberty(npos)
             }
         }
+        pos = b.nextStone[pos]
     }
 }
 
@@ -987,6 +1283,7 @@ func (b *Board) updateLegalityFor(pos int, whichSequence uint32) {
 // Checks if a black move at 'pos' is legal and makes sure the state of the board remains correct. 
 // 'whichSequence' denotes the sequence to set in b.fieldSequences{Black,White}.
 func (b *Board) updateLegalityForBlack(pos int, whichSequence uint32) {
+    b.journalLegality(pos, Black)
     _, b.actionOnNextBlackMove[pos] = b.calculateIfLegal(pos, Black)
     b.fieldSequencesBlack[pos] = whichSequence
 }
@@ -994,6 +1291,7 @@ func (b *Board) updateLegalityForBlack(pos int, whichSequence uint32) {
 // Checks if a white move at 'pos' is legal and makes sure the state of the board remains correct. 
 // 'whichSequence' denotes the sequence to set in b.fieldSequences{Black,White}.
 func (b *Board) updateLegalityForWhite(pos int, whichSequence uint32) {
+    b.journalLegality(pos, White)
     _, b.actionOnNextWhiteMove[pos] = b.calculateIfLegal(pos, White)
     b.fieldSequencesWhite[pos] = whichSequence
 }
@@ -1005,9 +1303,7 @@ func (b *Board) updateLegalityForAdjacentGroups(adjGroups GroupSlice, whichSeque
     //defer printDbgMsgf("returned from updateLegalityForAdjacentGroups\n") // </DBG>
 
     for _, grp := range adjGroups {
-        lastLib := grp.Liberties.Last()
-        for itLib := grp.Liberties.First(); itLib != lastLib; itLib = itLib.Next() {
-            lpos := itLib.Value()
+        for lpos := grp.liberties.next(0); lpos >= 0; lpos = grp.liberties.next(lpos+1) {
 
             /*x, y := b.posToXY(lpos)
             v, _ := pointToGTPVertex(*NewPoint(x, y))
@@ -1045,9 +1341,7 @@ func (b *Board) updateLegalityForFreeNeighboursOf(pos int, whichSequence uint32,
 func (b *Board) updateLegalityForLibertiesOf(group *Group, whichSequence uint32, alreadyUpdated []bool) {
     //printDbgMsg("in Board.updateLegalityForLibertiesOf\n") // <DBG/>
     //defer printDbgMsg("returned from Board.updateLegalityForLibertiesOf\n") // <DBG/>
-    lastLib := group.Liberties.Last()
-    for itLib := group.Liberties.First(); itLib != lastLib; itLib = itLib.Next() {
-        lpos := itLib.Value()
+    for lpos := group.liberties.next(0); lpos >= 0; lpos = group.liberties.next(lpos+1) {
         if !alreadyUpdated[lpos] {
             b.updateLegalityFor(lpos, whichSequence)
             alreadyUpdated[lpos] = true
@@ -1060,10 +1354,7 @@ func (b *Board) updateLegalityForLibertiesOf(group *Group, whichSequence uint32,
 func (b *Board) updateLegalityForLibertiesOfExcept(group *Group, exceptPos int, whichSequence uint32, alreadyUpdated []bool) {
     //printDbgMsg("in Board.updateLegalityForLibertiesOfExcept\n") // <DBG/>
     //defer printDbgMsg("returned from Board.updateLegalityForLibertiesOfExcept\n") // <DBG/>
-    lastLib := group.Liberties.Last()
-    for itLib := group.Liberties.First(); itLib != lastLib; itLib = itLib.Next() {
-        //exceptPos := b.xyToPos(exceptX, exceptY)
-        lpos := itLib.Value()
+    for lpos := group.liberties.next(0); lpos >= 0; lpos = group.liberties.next(lpos+1) {
         if exceptPos != lpos && !alreadyUpdated[lpos] {
             b.updateLegalityFor(lpos, whichSequence)
             alreadyUpdated[lpos] = true
@@ -1075,13 +1366,13 @@ func (b *Board) updateLegalityForLibertiesOfExcept(group *Group, exceptPos int,
 func (b *Board) updateLegalMoves(color Color) {
 // TODO: write tests for this...
     if color == Black {
-        for i := 0; i < b.BoardSize()*b.BoardSize(); i++ {
+        for i := 0; i < len(b.fields); i++ {
             if b.fields[i] == nil && b.fieldSequencesBlack[i] != b.currentSequence {
                 b.updateLegalityForBlack(i, b.currentSequence)
             }
         }
     } else {
-        for i := 0; i < b.BoardSize()*b.BoardSize(); i++ {
+        for i := 0; i < len(b.fields); i++ {
             if b.fields[i] == nil && b.fieldSequencesWhite[i] != b.currentSequence {
                 b.updateLegalityForWhite(i, b.currentSequence)
             }
@@ -1090,24 +1381,47 @@ func (b *Board) updateLegalMoves(color Color) {
 }
 
 func (b *Board) xyToPos(x, y int) int {
-    return xyToPos(x,y, b.boardSize)
+    return xyToPos(x,y, b.width)
 }
 
 // ##################### Board helper functions ##########################
 // Creates a new, initial board of size 'boardsize'.
 func NewBoard(boardsize int) *Board {
+    return NewRectangularBoard(boardsize, boardsize)
+}
+
+// Creates a new, initial board with 'width' columns and 'height' rows, both between 1 and MaxBoardSize. Other
+// sizes panic with a board size error, the front ends have to check them before, see gtpboardsize and ParseSGF.
+func NewRectangularBoard(width, height int) *Board {
+    if width < 1 || height < 1 || width > MaxBoardSize || height > MaxBoardSize {
+        panic(NewBoardSizeError(width, height))
+    }
+    l := width*height
     ret := &Board{ 
-        fields: make([]*Group, boardsize*boardsize),
-        actionOnNextBlackMove: make([]*actionFunc, boardsize*boardsize),
-        actionOnNextWhiteMove: make([]*actionFunc, boardsize*boardsize),
-        boardSize: boardsize,
-        fieldSequencesBlack: make([]uint32, boardsize*boardsize),
-        fieldSequencesWhite: make([]uint32, boardsize*boardsize),
+        fields: make([]*Group, l),
+        groups: make([]Group, l),
+        liberties: make(bitset, l*((l+63)/64)),
+        nextStone: make([]int, l),
+        actionOnNextBlackMove: make([]*actionFunc, l),
+        actionOnNextWhiteMove: make([]*actionFunc, l),
+        width: width,
+        height: height,
+        neighbours: neighbourTable(width, height),
+        fieldSequencesBlack: make([]uint32, l),
+        fieldSequencesWhite: make([]uint32, l),
+        history: make([]uint64, 0, 2*l),
+        journaling: true,
     }
     ret.Reset()
     return ret
 }
 
+// Creates a new error for a board with 'width' columns and 'height' rows, which is not supported.
+func NewBoardSizeError(width, height int) (err Error) {
+    return NewError(fmt.Sprintf("a board with %dx%d fields is not supported, the sides must be between 1 and %d",
+                                width, height, MaxBoardSize), ErrUnacceptableBoardSize)
+}
+
 // Creates a new FieldOccupiedError, indicating that (x,y) is alrady used.
 func NewFieldOccupiedError(x, y int) (err Error) {
     return NewError(fmt.Sprintf("(%d,%d) is already occupied", x, y), ErrFieldOccupied)
@@ -1126,16 +1440,20 @@ func NewFieldLegalityCheckedMoreThanOnceError(msg string) Error {
 // ################################################################################
 // ########################### common helper funcs ################################
 // ################################################################################
-func calculateNeighbours(x, y, boardsize int) []Point {
+// Returns the neighbours of (x,y) on a board with 'width' columns and 'height' rows. On a board which is only
+// one field wide or high, the fields have no neighbours in that direction.
+func calculateNeighbours(x, y, width, height int) []Point {
     ret := make([]Point, 4)
     count := 0
     switch x {
+        case width-1:
+            if width > 1 {
+                ret[count] = Point{ width - 2 , y }
+                count++
+            }
         case 0:
             ret[count] = Point{ 1, y }
             count++
-        case boardsize-1:
-            ret[count] = Point{ boardsize - 2 , y }
-            count++
         default:
             ret[count] = Point{ x-1, y }
             count++
@@ -1143,12 +1461,14 @@ func calculateNeighbours(x, y, boardsize int) []Point {
             count++
     }
     switch y {
+        case height-1:
+            if height > 1 {
+                ret[count] = Point{ x, height - 2 }
+                count++
+            }
         case 0:
             ret[count] = Point{ x, 1 }
             count++
-        case boardsize-1:
-            ret[count] = Point{ x, boardsize - 2 }
-            count++
         default:
             ret[count] = Point{ x, y-1 }
             count++
@@ -1158,12 +1478,34 @@ func calculateNeighbours(x, y, boardsize int) []Point {
     return ret[0:count]
 }
 
-func xyToPos(x, y, boardSize int) int {
-    return boardSize*y + x
+// Returns the neighbours of each pos on a board with 'width' columns and 'height' rows. The table is computed
+// once for each dimension and shared by all boards.
+func neighbourTable(width, height int) []([]int) {
+    neighbourCacheLock.Lock()
+    defer neighbourCacheLock.Unlock()
+    key := width<<16 | height
+    if table, ok := neighbourCache[key]; ok {
+        return table
+    }
+    table := make([]([]int), width*height)
+    for pos := range table {
+        x, y := posToXY(pos, width)
+        nbourPoints := calculateNeighbours(x, y, width, height)
+        table[pos] = make([]int, len(nbourPoints))
+        for i, p := range nbourPoints {
+            table[pos][i] = xyToPos(p.X, p.Y, width)
+        }
+    }
+    neighbourCache[key] = table
+    return table
+}
+
+func xyToPos(x, y, width int) int {
+    return width*y + x
 }
 
-func posToXY(pos, boardsize int) (x, y int) {
-    return pos%boardsize, pos/boardsize
+func posToXY(pos, width int) (x, y int) {
+    return pos%width, pos/width
 }
 
 
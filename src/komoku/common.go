@@ -37,6 +37,11 @@ const (
     ErrFieldLegalityCheckedMoreThanOnce;
     ErrGTPNotImplemented;
     ErrGTPIllegalCommand;
+    ErrSGFSyntaxError;
+    ErrPatternFileError;
+    ErrParameterError;
+    ErrSolverError;
+    ErrUndo;
 )
 
 // ################ interfaces ##############
@@ -179,6 +184,10 @@ var charDigit = map[string]int {
 
 var coordinateChars string = "ABCDEFGHJKLMNOPQRSTUVWXYZ"
 
+// The letters of the SGF coordinates: 'a' to 'z' for the first 26 columns and rows, 'A' to 'Z' for the
+// following ones on boards up to 52x52.
+const sgfCoordinateChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
 // TODO: there must be a better way to do this...
 func CharToDigit(c string) (digit int, err Error) {
     digit, ok := charDigit[c]
@@ -190,7 +199,7 @@ func CharToDigit(c string) (digit int, err Error) {
 
 // TODO: ...and for this also...
 func DigitToChar(digit int) (char string, err Error) {
-    if digit < 0 || digit > len(coordinateChars) {
+    if digit < 0 || digit >= len(coordinateChars) {
         return "", NewInvalidCoordinateDigitError(digit)
     }
     return coordinateChars[digit:digit+1], err
@@ -290,6 +299,18 @@ func pointToGTPVertex(p Point) (ret string, ok bool) {
     return ret, true
 }
 
+// Formats the result of a game which black wins by 'margin' points (negative if white wins) as in GTP,
+// e.g. "B+2.5", "W+0.5" or "0" for a jigo.
+func formatGameResult(margin float) string {
+    switch {
+        case margin > 0:
+            return fmt.Sprintf("B+%g", margin)
+        case margin < 0:
+            return fmt.Sprintf("W+%g", -margin)
+    }
+    return "0"
+}
+
 // rel is a path relative to the executable which is run. This func returns the absolute path.
 func relPathToAbs(rel string) string {
     wdir, _ := os.Getwd()
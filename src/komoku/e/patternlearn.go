@@ -0,0 +1,61 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+// Learns the strengths of move features from a corpus of SGF game records and writes them to a
+// weight file, which can be loaded with the GTP command komoku-loadpatterns.
+//
+// usage: patternlearn [-o weightfile] [-i iterations] game1.sgf game2.sgf ...
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "./komoku"
+)
+
+var output = flag.String("o", "patterns.txt", "the file the weights are written to")
+var iterations = flag.Int("i", 20, "the maximal number of MM iterations")
+var epsilon = flag.Float("e", 0.001, "stop as soon as no strength changes by more than this (relative) amount")
+
+func main() {
+    flag.Parse()
+    if flag.NArg() == 0 {
+        fmt.Fprintf(os.Stderr, "usage: patternlearn [-o weightfile] [-i iterations] [-e epsilon] sgffiles...\n")
+        os.Exit(1)
+    }
+
+    trainer := komoku.NewMMTrainer()
+    for _, filename := range flag.Args() {
+        game, err := komoku.ReadSGFFile(filename)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "skipping %s: %s\n", filename, err)
+            continue
+        }
+        if added := trainer.AddGame(game); added < len(game.Moves) {
+            fmt.Fprintf(os.Stderr, "%s: only %d of %d moves used\n", filename, added, len(game.Moves))
+        }
+    }
+    fmt.Printf("collected %d positions\n", trainer.NumPositions())
+
+    for i := 0; i < *iterations; i++ {
+        change := trainer.Iterate()
+        fmt.Printf("iteration %2d: max. relative change %f\n", i+1, change)
+        if change < *epsilon {
+            break
+        }
+    }
+
+    weights := trainer.Weights()
+    if err := weights.WriteFile(*output); err != nil {
+        fmt.Fprintf(os.Stderr, "cannot write %s: %s\n", *output, err)
+        os.Exit(1)
+    }
+    fmt.Printf("wrote %d features to %s\n", weights.NumFeatures(), *output)
+}
@@ -0,0 +1,84 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+// Solves a game on a tiny board exactly and prints the result with perfect play and the best move.
+// The position is either the empty board or the final position of the main line of an SGF file.
+//
+// usage: solvegame [-size boardsize] [-komi komi] [-rules rules] [-handicap stones] [-n nodes] [game.sgf]
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "./komoku"
+)
+
+var boardsize = flag.Int("size", 3, "the board size if no SGF file is given")
+var komi = flag.Float("komi", 0.0, "the komi if no SGF file is given")
+var rules = flag.String("rules", "chinese", "the ruleset, one of default, chinese, japanese, aga, new_zealand and tromp-taylor")
+var handicap = flag.Int("handicap", 0, "the number of handicap stones, which decides the handicap compensation")
+var nodes = flag.Int("n", 10000000, "the maximal number of nodes to search")
+
+func main() {
+    flag.Parse()
+    ruleset, ok := komoku.RulesetByName(*rules)
+    if !ok {
+        fmt.Fprintf(os.Stderr, "unknown ruleset %s\n", *rules)
+        os.Exit(1)
+    }
+    board := komoku.NewBoard(*boardsize)
+    gameKomi := *komi
+    toMove := komoku.Color(komoku.Black)
+    if flag.NArg() > 0 {
+        game, err := komoku.ReadSGFFile(flag.Arg(0))
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "cannot read %s: %s\n", flag.Arg(0), err)
+            os.Exit(1)
+        }
+        board = komoku.NewBoard(game.BoardSize)
+        board.SetRules(ruleset)
+        gameKomi = game.Komi
+        for _, mv := range game.Setup {
+            board.PlayMove(mv.Vertex.X, mv.Vertex.Y, mv.Color)
+        }
+        for _, mv := range game.Moves {
+            if mv.Vertex.Pass {
+                board.PlayPass(mv.Color)
+            } else if err := board.PlayMove(mv.Vertex.X, mv.Vertex.Y, mv.Color); err != nil {
+                fmt.Fprintf(os.Stderr, "illegal move in %s: %s\n", flag.Arg(0), err)
+                os.Exit(1)
+            }
+            toMove = !mv.Color
+        }
+    }
+
+    score, best, solved, err := board.SolveGame(toMove, ruleset, gameKomi, *handicap, *nodes)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s\n", err)
+        os.Exit(1)
+    }
+    if !solved {
+        fmt.Printf("unknown: the position could not be solved with %d nodes\n", *nodes)
+        os.Exit(2)
+    }
+    margin := score
+    bestMove := "pass"
+    if best >= 0 {
+        bestMove = fmt.Sprintf("%d,%d", best % board.Width(), best / board.Width())
+    }
+    switch {
+        case margin > 0:
+            fmt.Printf("B+%g, best move of %s: %s\n", margin, toMove, bestMove)
+        case margin < 0:
+            fmt.Printf("W+%g, best move of %s: %s\n", -margin, toMove, bestMove)
+        default:
+            fmt.Printf("jigo, best move of %s: %s\n", toMove, bestMove)
+    }
+}
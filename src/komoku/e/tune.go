@@ -0,0 +1,66 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+// Tunes the parameters of komoku by SPSA self-play and writes the tuned values to a config file,
+// which can be loaded with the GTP command komoku-loadparams.
+//
+// usage: tune [-o paramfile] [-l paramfile] [-n iterations] [-s simulations] [-g games] [-size boardsize] [names...]
+// If no parameter names are given, all parameters are tuned.
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "./komoku"
+)
+
+var output = flag.String("o", "komoku.params", "the file the tuned parameters are written to")
+var load = flag.String("l", "", "a parameter file to start from")
+var iterations = flag.Int("n", 100, "the number of SPSA iterations")
+var simulations = flag.Int("s", 200, "the number of simulations per move")
+var games = flag.Int("g", 8, "the number of games per iteration")
+var boardsize = flag.Int("size", 9, "the board size of the self-play games")
+
+func main() {
+    flag.Parse()
+    if *load != "" {
+        if err := komoku.LoadParameters(*load); err != nil {
+            fmt.Fprintf(os.Stderr, "cannot load %s: %s\n", *load, err)
+            os.Exit(1)
+        }
+    }
+
+    var params []*komoku.Parameter
+    if flag.NArg() == 0 {
+        params = komoku.ListParameters()
+    } else {
+        params = make([]*komoku.Parameter, flag.NArg())
+        for i, name := range flag.Args() {
+            p, ok := komoku.GetParameter(name)
+            if !ok {
+                fmt.Fprintf(os.Stderr, "unknown parameter %s\n", name)
+                os.Exit(1)
+            }
+            params[i] = p
+        }
+    }
+
+    tuner := komoku.NewTuner(params)
+    tuner.Simulations = *simulations
+    tuner.GamesPerIteration = *games
+    tuner.BoardSize = *boardsize
+    tuner.Run(*iterations)
+
+    if err := komoku.SaveParameters(*output); err != nil {
+        fmt.Fprintf(os.Stderr, "cannot write %s: %s\n", *output, err)
+        os.Exit(1)
+    }
+    fmt.Printf("wrote the tuned parameters to %s\n", *output)
+}
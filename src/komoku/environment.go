@@ -19,6 +19,8 @@ package komoku
 type Environment struct {
     *Game
     komi float
+    rules *Ruleset
+    handicap int // the number of handicap stones of the game
 }
 
 // ##################### Environment methods ##########################
@@ -27,13 +29,48 @@ func (e *Environment) SetKomi(newKomi float) {
     e.komi = newKomi
 }
 
+// Sets the number of handicap stones, which decides the handicap compensation of the ruleset.
+func (e *Environment) SetHandicap(handicap int) {
+    e.handicap = handicap
+}
+
+// Sets the ruleset of the game. It also applies to boards which are set up later by SetBoard.
+func (e *Environment) SetRules(r *Ruleset) {
+    e.rules = r
+    e.Game.Board.SetRules(r)
+}
+
+// Replaces the board of the game by 'b', which starts a new game without moves and handicap, and applies the
+// ruleset to it.
+func (e *Environment) SetBoard(b *Board) {
+    b.SetRules(e.rules)
+    e.Game.Board = b
+    e.Game.sequence.Resize(0, 0)
+    e.Game.SetDeadStones(nil)
+    e.handicap = 0
+}
+
+// Returns the score of black minus the score of white on 'b' according to the ruleset, the komi and the
+// handicap of the game. All stones on 'b' are considered alive.
+func (e *Environment) ScoreDifference(b GoBoard) float {
+    return e.rules.PlayoutScoreDifference(b, e.komi, e.handicap)
+}
+
+// Like ScoreDifference, but scores the board of the game with the dead stones marked by Game.SetDeadStones
+// removed.
+func (e *Environment) FinalScoreDifference() float {
+    return e.rules.ScoreDifference(e.Game.Board, e.komi, e.handicap, e.Game.DeadStones())
+}
+
 // ##################### Environment helper functions ##########################
 
 func NewEnvironment(boardsize int) *Environment {
-    return &Environment{
+    e := &Environment{
         Game: NewGame(boardsize),
         komi: DefaultKomi,
     }
+    e.SetRules(DefaultRules)
+    return e
 }
 
 
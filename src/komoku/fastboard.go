@@ -0,0 +1,481 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * A board for playouts. FastBoard stores the stones in bitsets and the liberties of each group as
+ * pseudo-liberties: every pair of a stone and an adjacent empty field counts once. A group is in atari
+ * if all of its pseudo-liberties are the same field, which the sum and the sum of squares of their
+ * positions tell without listing them. Unlike Board, FastBoard neither caches legal moves nor checks
+ * superko, so it only supports the playouts, while Board stays the reference implementation.
+ */
+
+package komoku
+
+import (
+    "os"
+    "rand"
+)
+
+// ################################################################################
+// ########################### FastBoard ##########################################
+// ################################################################################
+
+// A board for playouts, see the top of this file. The groups are circular lists of their stones in
+// 'next', and the data of a group is stored at the position of its root stone.
+type FastBoard struct {
+    width, height int
+    neighbours [][]int // shared by all boards of the same dimensions, see neighbourTable
+    black, white bitset // the stones of each color
+    root []int // the root stone of the group of each stone, -1 for empty fields
+    next []int // the next stone of the group of each stone
+    size []int // the number of stones of each group, indexed by its root
+    libs []int // the number of pseudo-liberties of each group, indexed by its root
+    libSum []int64 // the sum of the positions of the pseudo-liberties, indexed by the root
+    libSumSquares []int64 // the sum of their squares, indexed by the root
+    koPos int // a play of koColor at koPos is forbidden by the ko rule, -1 if there is no ko
+    koColor Color
+    colorOfNextPlay Color
+    prisonersBlack int // number of black prisoners
+    prisonersWhite int // number of white prisoners
+    lastMove int // pos of the last move, -1 if it was a pass or if there is none
+    secondLastMove int
+    hash uint64 // the same hash as Board.Hash
+    passStones bool // see Ruleset
+    suicide bool // see Ruleset
+    rand *rand.Rand
+    empty []int // buffer for PlayRandomMove
+}
+
+// ##################### FastBoard methods ##########################
+
+// Records 'lib' as a pseudo-liberty of the group with the root 'r'.
+func (b *FastBoard) addLiberty(r, lib int) {
+    b.libs[r]++
+    b.libSum[r] += int64(lib)
+    b.libSumSquares[r] += int64(lib)*int64(lib)
+}
+
+// Removes the pseudo-liberty 'lib' from the group with the root 'r'.
+func (b *FastBoard) removeLiberty(r, lib int) {
+    b.libs[r]--
+    b.libSum[r] -= int64(lib)
+    b.libSumSquares[r] -= int64(lib)*int64(lib)
+}
+
+// Returns the area of black and white like Board.AreaScore.
+func (b *FastBoard) AreaScore() (black, white int) {
+    size := len(b.root)
+    visited := newBitset(size)
+    stack := make([]int, 0, size)
+    for pos := 0; pos < size; pos++ {
+        if b.root[pos] >= 0 || visited.has(pos) {
+            continue
+        }
+        // flood fill the empty region of pos
+        regionSize := 0
+        touchesBlack, touchesWhite := false, false
+        stack = stack[0:1]
+        stack[0] = pos
+        visited.set(pos)
+        for len(stack) > 0 {
+            p := stack[len(stack)-1]
+            stack = stack[0:len(stack)-1]
+            regionSize++
+            for _, npos := range b.neighbours[p] {
+                switch {
+                    case b.black.has(npos):
+                        touchesBlack = true
+                    case b.white.has(npos):
+                        touchesWhite = true
+                    case !visited.has(npos):
+                        visited.set(npos)
+                        stack = stack[0:len(stack)+1]
+                        stack[len(stack)-1] = npos
+                }
+            }
+        }
+        if touchesBlack && !touchesWhite {
+            black += regionSize
+        } else if touchesWhite && !touchesBlack {
+            white += regionSize
+        }
+    }
+    stonesBlack, stonesWhite := b.numberOfStones()
+    return black + stonesBlack, white + stonesWhite
+}
+
+// Removes the group of the stone at 'pos' from the board and counts its stones as prisoners.
+func (b *FastBoard) capture(pos int) {
+    color := Color(b.white.has(pos))
+    stones := b.stonesOf(color)
+    prisoners := &b.prisonersWhite
+    if color == Black {
+        prisoners = &b.prisonersBlack
+    }
+    // clear the stones first, so that only the surrounding groups gain liberties
+    p := pos
+    for {
+        stones.unset(p)
+        b.root[p] = -1
+        b.hash ^= zobristStone(p, color)
+        *prisoners++
+        if p = b.next[p]; p == pos {
+            break
+        }
+    }
+    for {
+        for _, npos := range b.neighbours[p] {
+            if r := b.root[npos]; r >= 0 {
+                b.addLiberty(r, p)
+            }
+        }
+        if p = b.next[p]; p == pos {
+            break
+        }
+    }
+}
+
+// Returns the color of the player who plays the next turn.
+func (b *FastBoard) ColorOfNextPlay() Color {
+    return b.colorOfNextPlay
+}
+
+// Returns an independent copy of 'b' with its own random number generator.
+func (b *FastBoard) Copy() *FastBoard {
+    sec, nsec, _ := os.Time()
+    cpy := *b
+    cpy.black = b.black.copy()
+    cpy.white = b.white.copy()
+    cpy.root = copyInts(b.root)
+    cpy.next = copyInts(b.next)
+    cpy.size = copyInts(b.size)
+    cpy.libs = copyInts(b.libs)
+    cpy.libSum = make([]int64, len(b.libSum))
+    copy(cpy.libSum, b.libSum)
+    cpy.libSumSquares = make([]int64, len(b.libSumSquares))
+    copy(cpy.libSumSquares, b.libSumSquares)
+    cpy.rand = rand.New(rand.NewSource(sec+nsec))
+    cpy.empty = make([]int, len(b.root))
+    return &cpy
+}
+
+// Like Copy, but returns the copy as a GoBoard.
+func (b *FastBoard) Clone() GoBoard {
+    return b.Copy()
+}
+
+// Returns the Zobrist hash of the position, which is the same as the hash of a Board with the same position.
+func (b *FastBoard) Hash() uint64 {
+    return b.hash
+}
+
+// Returns the number of rows.
+func (b *FastBoard) Height() int {
+    return b.height
+}
+
+// Is the group with the root 'r' in atari? This is the case if all of its pseudo-liberties are the same
+// field, i.e. if the variance of their positions is 0.
+func (b *FastBoard) inAtari(r int) bool {
+    return int64(b.libs[r])*b.libSumSquares[r] == b.libSum[r]*b.libSum[r]
+}
+
+// Is 'pos' an eye of 'color'? Playouts don't fill their own eyes. Unlike Board.isEyeFillingMove, the
+// neighbours may belong to different groups, as long as none of them is in atari.
+func (b *FastBoard) isEye(pos int, color Color) bool {
+    stones := b.stonesOf(color)
+    for _, npos := range b.neighbours[pos] {
+        if !stones.has(npos) || b.inAtari(b.root[npos]) {
+            return false
+        }
+    }
+    return true
+}
+
+// Is it legal to play a stone of color 'color' at 'pos'? The same moves are legal as on a Board
+// without superko.
+func (b *FastBoard) IsLegalMove(pos int, color Color) bool {
+    if b.root[pos] >= 0 || (pos == b.koPos && color == b.koColor) {
+        return false
+    }
+    friends := false
+    for _, npos := range b.neighbours[pos] {
+        r := b.root[npos]
+        switch {
+            case r < 0:
+                return true
+            case b.stonesOf(color).has(npos):
+                // joining a group which keeps a liberty
+                if !b.inAtari(r) {
+                    return true
+                }
+                friends = true
+            case b.inAtari(r):
+                // capturing
+                return true
+        }
+    }
+    // only the suicide of more than one stone may be legal
+    return friends && b.suicide
+}
+
+// Returns a slice of legal moves for color 'color' as posses.
+func (b *FastBoard) listLegalPosses(color Color) []int {
+    ret := make([]int, 0, len(b.root))
+    for pos := 0; pos < len(b.root); pos++ {
+        if b.IsLegalMove(pos, color) {
+            ret = ret[0:len(ret)+1]
+            ret[len(ret)-1] = pos
+        }
+    }
+    return ret
+}
+
+// Joins the groups with the roots 'r1' and 'r2' and returns the root of the joined group. The stones of
+// the smaller group move to the larger one.
+func (b *FastBoard) merge(r1, r2 int) int {
+    if r1 == r2 {
+        return r1
+    }
+    if b.size[r1] < b.size[r2] {
+        r1, r2 = r2, r1
+    }
+    p := r2
+    for {
+        b.root[p] = r1
+        if p = b.next[p]; p == r2 {
+            break
+        }
+    }
+    b.next[r1], b.next[r2] = b.next[r2], b.next[r1]
+    b.size[r1] += b.size[r2]
+    b.libs[r1] += b.libs[r2]
+    b.libSum[r1] += b.libSum[r2]
+    b.libSumSquares[r1] += b.libSumSquares[r2]
+    return r1
+}
+
+// Returns the number of prisoners for black and white.
+func (b *FastBoard) numberOfPrisoners() (nblack, nwhite int) {
+    return b.prisonersBlack, b.prisonersWhite
+}
+
+// Returns the number of {black,white} stones on the board.
+func (b *FastBoard) numberOfStones() (nblack, nwhite int) {
+    return b.black.count(), b.white.count()
+}
+
+// Places a stone of 'color' at 'pos' and joins it with the adjacent stones of the same color, without
+// capturing. Returns the root of the group of the new stone.
+func (b *FastBoard) placeStone(pos int, color Color) int {
+    b.stonesOf(color).set(pos)
+    b.root[pos], b.next[pos], b.size[pos] = pos, pos, 1
+    b.libs[pos], b.libSum[pos], b.libSumSquares[pos] = 0, 0, 0
+    b.hash ^= zobristStone(pos, color)
+    stones := b.stonesOf(color)
+    for _, npos := range b.neighbours[pos] {
+        if nr := b.root[npos]; nr < 0 {
+            b.addLiberty(pos, npos)
+        } else {
+            b.removeLiberty(nr, pos)
+        }
+    }
+    for _, npos := range b.neighbours[pos] {
+        if stones.has(npos) {
+            b.merge(b.root[pos], b.root[npos])
+        }
+    }
+    return b.root[pos]
+}
+
+// Fills 'b' again with the position, the side to move, the ko, the prisoners and the rules of 'board', which
+// has the dimensions of 'b'. Unlike NewFastBoard, this allocates nothing, see AI.playoutBoard.
+func (b *FastBoard) fill(board *Board) {
+    b.black.clear()
+    b.white.clear()
+    for pos := range b.root {
+        b.root[pos] = -1
+    }
+    b.koPos = -1
+    b.colorOfNextPlay = board.colorOfNextPlay
+    b.prisonersBlack, b.prisonersWhite = board.prisonersBlack, board.prisonersWhite
+    b.lastMove, b.secondLastMove = board.lastMove, board.secondLastMove
+    b.passStones, b.suicide = board.passStones, board.suicide
+    b.hash = 0
+    for pos := range b.root {
+        if grp := board.fields[pos]; grp != nil {
+            b.placeStone(pos, grp.Color)
+        }
+    }
+    if board.ko != nil {
+        b.koPos, b.koColor = board.ko.Pos, board.ko.Color
+    }
+    b.hash ^= b.stateHash()
+}
+
+// Play a move of color 'color' at (x,y).
+func (b *FastBoard) PlayMove(x, y int, color Color) Error {
+    if x < 0 || y < 0 || x >= b.width || y >= b.height {
+        return NewIllegalMoveError(x, y, color)
+    }
+    return b.playMoveByPos(b.xyToPos(x, y), color)
+}
+
+// Play a move of color 'color' at 'pos'.
+func (b *FastBoard) playMoveByPos(pos int, color Color) Error {
+    if !b.IsLegalMove(pos, color) {
+        x, y := b.posToXY(pos)
+        return NewIllegalMoveError(x, y, color)
+    }
+    b.hash ^= b.stateHash()
+    // a move into a field without friends and liberties which captures a single stone is a ko
+    stones := b.stonesOf(color)
+    lonely := true
+    for _, npos := range b.neighbours[pos] {
+        if b.root[npos] < 0 || stones.has(npos) {
+            lonely = false
+        }
+    }
+    r := b.placeStone(pos, color)
+    captured := 0
+    koPos := -1
+    for _, npos := range b.neighbours[pos] {
+        if nr := b.root[npos]; nr >= 0 && !stones.has(npos) && b.libs[nr] == 0 {
+            captured += b.size[nr]
+            koPos = npos
+            b.capture(npos)
+        }
+    }
+    if b.libs[r] == 0 {
+        // a suicide
+        b.capture(pos)
+    }
+    b.koPos = -1
+    if lonely && captured == 1 {
+        b.koPos, b.koColor = koPos, !color
+    }
+    b.colorOfNextPlay = !color
+    b.hash ^= b.stateHash()
+    b.secondLastMove, b.lastMove = b.lastMove, pos
+    return nil
+}
+
+// The player of color 'color' plays a pass.
+func (b *FastBoard) PlayPass(color Color) {
+    if b.passStones {
+        if color == Black {
+            b.prisonersBlack++
+        } else {
+            b.prisonersWhite++
+        }
+    }
+    b.hash ^= b.stateHash()
+    b.colorOfNextPlay = !color
+    b.hash ^= b.stateHash()
+    b.secondLastMove, b.lastMove = b.lastMove, -1
+}
+
+// Plays a uniformly random legal move for player 'color' which does not fill an own eye, or a pass if
+// there is none, and returns the played vertex.
+func (b *FastBoard) PlayRandomMove(color Color) Vertex {
+    // collect the empty fields from the bitsets
+    n := 0
+    for i := range b.black {
+        free := ^(b.black[i] | b.white[i])
+        for ; free != 0; free &= free - 1 {
+            pos := i*64
+            for bit := free &^ (free - 1); bit != 1; bit >>= 1 {
+                pos++
+            }
+            if pos >= len(b.root) {
+                break
+            }
+            b.empty[n] = pos
+            n++
+        }
+    }
+    // draw without replacement until a wanted move is found
+    for n > 0 {
+        i := b.rand.Intn(n)
+        pos := b.empty[i]
+        if b.IsLegalMove(pos, color) && !b.isEye(pos, color) {
+            b.playMoveByPos(pos, color)
+            x, y := b.posToXY(pos)
+            return *NewVertexByInts(x, y, false)
+        }
+        n--
+        b.empty[i] = b.empty[n]
+    }
+    b.PlayPass(color)
+    return *NewVertexByInts(0, 0, true)
+}
+
+func (b *FastBoard) posToXY(pos int) (x, y int) {
+    return posToXY(pos, b.width)
+}
+
+// Returns the part of the hash which does not depend on the stones, see Board.stateHash.
+func (b *FastBoard) stateHash() uint64 {
+    var hash uint64
+    if b.colorOfNextPlay == White {
+        hash ^= zobristWhiteToMove
+    }
+    if b.koPos >= 0 && b.koColor == b.colorOfNextPlay {
+        hash ^= zobristKo[b.koPos]
+    }
+    return hash
+}
+
+// Returns the stones of 'color'.
+func (b *FastBoard) stonesOf(color Color) bitset {
+    if color == Black {
+        return b.black
+    }
+    return b.white
+}
+
+// Returns the number of columns.
+func (b *FastBoard) Width() int {
+    return b.width
+}
+
+func (b *FastBoard) xyToPos(x, y int) int {
+    return xyToPos(x, y, b.width)
+}
+
+// ##################### FastBoard helper functions ##########################
+
+func copyInts(ints []int) []int {
+    cpy := make([]int, len(ints))
+    copy(cpy, ints)
+    return cpy
+}
+
+// Creates a FastBoard with the position, the side to move, the ko, the prisoners and the rules of 'board'.
+// The superko rule of 'board' is not checked by the FastBoard.
+func NewFastBoard(board *Board) *FastBoard {
+    sec, nsec, _ := os.Time()
+    l := len(board.fields)
+    b := &FastBoard{
+        width: board.width,
+        height: board.height,
+        neighbours: board.neighbours,
+        black: newBitset(l),
+        white: newBitset(l),
+        root: make([]int, l),
+        next: make([]int, l),
+        size: make([]int, l),
+        libs: make([]int, l),
+        libSum: make([]int64, l),
+        libSumSquares: make([]int64, l),
+        rand: rand.New(rand.NewSource(sec+nsec)),
+        empty: make([]int, l),
+    }
+    b.fill(board)
+    return b
+}
@@ -23,6 +23,7 @@ import (
 type Game struct {
     Board *Board // The current board
     sequence vector.Vector // the sequence of moves
+    deadStones []int // the positions of the stones marked as dead, see SetDeadStones
 }
 
 // ##################### Game methods ##########################
@@ -37,20 +38,26 @@ func (g *Game) LastMove() *Move {
 }
 
 func (g *Game) PlayMove(x, y int, color Color) (err Error) {
+    if err = g.Board.PlayMove(x,y,color); err != nil {
+        return err
+    }
     g.sequence.Push(*NewMove(*NewPoint(x,y), color, false))
-    return g.Board.PlayMove(x,y,color)
+    g.deadStones = nil
+    return nil
 }
 
 func (g *Game) PlayRandomMove(color Color) Vertex {
     vertex := g.Board.PlayRandomMove(color)
     move := *NewMoveByVertex(&vertex, color)
     g.sequence.Push(move)
+    g.deadStones = nil
     return vertex
 }
 
 func (g *Game) PlayPass(color Color) {
     g.sequence.Push(*NewMove(*NewPoint(0,0), color, true))
     g.Board.PlayPass(color)
+    g.deadStones = nil
 }
 
 // Plays out the sequence 'seq' of moves
@@ -64,10 +71,34 @@ func (g *Game) PlaySequence(seq []Move) {
     }
 }
 
+// Takes back the last move or pass, see Board.Undo.
+func (g *Game) Undo() (err Error) {
+    if err = g.Board.Undo(); err != nil {
+        return err
+    }
+    g.sequence.Pop()
+    g.deadStones = nil
+    return nil
+}
+
 // Resets the game, i.e. clears the board and sets everything to initial values
 func (g *Game) Reset() {
     g.Board.Reset()
     g.sequence.Resize(0,0)
+    g.deadStones = nil
+}
+
+// Marks the stones at the positions 'dead' as dead for scoring the final position, see
+// Environment.FinalScoreDifference. The marks replace the previous ones. Every move, pass or undo clears
+// them, since they only apply to the position they were set for.
+func (g *Game) SetDeadStones(dead []int) {
+    g.deadStones = make([]int, len(dead))
+    copy(g.deadStones, dead)
+}
+
+// Returns the positions of the stones marked as dead.
+func (g *Game) DeadStones() []int {
+    return g.deadStones
 }
 
 // ##################### Game helper functions ##########################
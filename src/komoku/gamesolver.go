@@ -0,0 +1,386 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * An exact solver for games on tiny boards (3x3 up to 5x5). It computes the minimax value of the score
+ * under a Ruleset by alpha-beta search with a transposition table. The game ends after two passes in a
+ * row, and all stones which are on the board at the end of the game count as alive. The ruleset decides
+ * the legality of suicide, the superko rule, the scoring and the pass stones. Rules without superko only
+ * forbid the immediate recapture of a simple ko, which would allow endless cycles, so the solver forbids
+ * situational repetitions under these rules. The positions of the game before the solved position count
+ * for superko like those of the search.
+ *
+ * Superko makes the value of a position depend on its history (the graph history interaction problem).
+ * The search of a position depends on the positions of the path only through those which it reaches,
+ * since only these can forbid a move. So every entry of the transposition table keeps a filter of the
+ * positions which its search reached, together with the positions of the path in this filter, and it is
+ * only used on a path which has the same positions in the filter. The filter may contain positions which
+ * were not reached, which only costs a useless search, but it never misses one.
+ */
+
+package komoku
+
+import (
+    "fmt"
+)
+
+const maxGameSolverBoardSize = 5
+
+// The kinds of values stored in the transposition table
+const (
+    gsExact = iota
+    gsLowerBound
+    gsUpperBound
+)
+
+const gsInfinity = 1 << 20
+
+// The size of gsFilter in words
+const gsFilterWords = 16
+
+// ################################################################################
+// ########################### gsFilter struct ####################################
+// ################################################################################
+
+// A Bloom filter of positions, given by their hashes, see gameSolver.positionID.
+type gsFilter [gsFilterWords]uint64
+
+// ##################### gsFilter methods ##########################
+
+func (f *gsFilter) add(hash uint64) {
+    for _, bit := range [2]uint64{ hash, hash >> 32 } {
+        bit %= gsFilterWords*64
+        f[bit>>6] |= uint64(1) << (bit&63)
+    }
+}
+
+// Returns false if the position with the hash 'hash' has not been added to 'f'.
+func (f *gsFilter) has(hash uint64) bool {
+    for _, bit := range [2]uint64{ hash, hash >> 32 } {
+        bit %= gsFilterWords*64
+        if f[bit>>6] & (uint64(1) << (bit&63)) == 0 {
+            return false
+        }
+    }
+    return true
+}
+
+// Adds the positions of 'other' to 'f'.
+func (f *gsFilter) join(other *gsFilter) {
+    for i, word := range other {
+        f[i] |= word
+    }
+}
+
+// ################################################################################
+// ########################### gameSolver struct ##################################
+// ################################################################################
+
+type gsEntry struct {
+    value int
+    kind int // one of gs{Exact,LowerBound,UpperBound}
+    best int // the best move found, -1 is a pass
+    reached gsFilter // the positions which the search reached, see gameSolver.matchesPath
+    history []uint64 // the positions of the path in 'reached', in the order of the path
+}
+
+// A position after a move, see gameSolver.children
+type gsChild struct {
+    pos int // the move, -1 is a pass
+    board *Board
+    id uint64 // the position of 'board', see gameSolver.positionID
+    score int // the area score of 'board' for the side which did the move, which orders the moves
+}
+
+type gameSolver struct {
+    rules *Ruleset
+    superko SuperkoRule // the repetitions which the solver forbids, see positionID
+    table map[string]*gsEntry // position key -> value for the side to move
+    history map[uint64]int // the positions on the current path -> the number of times they occur on it
+    path []uint64 // the same positions in the order of the path
+    maxNodes int
+    nodes int
+    aborted bool
+}
+
+// ##################### gsChild methods ##########################
+
+// Is 'c' searched before 'other'? 'ttBest' is the best move of an earlier search.
+func (c *gsChild) before(other *gsChild, ttBest int) bool {
+    if other.pos == ttBest {
+        return false
+    }
+    return c.pos == ttBest || c.score > other.score
+}
+
+// ##################### gameSolver methods ##########################
+
+// Returns the hash which identifies the position on 'b' for the superko rule of the solver: the hash of
+// the stones for positional superko, and the hash of the stones and the side to move otherwise.
+func (s *gameSolver) positionID(b *Board) uint64 {
+    if s.superko == PositionalSuperko {
+        return b.hash ^ b.stateHash()
+    }
+    return b.positionHash()
+}
+
+// Appends the position 'id' to the current path.
+func (s *gameSolver) pushPath(id uint64) {
+    s.history[id]++
+    if len(s.path) == cap(s.path) {
+        newPath := make([]uint64, len(s.path), 2*cap(s.path)+1)
+        copy(newPath, s.path)
+        s.path = newPath
+    }
+    s.path = s.path[0:len(s.path)+1]
+    s.path[len(s.path)-1] = id
+}
+
+// Removes the last position from the current path.
+func (s *gameSolver) popPath() {
+    id := s.path[len(s.path)-1]
+    if s.history[id] == 1 {
+        s.history[id] = 0, false
+    } else {
+        s.history[id]--
+    }
+    s.path = s.path[0:len(s.path)-1]
+}
+
+// Appends the positions of the game before 'b', see Board.recordPosition, to the current path. The history
+// does not tell the side to move, so under positional superko both hashes of the stones are appended.
+func (s *gameSolver) pushGameHistory(b *Board) {
+    for _, hash := range b.history {
+        s.pushPath(hash)
+        if s.superko == PositionalSuperko {
+            s.pushPath(hash ^ zobristWhiteToMove)
+        }
+    }
+}
+
+// Returns the positions of the current path which are in 'reached'.
+func (s *gameSolver) pathIn(reached *gsFilter) []uint64 {
+    ret := make([]uint64, 0, 4)
+    for _, id := range s.path {
+        if reached.has(id) {
+            if len(ret) == cap(ret) {
+                newRet := make([]uint64, len(ret), 2*cap(ret))
+                copy(newRet, ret)
+                ret = newRet
+            }
+            ret = ret[0:len(ret)+1]
+            ret[len(ret)-1] = id
+        }
+    }
+    return ret
+}
+
+// Is the search of 'entry' valid on the current path? The search forbids the same moves if the path has
+// the same positions in the filter of the entry.
+func (s *gameSolver) matchesPath(entry *gsEntry) bool {
+    n := 0
+    for _, id := range s.path {
+        if entry.reached.has(id) {
+            if n == len(entry.history) || entry.history[n] != id {
+                return false
+            }
+            n++
+        }
+    }
+    return n == len(entry.history)
+}
+
+// Returns the positions after the moves of 'toMove' on 'b'. The moves which repeat a position of the
+// current path are left out, but their positions are added to 'reached' like the others.
+func (s *gameSolver) children(b *Board, toMove Color, reached *gsFilter) []*gsChild {
+    legal := b.listLegalPosses(toMove)
+    children := make([]*gsChild, 0, len(legal)+1)
+    for _, pos := range legal {
+        cpy := b.Copy()
+        cpy.playMoveByPos(pos, toMove)
+        id := s.positionID(cpy)
+        reached.add(id)
+        if s.history[id] > 0 {
+            continue
+        }
+        children = children[0:len(children)+1]
+        children[len(children)-1] = &gsChild{ pos: pos, board: cpy, id: id, score: areaScoreDifference(cpy) }
+    }
+    // a pass is never a repetition
+    cpy := b.Copy()
+    cpy.PlayPass(toMove)
+    children = children[0:len(children)+1]
+    children[len(children)-1] = &gsChild{ pos: -1, board: cpy, id: s.positionID(cpy),
+                                          score: areaScoreDifference(cpy) }
+    if toMove == White {
+        for _, child := range children {
+            child.score = -child.score
+        }
+    }
+    return children
+}
+
+// Returns the score of black minus the score of white on 'b' at the end of the game, without komi and
+// handicap compensation, which do not change the best moves. See Ruleset.ScoreDifference.
+func (s *gameSolver) score(b *Board) int {
+    return int(s.rules.ScoreDifference(b, 0, 0, nil))
+}
+
+// Returns the key of 'b', which has been reached after 'passes' passes in a row, in the transposition
+// table. Under territory scoring, the values depend on the prisoners as well.
+func (s *gameSolver) tableKey(b *Board, passes int) string {
+    key := positionKey(b, passes)
+    if s.rules.Scoring == TerritoryScoring {
+        key += fmt.Sprintf(" %d", b.prisonersBlack - b.prisonersWhite)
+    }
+    return key
+}
+
+// Searches the position 'b', which has been reached after 'passes' passes in a row, with the window
+// (alpha, beta). Returns the value of 'b' for the side to move and the best move. The positions which the
+// search reaches are added to 'reached'.
+func (s *gameSolver) search(b *Board, passes, alpha, beta int, reached *gsFilter) (value, best int) {
+    toMove := b.ColorOfNextPlay()
+    if passes >= 2 {
+        value = s.score(b)
+        if toMove == White {
+            value = -value
+        }
+        return value, -1
+    }
+    s.nodes++
+    if s.nodes > s.maxNodes {
+        s.aborted = true
+        return 0, -1
+    }
+
+    key := s.tableKey(b, passes)
+    ttBest := -1
+    if entry, ok := s.table[key]; ok {
+        // the best move is a good guess even if the value is not valid on this path
+        ttBest = entry.best
+        if s.matchesPath(entry) {
+            switch entry.kind {
+                case gsExact:
+                    reached.join(&entry.reached)
+                    return entry.value, entry.best
+                case gsLowerBound:
+                    alpha = maxInt(alpha, entry.value)
+                case gsUpperBound:
+                    beta = minInt(beta, entry.value)
+            }
+            if alpha >= beta {
+                reached.join(&entry.reached)
+                return entry.value, entry.best
+            }
+        }
+    }
+
+    // the best move of an earlier search comes first, then the moves with the best area score after them
+    var ownReached gsFilter
+    children := s.children(b, toMove, &ownReached)
+    for i := 1; i < len(children); i++ {
+        for j := i; j > 0 && children[j].before(children[j-1], ttBest); j-- {
+            children[j], children[j-1] = children[j-1], children[j]
+        }
+    }
+
+    origAlpha := alpha
+    value, best = -gsInfinity, -1
+    for _, child := range children {
+        var v int
+        childPasses := 0
+        if child.pos < 0 {
+            childPasses = passes + 1
+        }
+        s.pushPath(child.id)
+        v, _ = s.search(child.board, childPasses, -beta, -alpha, &ownReached)
+        s.popPath()
+        if s.aborted {
+            return 0, -1
+        }
+        v = -v
+        if v > value {
+            value, best = v, child.pos
+        }
+        alpha = maxInt(alpha, v)
+        if alpha >= beta {
+            break
+        }
+    }
+
+    entry := &gsEntry{ value: value, kind: gsExact, best: best, reached: ownReached }
+    if value <= origAlpha {
+        entry.kind = gsUpperBound
+    } else if value >= beta {
+        entry.kind = gsLowerBound
+    }
+    entry.history = s.pathIn(&ownReached)
+    s.table[key] = entry
+    reached.join(&ownReached)
+    return value, best
+}
+
+// ##################### game solver methods of Board ##########################
+
+// Computes the value of the game on 'b' with 'toMove' to play under the rules 'rules', i.e. the score of
+// black minus the score of white at the end of the game if both sides play perfectly, where white gets
+// 'komi' and the compensation for 'handicap' handicap stones. 'best' is the best move of 'toMove', -1
+// denotes a pass. 'solved' is false if the search needs more than 'maxNodes' nodes.
+func (b *Board) SolveGame(toMove Color, rules *Ruleset, komi float, handicap int, maxNodes int) (score float, best int, solved bool, err Error) {
+    if b.width > maxGameSolverBoardSize || b.height > maxGameSolverBoardSize {
+        return 0, -1, false, NewSolverError(fmt.Sprintf("the game solver supports boards up to %dx%d",
+                                                        maxGameSolverBoardSize, maxGameSolverBoardSize))
+    }
+    root := b.Copy()
+    root.SetRules(rules)
+    root.setColorOfNextPlay(toMove)
+    s := &gameSolver{
+        rules: rules,
+        superko: rules.Superko,
+        table: make(map[string]*gsEntry),
+        history: make(map[uint64]int),
+        maxNodes: maxNodes,
+    }
+    if s.superko == NoSuperko {
+        s.superko = SituationalSuperko
+    }
+    // the solver forbids the repetitions itself
+    root.SetSuperko(NoSuperko, 0)
+    s.pushGameHistory(b)
+    s.pushPath(s.positionID(root))
+    // an area score is at most the number of fields, so this window is exact
+    alpha, beta := -gsInfinity, gsInfinity
+    if rules.Scoring == AreaScoring {
+        alpha, beta = -len(root.fields), len(root.fields)
+    }
+    var reached gsFilter
+    value, best := s.search(root, 0, alpha, beta, &reached)
+    if s.aborted {
+        return 0, -1, false, nil
+    }
+    if toMove == White {
+        value = -value
+    }
+    return float(value) - komi - rules.HandicapCompensation(handicap), best, true, nil
+}
+
+// ##################### game solver helper functions ##########################
+
+// Returns the difference between the black and the white area of 'b', see Board.AreaScore.
+func areaScoreDifference(b *Board) int {
+    black, white := b.AreaScore()
+    return black - white
+}
+
+func maxInt(a, b int) int {
+    if a > b {
+        return a
+    }
+    return b
+}
@@ -8,51 +8,181 @@
 
 package komoku
 
+// ################################################################################
+// ########################### bitset #############################################
+// ################################################################################
+
+// A set of positions, 64 per word.
+type bitset []uint64
+
+func newBitset(size int) bitset {
+    return make(bitset, (size+63)/64)
+}
+
+// Removes all positions from 's'.
+func (s bitset) clear() {
+    for i := range s {
+        s[i] = 0
+    }
+}
+
+// Returns an independent copy of 's'.
+func (s bitset) copy() bitset {
+    cpy := make(bitset, len(s))
+    copy(cpy, s)
+    return cpy
+}
+
+// Returns the number of positions in 's'.
+func (s bitset) count() int {
+    n := 0
+    for _, word := range s {
+        for ; word != 0; word &= word - 1 {
+            n++
+        }
+    }
+    return n
+}
+
+func (s bitset) has(pos int) bool {
+    return s[pos>>6] & (uint64(1) << uint(pos&63)) != 0
+}
+
+// Returns the smallest position in 's' which is not smaller than 'pos', -1 if there is none.
+func (s bitset) next(pos int) int {
+    for i := pos>>6; i < len(s); i++ {
+        word := s[i]
+        if i == pos>>6 {
+            word &^= uint64(1) << uint(pos&63) - 1
+        }
+        if word != 0 {
+            ret := i*64
+            for ; word & 1 == 0; word >>= 1 {
+                ret++
+            }
+            return ret
+        }
+    }
+    return -1
+}
+
+func (s bitset) set(pos int) {
+    s[pos>>6] |= uint64(1) << uint(pos&63)
+}
+
+func (s bitset) unset(pos int) {
+    s[pos>>6] &^= uint64(1) << uint(pos&63)
+}
+
 // ################################################################################
 // ########################### Group struct #######################################
 // ################################################################################
 
+// A group of stones. The stones form a chain in the array 'next' of the board, starting at 'first', so
+// joining groups and capturing them needs no memory. The liberties are a bitset of the positions.
 type Group struct {
     Color
-    Fields *IntList // The fields which are occupied by the group
-    Liberties *IntList
+    first int // a stone of the group
+    numStones int
+    next []int // the next stone of each stone, shared with the board, see Board.nextStone
+    liberties bitset
+    numLiberties int
 }
 
 // ##################### Group methods ##########################
 
-// returns an equivalent but completlely independent copy of g
-func (g *Group) Copy() *Group {
-    cpy := NewGroup(g.Color)
-    // copy Fields
-    last := g.Fields.Last()
-    var it *IntListNode
-    for it = g.Fields.First(); it != last; it = it.Next() {
-        cpy.Fields.Append(it.Value())
+// Adds 'pos' to the liberties of 'g' if it is not a liberty already.
+func (g *Group) addLiberty(pos int) {
+    if !g.liberties.has(pos) {
+        g.liberties.set(pos)
+        g.numLiberties++
     }
-    // copy Liberties
-    last = g.Liberties.Last()
-    for it = g.Liberties.First(); it != last; it = it.Next() {
-        cpy.Liberties.Append(it.Value())
+}
+
+// Removes all liberties of 'g'.
+func (g *Group) clearLiberties() {
+    g.liberties.clear()
+    g.numLiberties = 0
+}
+
+// Makes 'dst' an equivalent but completely independent copy of 'g' and returns it. 'dst' keeps its own
+// liberty bitset, which is only allocated if it does not fit, so copying into the same group again
+// allocates nothing. The copy shares the stone chain of 'g', a board which copies its groups has to set the
+// chain of its own.
+func (g *Group) Copy(dst *Group) *Group {
+    liberties := dst.liberties
+    if len(liberties) != len(g.liberties) {
+        liberties = make(bitset, len(g.liberties))
     }
-    return cpy
+    *dst = *g
+    copy(liberties, g.liberties)
+    dst.liberties = liberties
+    return dst
+}
+
+// Calls 'f' for each liberty of 'g' in ascending order.
+func (g *Group) DoLiberties(f func(pos int)) {
+    for lib := g.liberties.next(0); lib >= 0; lib = g.liberties.next(lib+1) {
+        f(lib)
+    }
+}
+
+// Calls 'f' for each stone of 'g'.
+func (g *Group) DoStones(f func(pos int)) {
+    pos := g.first
+    for n := 0; n < g.numStones; n++ {
+        f(pos)
+        pos = g.next[pos]
+    }
+}
+
+// Returns the smallest liberty of 'g', -1 if it has none.
+func (g *Group) FirstLiberty() int {
+    return g.liberties.next(0)
+}
+
+// Returns the first stone of the chain of 'g'.
+func (g *Group) FirstStone() int {
+    return g.first
+}
+
+func (g *Group) HasLiberty(pos int) bool {
+    return g.liberties.has(pos)
+}
+
+// Returns the liberties of 'g' in ascending order.
+func (g *Group) Liberties() []int {
+    ret := make([]int, 0, g.numLiberties)
+    for lib := g.liberties.next(0); lib >= 0; lib = g.liberties.next(lib+1) {
+        ret = ret[0:len(ret)+1]
+        ret[len(ret)-1] = lib
+    }
+    return ret
 }
 
 func (g *Group) NumLiberties() int {
-    return g.Liberties.Length()
+    return g.numLiberties
 }
 
 func (g *Group) NumStones() int {
-    return g.Fields.Length()
+    return g.numStones
+}
+
+// Removes 'pos' from the liberties of 'g' if it is a liberty.
+func (g *Group) removeLiberty(pos int) {
+    if g.liberties.has(pos) {
+        g.liberties.unset(pos)
+        g.numLiberties--
+    }
 }
 
 // ##################### Group helper functions ##########################
 
-// Creates a new empty group of color 'c'.
+// Creates a new empty group of color 'c' without stones and without room for liberties. The groups on a
+// board are created by Board.CreateGroup.
 func NewGroup(c Color) *Group {
     return &Group{
         Color: c,
-        Fields: NewIntList(),
-        Liberties: NewIntList(),
     }
 }
 
@@ -41,6 +41,7 @@ type GTPCommandFunc func(object *GTPObject, params []interface{}) (result string
 // Everything to completely describe a GTP command
 type GTPCommand struct {
     Signature []int // Signature of expected arguments, such as GTPBool etc..
+    Optional int // The number of arguments at the end of Signature which may be omitted
     Func GTPCommandFunc // The actual code
 }
 
@@ -74,8 +75,12 @@ func (obj *GTPObject) ExecuteCommand(input string) (result string, quit bool, er
     }
     // Check the arguments
     signatureLen := len(gtpCmd.Signature)
-    if signatureLen != len(args) {
-        return obj.formatErrorResponse(hasId, id, fmt.Sprintf("wrong number of arguments, %d argument(s) expected", signatureLen)), false, nil
+    if len(args) > signatureLen || len(args) < signatureLen - gtpCmd.Optional {
+        expected := fmt.Sprintf("%d", signatureLen)
+        if gtpCmd.Optional > 0 {
+            expected = fmt.Sprintf("%d to %d", signatureLen - gtpCmd.Optional, signatureLen)
+        }
+        return obj.formatErrorResponse(hasId, id, fmt.Sprintf("wrong number of arguments, %s argument(s) expected", expected)), false, nil
     }
     argsToPass := make([]interface{}, len(args))
     for i := 0; i < len(args); i++ {
@@ -83,7 +88,7 @@ func (obj *GTPObject) ExecuteCommand(input string) (result string, quit bool, er
         // TODO: Do the type conversion (e.g. gtpVertexToPoint) here
         switch gtpCmd.Signature[i] {
             case GTPBool:
-                if args[i] != "true" || args[i] != "false" {
+                if args[i] != "true" && args[i] != "false" {
                     errmsg := fmt.Sprintf("argument %d has to be a boolean", i)
                     return obj.formatErrorResponse(hasId, id, errmsg), false, nil
                 } else {
@@ -133,6 +138,9 @@ func (obj *GTPObject) ExecuteCommand(input string) (result string, quit bool, er
                 panic("\n\nThe signature of " + commandName + " is set erroneous.\n\n")
         }
     }
+    // The thinkers copy the game board for every simulation and share its random generator and cached
+    // actions, so no command may read or change it while they run. Pondering goes on after the command.
+    defer obj.ai.startThinking(obj.ai.stopThinking())
     cmdResponse, retQuit, err := gtpCmd.Func(obj, argsToPass)
     if err != nil {
         return obj.formatErrorResponse(hasId, id, cmdResponse), retQuit, nil
@@ -228,14 +236,21 @@ func (obj *GTPObject) preprocessLine(input string) (result string) {
 
 // ##################### GTPObject helper functions ##########################
 func NewGTPObject() *GTPObject {
+    return NewGTPObjectForAI(NewAI(DefaultBoardSize))
+}
+
+// Returns a GTPObject which plays with 'ai', e.g. one which has been set up by command line flags.
+func NewGTPObjectForAI(ai *AI) *GTPObject {
 
     ret := &GTPObject{ commands: make(map[string]*GTPCommand),
-                       ai: NewAI(DefaultBoardSize),
+                       ai: ai,
                      }
 
     // GTP commands
     ret.commands["boardsize"] = gtpboardsize(ret)
     ret.commands["clear_board"] = gtpclear_board(ret)
+    ret.commands["final_score"] = gtpfinal_score(ret)
+    ret.commands["final_status_list"] = gtpfinal_status_list(ret)
     ret.commands["genmove"] = gtpgenmove(ret)
     ret.commands["known_command"] = gtpknown_command(ret)
     ret.commands["komi"] = gtpkomi(ret)
@@ -245,18 +260,43 @@ func NewGTPObject() *GTPObject {
     ret.commands["protocol_version"] = gtpprotocol_version(ret)
     ret.commands["quit"] = gtpquit(ret)
     ret.commands["showboard"] = gtpshowboard(ret)
+    ret.commands["undo"] = gtpundo(ret)
     ret.commands["version"] = gtpversion(ret)
 
+    // GoGui rules extensions
+    ret.commands["gogui-rules_board_size"] = gtpgogui_rules_board_size(ret)
+    ret.commands["gogui-rules_final_result"] = gtpgogui_rules_final_result(ret)
+    ret.commands["gogui-rules_game_id"] = gtpgogui_rules_game_id(ret)
+    ret.commands["gogui-rules_legal_moves"] = gtpgogui_rules_legal_moves(ret)
+    ret.commands["gogui-rules_side_to_move"] = gtpgogui_rules_side_to_move(ret)
+
     // Private extensions
     ret.commands["komoku-alllegal"] = gtpkomoku_alllegal(ret)
+    ret.commands["komoku-fastplayouts"] = gtpkomoku_fastplayouts(ret)
     ret.commands["komoku-genmovedbg"] = gtpkomoku_genmovedbg(ret)
     ret.commands["komoku-getenv"] = gtpkomoku_getenv(ret)
     ret.commands["komoku-getgroup"] = gtpkomoku_getgroup(ret)
+    ret.commands["komoku-heavyladders"] = gtpkomoku_heavyladders(ret)
     ret.commands["komoku-infocmd"] = gtpkomoku_infocmd(ret)
+    ret.commands["komoku-ladder"] = gtpkomoku_ladder(ret)
+    ret.commands["komoku-lifeanddeath"] = gtpkomoku_lifeanddeath(ret)
+    ret.commands["komoku-listparams"] = gtpkomoku_listparams(ret)
+    ret.commands["komoku-loadparams"] = gtpkomoku_loadparams(ret)
+    ret.commands["komoku-loadpatterns"] = gtpkomoku_loadpatterns(ret)
+    ret.commands["komoku-mercy"] = gtpkomoku_mercy(ret)
+    ret.commands["komoku-movecap"] = gtpkomoku_movecap(ret)
+    ret.commands["komoku-moveselection"] = gtpkomoku_moveselection(ret)
     ret.commands["komoku-numgroups"] = gtpkomoku_numgroups(ret)
     ret.commands["komoku-numstones"] = gtpkomoku_numstones(ret)
     ret.commands["komoku-playfork"] = gtpkomoku_playfork(ret)
+    ret.commands["komoku-playoutstats"] = gtpkomoku_playoutstats(ret)
     ret.commands["komoku-placehandi"] = gtpkomoku_placehandi(ret)
+    ret.commands["komoku-rules"] = gtpkomoku_rules(ret)
+    ret.commands["komoku-saveparams"] = gtpkomoku_saveparams(ret)
+    ret.commands["komoku-setparam"] = gtpkomoku_setparam(ret)
+    ret.commands["komoku-solve"] = gtpkomoku_solve(ret)
+    ret.commands["komoku-solvegame"] = gtpkomoku_solvegame(ret)
+    ret.commands["komoku-stats"] = gtpkomoku_stats(ret)
     ret.commands["komoku-showliberties"] = gtpkomoku_showliberties(ret)
     ret.commands["komoku-source"] = gtpkomoku_source(ret)
     ret.commands["komoku-sourceforkn"] = gtpkomoku_sourceforkn(ret)
@@ -285,9 +325,10 @@ func NewGTPIllegalCommand(msg string) (err Error) {
 // #################### Function for running the GTP-mode #########################
 // ################################################################################
 
-func RunGTPMode() {
+// Runs the GTP mode with 'ai'.
+func RunGTPMode(ai *AI) {
     // Create the GTPObject and start the input loop
-    gtpObject := NewGTPObject()
+    gtpObject := NewGTPObjectForAI(ai)
     in := bufio.NewReader(os.Stdin)
     for {
         line, err := in.ReadString('\n')
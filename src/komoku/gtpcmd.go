@@ -26,26 +26,34 @@ import (
     //"rand"
     "os"
     "bufio"
+    "strings"
 )
 
 // The board size is changed. The board configuration, number of captured stones, and move history become arbitrary.
+// With a second argument, as sent by GoGui, the board gets argument 0 columns and argument 1 rows.
 // TODO: not yet implemented completely
 func gtpboardsize(obj *GTPObject) *GTPCommand {
-    signature := []int { GTPInt }
+    signature := []int { GTPInt, GTPInt }
     f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
-        boardsize, ok := params[0].(uint)
+        width, ok := params[0].(uint)
         if !ok {
             panic("\n\nType assertion for first parameter of boardsize failed.\n\n")
         }
-        if boardsize < 5 || boardsize > 25 {
+        height := width
+        if len(params) > 1 {
+            height, _ = params[1].(uint)
+        }
+        // the column letters of GTP vertices end with Z, so larger boards are only supported by the library
+        if width < 5 || width > 25 || height < 5 || height > 25 {
             return "unacceptable size", false, NewUnacceptableBoardSizeError()
         }
 
         // TODO: get rid of this cast
-        object.ai.environment.Game.Board = NewBoard(int(boardsize))
+        object.ai.SetBoard(NewRectangularBoard(int(width), int(height)))
         return result, false, nil
     }
     return &GTPCommand{ Signature: signature,
+                        Optional: 1,
                         Func: f,
                       }
 }
@@ -55,8 +63,8 @@ func gtpboardsize(obj *GTPObject) *GTPCommand {
 func gtpclear_board(obj *GTPObject) *GTPCommand {
     signature := []int { }
     f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
-        curSize := object.ai.environment.Game.Board.BoardSize()
-        object.ai.environment.Game.Board = NewBoard(curSize)
+        cur := object.ai.environment.Game.Board
+        object.ai.SetBoard(NewRectangularBoard(cur.Width(), cur.Height()))
         return result, false, nil
     }
     return &GTPCommand{ Signature: signature,
@@ -64,6 +72,55 @@ func gtpclear_board(obj *GTPObject) *GTPCommand {
                       }
 }
 
+// Prints the score of the game according to the ruleset, e.g. "B+3.5". The dead stones are estimated by
+// playouts, see AI.FinalStatus.
+func gtpfinal_score(obj *GTPObject) *GTPCommand {
+    signature := []int {}
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        return formatGameResult(obj.ai.FinalScore()), false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Prints the stones with the status given as argument 0 (alive, dead or seki), one group per line. The status
+// is estimated by playouts, see AI.FinalStatus.
+func gtpfinal_status_list(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPString }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        name, _ := params[0].(string)
+        wanted, ok := ParseStoneStatus(name)
+        if !ok {
+            emsg := "argument 0 has to be one of alive, dead and seki"
+            return emsg, false, NewGTPSyntaxError(emsg)
+        }
+        status := obj.ai.FinalStatus()
+        b := obj.ai.environment.Game.Board
+        lines := make([]string, 0, len(status))
+        for pos, s := range status {
+            grp := b.fields[pos]
+            // each group is printed at its first stone
+            if s != wanted || grp == nil || grp.FirstStone() != pos {
+                continue
+            }
+            vertices := make([]string, 0, grp.NumStones())
+            grp.DoStones(func(p int) {
+                x, y := b.posToXY(p)
+                v, _ := pointToGTPVertex(*NewPoint(x, y))
+                vertices = vertices[0:len(vertices)+1]
+                vertices[len(vertices)-1] = v
+            })
+            lines = lines[0:len(lines)+1]
+            lines[len(lines)-1] = strings.Join(vertices, " ")
+        }
+        return strings.Join(lines, "\n"), false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
 // Generate a move of the requested color. This is where the AI kicks in.
 /*func gtpgenmove(obj *GTPObject) *GTPCommand {
     signature := []int { GTPColor }
@@ -105,6 +162,75 @@ func gtpgenmove(obj *GTPObject) *GTPCommand {
                       }
 }
 
+// Prints the board size, or the number of columns and rows of a rectangular board
+func gtpgogui_rules_board_size(obj *GTPObject) *GTPCommand {
+    signature := []int {}
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        b := obj.ai.environment.Game.Board
+        if b.Width() != b.Height() {
+            return fmt.Sprintf("%d %d", b.Width(), b.Height()), false, nil
+        }
+        return fmt.Sprintf("%d", b.Width()), false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Prints the score of the current position according to the ruleset, e.g. "B+3.5". All stones on the board
+// are considered alive.
+func gtpgogui_rules_final_result(obj *GTPObject) *GTPCommand {
+    signature := []int {}
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        e := obj.ai.environment
+        return formatGameResult(e.ScoreDifference(e.Game.Board)), false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Prints the name of the game
+func gtpgogui_rules_game_id(obj *GTPObject) *GTPCommand {
+    signature := []int {}
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        return "Go", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Prints the legal moves of the player to move according to the ruleset, including pass.
+func gtpgogui_rules_legal_moves(obj *GTPObject) *GTPCommand {
+    signature := []int {}
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        b := obj.ai.environment.Game.Board
+        for _, p := range b.ListLegalPoints(b.ColorOfNextPlay()) {
+            v, _ := pointToGTPVertex(p)
+            result += v + " "
+        }
+        return result + "pass", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Prints the color of the player to move, either "black" or "white"
+func gtpgogui_rules_side_to_move(obj *GTPObject) *GTPCommand {
+    signature := []int {}
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        if obj.ai.environment.Game.Board.ColorOfNextPlay() == Black {
+            return "black", false, nil
+        }
+        return "white", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
 // Expexts one string argument, called 'cmdName'. Prints "true" if the command is known, "false" otherwise.
 func gtpknown_command(obj *GTPObject) *GTPCommand {
     signature := []int { GTPString }
@@ -151,6 +277,21 @@ func gtpkomoku_alllegal(obj *GTPObject) *GTPCommand {
                       }
 }
 
+// Expects a boolean. Enables or disables playouts on a FastBoard, see AI.SetFastPlayouts. Fast playouts
+// silently disable the ladder and nakade replies, the patterns and the seki avoidance of the playouts, and
+// they ignore superko, so they are always capped even if komoku-movecap disabled the cap.
+func gtpkomoku_fastplayouts(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPBool }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        fast, _ := params[0].(bool)
+        obj.ai.SetFastPlayouts(fast)
+        return "", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
 // Generate a move of the requested color. This is the debug version of genmove
 func gtpkomoku_genmovedbg(obj *GTPObject) *GTPCommand {
     signature := []int { GTPColor }
@@ -203,7 +344,20 @@ func gtpkomoku_getgroup(obj *GTPObject) *GTPCommand {
         if grp == nil {
             return "empty", false, nil
         }
-        return fmt.Sprintf("color: %s, #stones: %d, #liberties: %d", grp.Color, grp.Fields.Length(), grp.Liberties.Length()), false, nil
+        return fmt.Sprintf("color: %s, #stones: %d, #liberties: %d", grp.Color, grp.NumStones(), grp.NumLiberties()), false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Argument: bool. Enables or disables the ladder replies in the playouts.
+func gtpkomoku_heavyladders(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPBool }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        heavy, _ := params[0].(bool)
+        obj.ai.SetHeavyLadders(heavy)
+        return "", false, nil
     }
     return &GTPCommand{ Signature: signature,
                         Func: f,
@@ -250,6 +404,164 @@ func gtpkomoku_infocmd(obj *GTPObject) *GTPCommand {
                       }
 }
 
+// Argument: vertex. Reads the ladder of the group on the vertex. Prints "captured" or "escapes", or
+// "none" if the vertex is empty or the group has more than two liberties. A group in atari is assumed
+// to be the side to move, a group with two liberties is chased with the opponent to move.
+func gtpkomoku_ladder(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPVertex }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        vertex, _ := params[0].(Vertex)
+        if vertex.Pass {
+            emsg := "argument 0 has to be a vertex other than pass"
+            return emsg, false, NewGTPSyntaxError(emsg)
+        }
+        board := obj.ai.environment.Game.Board
+        switch board.ReadLadder(board.xyToPos(vertex.X, vertex.Y)) {
+            case LadderCaptured:
+                return "captured", false, nil
+            case LadderEscapes:
+                return "escapes", false, nil
+        }
+        return "none", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Argument: bool. Enables or disables the life-and-death solver during move generation.
+func gtpkomoku_lifeanddeath(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPBool }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        solve, _ := params[0].(bool)
+        obj.ai.SetLifeAndDeathSolving(solve)
+        return "", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Prints one line per parameter: name, value, minimum and maximum.
+func gtpkomoku_listparams(obj *GTPObject) *GTPCommand {
+    signature := []int {}
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        for i, p := range ListParameters() {
+            if i > 0 {
+                result += "\n"
+            }
+            result += fmt.Sprintf("%s %s [%g, %g]", p.Name, p, p.Min, p.Max)
+        }
+        return result, false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Loads parameter values from the given file, see LoadParameters.
+func gtpkomoku_loadparams(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPString }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        filename, _ := params[0].(string)
+        if err := LoadParameters(filename); err != nil {
+            return fmt.Sprintf("cannot load parameters: %s", err), false, err
+        }
+        return "", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Loads pattern weights written by e/patternlearn from the given file and uses them for playouts and priors.
+func gtpkomoku_loadpatterns(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPString }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        filename, _ := params[0].(string)
+        weights, err := ReadPatternWeights(filename)
+        if err != nil {
+            return fmt.Sprintf("cannot load patterns: %s", err), false, err
+        }
+        obj.ai.SetPatterns(weights)
+        return fmt.Sprintf("%d features loaded", weights.NumFeatures()), false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Sets the mercy rule for playouts. Arguments: int threshold, string mode, where mode is either "stones"
+// or "captures". A threshold of 0 disables the mercy rule.
+func gtpkomoku_mercy(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPInt, GTPString }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        threshold := int(params[0].(uint))
+        var mode int
+        switch params[1].(string) {
+            case "stones":
+                mode = MercyStoneDifference
+            case "captures":
+                mode = MercyCaptureDifference
+            default:
+                emsg := "argument 1 has to be either 'stones' or 'captures'"
+                return emsg, false, NewGTPSyntaxError(emsg)
+        }
+        obj.ai.SetMercyRule(threshold, mode)
+        return "", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Caps the length of playouts. Arguments: float factor, string action. Playouts are stopped after factor times
+// the average length of a random game. If action is "score", the position at the cap is scored, if it is
+// "discard", the playout is thrown away. A factor of 0 disables the cap.
+func gtpkomoku_movecap(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPFloat, GTPString }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        factor := params[0].(float)
+        var discard bool
+        switch params[1].(string) {
+            case "score":
+                discard = false
+            case "discard":
+                discard = true
+            default:
+                emsg := "argument 1 has to be either 'score' or 'discard'"
+                return emsg, false, NewGTPSyntaxError(emsg)
+        }
+        obj.ai.SetMoveCap(factor, discard)
+        return "", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Sets the randomized move selection of genmove: "komoku-moveselection <temperature> <moves> <epsilon>" draws the
+// first <moves> moves with the given temperature and later moves among those within epsilon of the best winning
+// percentage; a positive epsilon also restricts the moves drawn with the temperature. "komoku-moveselection 0 0 0"
+// switches back to always playing the best move.
+func gtpkomoku_moveselection(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPFloat, GTPInt, GTPFloat }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        temperature := params[0].(float)
+        moves := params[1].(uint)
+        epsilon := params[2].(float)
+        if temperature < 0 || epsilon < 0 {
+            emsg := "the temperature and epsilon must not be negative"
+            return emsg, false, NewGTPSyntaxError(emsg)
+        }
+        obj.ai.SetMoveSelection(temperature, int(moves), epsilon)
+        return "", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
 // Prints the number of groups in this format: "#black: <number>, #white: <number>"
 func gtpkomoku_numgroups(obj *GTPObject) *GTPCommand {
     signature := []int {}
@@ -300,11 +612,22 @@ func gtpkomoku_playfork(obj *GTPObject) *GTPCommand {
                       }
 }
 
+// Prints how many playouts were run and how many of them were stopped by the mercy rule or the move cap
+func gtpkomoku_playoutstats(obj *GTPObject) *GTPCommand {
+    signature := []int {}
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        return obj.ai.stats.playoutSummary(), false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
 // places the given number of handicap stones (which are black stones)
 func gtpkomoku_placehandi(obj *GTPObject) *GTPCommand {
     signature := []int { GTPInt }
     f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
-        if obj.ai.environment.Game.Board.BoardSize() != 9 {
+        if b := obj.ai.environment.Game.Board; b.Width() != 9 || b.Height() != 9 {
             return "this command is only implemented for boards of size 9", false, NewGTPNotImplementedError("not implemented")
         }
         numHandi, _ := params[0].(uint)
@@ -327,6 +650,7 @@ func gtpkomoku_placehandi(obj *GTPObject) *GTPCommand {
             y := moves[i].Y
             obj.ai.PlayMove(x,y,Black)
         }
+        obj.ai.environment.SetHandicap(int(numHandi))
 
         return "", false, nil
     }
@@ -335,6 +659,161 @@ func gtpkomoku_placehandi(obj *GTPObject) *GTPCommand {
                       }
 }
 
+// Sets the ruleset, which is one of default, chinese, japanese, aga, new_zealand and tromp-taylor. With "show", the
+// current ruleset is printed.
+func gtpkomoku_rules(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPString }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        name, _ := params[0].(string)
+        if name == "show" {
+            return obj.ai.environment.rules.String(), false, nil
+        }
+        r, ok := RulesetByName(name)
+        if !ok {
+            emsg := "argument 0 has to be one of show, default, chinese, japanese, aga, new_zealand and tromp-taylor"
+            return emsg, false, NewGTPSyntaxError(emsg)
+        }
+        obj.ai.SetRules(r)
+        return "", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Writes the values of all parameters to the given file, see SaveParameters.
+func gtpkomoku_saveparams(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPString }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        filename, _ := params[0].(string)
+        if err := SaveParameters(filename); err != nil {
+            return fmt.Sprintf("cannot save parameters: %s", err), false, err
+        }
+        return "", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Arguments: string name, float value. Sets the parameter 'name' to 'value'.
+func gtpkomoku_setparam(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPString, GTPFloat }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        name, _ := params[0].(string)
+        value, _ := params[1].(float)
+        p, ok := GetParameter(name)
+        if !ok {
+            emsg := fmt.Sprintf("unknown parameter %s", name)
+            return emsg, false, NewGTPSyntaxError(emsg)
+        }
+        if err := p.Set(value); err != nil {
+            return err.String(), false, err
+        }
+        return "", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Arguments: vertex, color. Solves the life and death of the group on the vertex with 'color' to move. Prints
+// "alive" or "dead" followed by the main line, or "unknown" if the solver ran out of nodes.
+func gtpkomoku_solve(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPVertex, GTPColor }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        vertex, _ := params[0].(Vertex)
+        color, _ := params[1].(Color)
+        if vertex.Pass {
+            emsg := "argument 0 has to be a vertex other than pass"
+            return emsg, false, NewGTPSyntaxError(emsg)
+        }
+        board := obj.ai.environment.Game.Board
+        target := board.xyToPos(vertex.X, vertex.Y)
+        solveResult, line, err := board.SolveLifeAndDeath(target, color, paramSolverNodes.Int())
+        if err != nil {
+            return err.String(), false, err
+        }
+        if solveResult == SolveUnknown {
+            return "unknown", false, nil
+        }
+        // The side to move wins; it owns the group if and only if the group lives.
+        ownsGroup := board.fields[target].Color == color
+        if (solveResult == SolveWin) == ownsGroup {
+            result = "alive"
+        } else {
+            result = "dead"
+        }
+        for _, pos := range line {
+            if pos < 0 {
+                result += " pass"
+            } else {
+                x, y := board.posToXY(pos)
+                v, _ := pointToGTPVertex(*NewPoint(x, y))
+                result += " " + v
+            }
+        }
+        return result, false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Solves the game on the current board exactly, with the color of the next play to move. Prints the result
+// with perfect play under the ruleset, the komi and the handicap of the game and the best move, or "unknown"
+// if the node budget is exhausted.
+func gtpkomoku_solvegame(obj *GTPObject) *GTPCommand {
+    signature := []int {}
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        env := obj.ai.environment
+        board := env.Game.Board
+        score, best, solved, err := board.SolveGame(board.ColorOfNextPlay(), env.rules, env.komi, env.handicap,
+                                                    paramGameSolverNodes.Int())
+        if err != nil {
+            return err.String(), false, err
+        }
+        if !solved {
+            return "unknown", false, nil
+        }
+        result = formatGameResult(score)
+        if best < 0 {
+            result += " pass"
+        } else {
+            x, y := board.posToXY(best)
+            v, _ := pointToGTPVertex(*NewPoint(x, y))
+            result += " " + v
+        }
+        return result, false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
+// Prints the statistics of the search, see SearchStatistics. With "json", they are printed as one line
+// of JSON; "on" and "off" switch the JSON output to stderr after each generated move on and off.
+func gtpkomoku_stats(obj *GTPObject) *GTPCommand {
+    signature := []int { GTPString }
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        mode, _ := params[0].(string)
+        switch mode {
+            case "text":
+                return obj.ai.Statistics().String(), false, nil
+            case "json":
+                return obj.ai.Statistics().JSON(), false, nil
+            case "on", "off":
+                obj.ai.SetPrintStatistics(mode == "on")
+                return "", false, nil
+        }
+        emsg := "argument 0 has to be one of text, json, on and off"
+        return emsg, false, NewGTPSyntaxError(emsg)
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
 // Prints the liberties of the specified group (as vertices) or "empty"
 func gtpkomoku_showliberties(obj *GTPObject) *GTPCommand {
     signature := []int { GTPVertex }
@@ -348,9 +827,9 @@ func gtpkomoku_showliberties(obj *GTPObject) *GTPCommand {
         if group == nil {
             return "there is no group", false, nil
         }
-        libPoints := make([]Point, group.Liberties.Length())
+        libPoints := make([]Point, group.NumLiberties())
         i := 0
-        group.Liberties.Do(func(val int) {
+        group.DoLiberties(func(val int) {
             pX, pY := obj.ai.environment.Game.Board.posToXY(val)
             libPoints[i] = *NewPoint(pX, pY)
             i++
@@ -473,7 +952,6 @@ func gtpkomoku_sourceforkn(obj *GTPObject) *GTPCommand {
                       }
 }
 
-
 // List all commands, one by each line, sorted alphabetically
 func gtplist_commands(obj *GTPObject) *GTPCommand {
     signature := []int {}
@@ -574,6 +1052,21 @@ func gtpshowboard(obj *GTPObject) *GTPCommand {
                       }
 }
 
+// The last move is taken back. The response is "cannot undo" if there is no move or if the move cannot be
+// taken back.
+func gtpundo(obj *GTPObject) *GTPCommand {
+    signature := []int {}
+    f := func(object *GTPObject, params []interface{}) (result string, quit bool, err Error) {
+        if err = obj.ai.Undo(); err != nil {
+            return "cannot undo", false, err
+        }
+        return "", false, nil
+    }
+    return &GTPCommand{ Signature: signature,
+                        Func: f,
+                      }
+}
+
 // Print the version of komoku
 func gtpversion(obj *GTPObject) *GTPCommand {
     signature := []int {}
@@ -0,0 +1,103 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * Zobrist hashing of board positions. The hash of a position is the xor of a random key for
+ * every stone, a key for the side to move and a key for the ko point if the ko concerns the side
+ * to move. The Board keeps its hash up to date incrementally.
+ */
+
+package komoku
+
+import (
+    "rand"
+)
+
+// The largest number of fields of a supported board. The keys of the fields of smaller boards do not depend
+// on this number.
+const maxZobristFields = MaxBoardSize*MaxBoardSize
+
+// The keys are the same in every run, so hashes can be stored, e.g. in opening books.
+const zobristSeed = 20101019
+
+// ################################################################################
+// ########################### global variables ###################################
+// ################################################################################
+
+var (
+    zobristBlack []uint64 // the keys of black stones, indexed by pos
+    zobristWhite []uint64 // the keys of white stones, indexed by pos
+    zobristKo []uint64 // the keys of ko points, indexed by pos
+    zobristWhiteToMove uint64
+)
+
+func init() {
+    r := rand.New(rand.NewSource(zobristSeed))
+    zobristBlack = make([]uint64, maxZobristFields)
+    zobristWhite = make([]uint64, maxZobristFields)
+    zobristKo = make([]uint64, maxZobristFields)
+    for pos := 0; pos < maxZobristFields; pos++ {
+        zobristBlack[pos] = randomUint64(r)
+        zobristWhite[pos] = randomUint64(r)
+        zobristKo[pos] = randomUint64(r)
+    }
+    zobristWhiteToMove = randomUint64(r)
+}
+
+// ##################### hashing methods of Board ##########################
+
+// Computes the hash of the position on 'b' from scratch.
+func (b *Board) computeHash() uint64 {
+    hash := b.stateHash()
+    for pos := 0; pos < len(b.fields); pos++ {
+        if grp := b.fields[pos]; grp != nil {
+            hash ^= zobristStone(pos, grp.Color)
+        }
+    }
+    return hash
+}
+
+// Returns the Zobrist hash of the position: the stones, the side to move and the ko point. The hash is
+// maintained by playMoveByPos, PlayPass, removeGroup and Reset. Placing stones by CreateGroup does not
+// update it.
+func (b *Board) Hash() uint64 {
+    return b.hash
+}
+
+// Sets the side to move and updates the hash.
+func (b *Board) setColorOfNextPlay(color Color) {
+    b.hash ^= b.stateHash()
+    b.colorOfNextPlay = color
+    b.hash ^= b.stateHash()
+}
+
+// Returns the part of the hash which does not depend on the stones: the side to move and the ko point,
+// if the ko concerns the side to move.
+func (b *Board) stateHash() uint64 {
+    var hash uint64
+    if b.colorOfNextPlay == White {
+        hash ^= zobristWhiteToMove
+    }
+    if b.ko != nil && b.ko.Color == b.colorOfNextPlay {
+        hash ^= zobristKo[b.ko.Pos]
+    }
+    return hash
+}
+
+// ##################### hashing helper functions ##########################
+
+func randomUint64(r *rand.Rand) uint64 {
+    return uint64(r.Int63()) << 1 ^ uint64(r.Int63())
+}
+
+func zobristStone(pos int, color Color) uint64 {
+    if color == Black {
+        return zobristBlack[pos]
+    }
+    return zobristWhite[pos]
+}
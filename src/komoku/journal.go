@@ -0,0 +1,275 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * The change journal of the Board, which makes it possible to take back moves with Board.Undo. For
+ * every move and pass, the journal records the old values of everything the move changes: the fields
+ * and the stone chains, the groups, the cached legalities and the scalar state like the ko, the
+ * prisoners and the hash. The legalities which are computed lazily after the move are recorded
+ * with the move as well, so that Undo restores the exact previous state.
+ */
+
+package komoku
+
+// ################################################################################
+// ########################### journal types ######################################
+// ################################################################################
+
+type fieldChange struct {
+    pos int
+    group *Group
+    nextStone int
+}
+
+type groupChange struct {
+    group *Group
+    saved Group // the old state. The liberties are restored by the libertyChanges
+}
+
+// The old value of one word of the liberties of a group
+type libertyChange struct {
+    liberties bitset
+    word int
+    old uint64
+}
+
+type legalityChange struct {
+    pos int
+    color Color
+    action *actionFunc
+    sequence uint32
+}
+
+// The changes of one move or pass
+type journalEntry struct {
+    ko *koLock
+    colorOfNextPlay Color
+    currentSequence uint32
+    prisonersBlack, prisonersWhite int
+    lastMove, secondLastMove int
+    hash uint64
+    historyLength int
+    suicide bool // the suicide rule the move has been played under
+    fields []fieldChange
+    groups []groupChange
+    liberties []libertyChange
+    legalities []legalityChange
+    // playMoveByPos increments the sequences of all fields after the first 'bumpedAt' legality changes
+    bumpedAt int
+    bumpedBlack, bumpedWhite bool
+}
+
+// ##################### journal methods of Board ##########################
+
+// Enables or disables the journal. Disabling it forgets all recorded moves.
+func (b *Board) SetJournaling(enable bool) {
+    b.journaling = enable
+    b.journal = b.journal[0:0]
+}
+
+// Returns the number of moves which can be taken back by Undo.
+func (b *Board) UndoableMoves() int {
+    return len(b.journal)
+}
+
+// Takes back the last move or pass and restores the exact state before it. If the suicide rule has been
+// changed since the move, the legalities are computed again for the current rule.
+func (b *Board) Undo() Error {
+    if len(b.journal) == 0 {
+        return NewUndoError("there is no move to undo")
+    }
+    e := b.journal[len(b.journal)-1]
+    b.journal = b.journal[0:len(b.journal)-1]
+    // the legalities are reverted in reverse order, the increment of all sequences in between
+    for i := len(e.legalities)-1; i >= 0; i-- {
+        if i == e.bumpedAt - 1 {
+            b.unbumpSequences(e)
+        }
+        b.revertLegality(e.legalities[i])
+    }
+    if e.bumpedAt == 0 {
+        b.unbumpSequences(e)
+    }
+    for _, g := range e.groups {
+        *g.group = g.saved
+    }
+    for i := len(e.liberties)-1; i >= 0; i-- {
+        change := e.liberties[i]
+        change.liberties[change.word] = change.old
+    }
+    for i := len(e.fields)-1; i >= 0; i-- {
+        change := e.fields[i]
+        b.fields[change.pos], b.nextStone[change.pos] = change.group, change.nextStone
+    }
+    b.ko = e.ko
+    b.colorOfNextPlay = e.colorOfNextPlay
+    b.currentSequence = e.currentSequence
+    b.prisonersBlack, b.prisonersWhite = e.prisonersBlack, e.prisonersWhite
+    b.lastMove, b.secondLastMove = e.lastMove, e.secondLastMove
+    b.hash = e.hash
+    b.history = b.history[0:e.historyLength]
+    if e.suicide != b.suicide {
+        // the restored legalities belong to the other suicide rule. The new ones are recorded with the
+        // previous move, like all legalities which are computed after it.
+        for pos := range b.fields {
+            b.updateLegalityForBlack(pos, b.currentSequence)
+            b.updateLegalityForWhite(pos, b.currentSequence)
+        }
+    }
+    return nil
+}
+
+// Starts the journal entry of a new move or pass.
+func (b *Board) beginJournalEntry() {
+    if !b.journaling {
+        return
+    }
+    e := &journalEntry{
+        ko: b.ko,
+        colorOfNextPlay: b.colorOfNextPlay,
+        currentSequence: b.currentSequence,
+        prisonersBlack: b.prisonersBlack,
+        prisonersWhite: b.prisonersWhite,
+        lastMove: b.lastMove,
+        secondLastMove: b.secondLastMove,
+        hash: b.hash,
+        historyLength: len(b.history),
+        suicide: b.suicide,
+        bumpedAt: -1,
+    }
+    if len(b.journal) == cap(b.journal) {
+        newJournal := make([]*journalEntry, len(b.journal), 2*cap(b.journal)+1)
+        copy(newJournal, b.journal)
+        b.journal = newJournal
+    }
+    b.journal = b.journal[0:len(b.journal)+1]
+    b.journal[len(b.journal)-1] = e
+}
+
+// Returns the journal entry which records the changes, nil if nothing has to be recorded.
+func (b *Board) currentJournalEntry() *journalEntry {
+    if !b.journaling || len(b.journal) == 0 {
+        return nil
+    }
+    return b.journal[len(b.journal)-1]
+}
+
+// Records the group at 'pos' and the next stone in its chain before they change.
+func (b *Board) journalField(pos int) {
+    if e := b.currentJournalEntry(); e != nil {
+        if len(e.fields) == cap(e.fields) {
+            newFields := make([]fieldChange, len(e.fields), 2*cap(e.fields)+4)
+            copy(newFields, e.fields)
+            e.fields = newFields
+        }
+        e.fields = e.fields[0:len(e.fields)+1]
+        e.fields[len(e.fields)-1] = fieldChange{ pos: pos, group: b.fields[pos], nextStone: b.nextStone[pos] }
+    }
+}
+
+// Records the stones and the number of liberties of 'group' before they change. Each group is recorded once per
+// move. The liberties themselves are recorded by journalLiberty and journalLiberties.
+func (b *Board) journalGroup(group *Group) {
+    if e := b.currentJournalEntry(); e != nil {
+        for _, g := range e.groups {
+            if g.group == group {
+                return
+            }
+        }
+        if len(e.groups) == cap(e.groups) {
+            newGroups := make([]groupChange, len(e.groups), 2*cap(e.groups)+4)
+            copy(newGroups, e.groups)
+            e.groups = newGroups
+        }
+        e.groups = e.groups[0:len(e.groups)+1]
+        e.groups[len(e.groups)-1] = groupChange{ group: group, saved: *group }
+    }
+}
+
+// Records the word of the liberties of 'group' which holds 'pos' before it changes.
+func (b *Board) journalLiberty(group *Group, pos int) {
+    if e := b.currentJournalEntry(); e != nil {
+        e.recordLibertyWord(group.liberties, pos>>6)
+    }
+}
+
+// Records all words of the liberties of 'group' before they are computed again.
+func (b *Board) journalLiberties(group *Group) {
+    if e := b.currentJournalEntry(); e != nil {
+        for word := range group.liberties {
+            e.recordLibertyWord(group.liberties, word)
+        }
+    }
+}
+
+// Records the cached legality of a move of 'color' at 'pos' before it changes.
+func (b *Board) journalLegality(pos int, color Color) {
+    if e := b.currentJournalEntry(); e != nil {
+        if len(e.legalities) == cap(e.legalities) {
+            newLegalities := make([]legalityChange, len(e.legalities), 2*cap(e.legalities)+8)
+            copy(newLegalities, e.legalities)
+            e.legalities = newLegalities
+        }
+        e.legalities = e.legalities[0:len(e.legalities)+1]
+        change := &e.legalities[len(e.legalities)-1]
+        change.pos, change.color = pos, color
+        if color == Black {
+            change.action, change.sequence = b.actionOnNextBlackMove[pos], b.fieldSequencesBlack[pos]
+        } else {
+            change.action, change.sequence = b.actionOnNextWhiteMove[pos], b.fieldSequencesWhite[pos]
+        }
+    }
+}
+
+// Records that the sequences of all fields are incremented for the colors which are up to date.
+func (b *Board) journalBump(blackUpToDate, whiteUpToDate bool) {
+    if e := b.currentJournalEntry(); e != nil {
+        e.bumpedAt = len(e.legalities)
+        e.bumpedBlack, e.bumpedWhite = blackUpToDate, whiteUpToDate
+    }
+}
+
+func (b *Board) revertLegality(change legalityChange) {
+    if change.color == Black {
+        b.actionOnNextBlackMove[change.pos], b.fieldSequencesBlack[change.pos] = change.action, change.sequence
+    } else {
+        b.actionOnNextWhiteMove[change.pos], b.fieldSequencesWhite[change.pos] = change.action, change.sequence
+    }
+}
+
+// Reverts the increment of the sequences in playMoveByPos. The fields whose sequences have not been
+// incremented are those which have been updated by the move, and their old sequences are restored
+// from the journal afterwards.
+func (b *Board) unbumpSequences(e *journalEntry) {
+    for i := 0; i < len(b.fields); i++ {
+        if e.bumpedBlack {
+            b.fieldSequencesBlack[i]--
+        }
+        if e.bumpedWhite {
+            b.fieldSequencesWhite[i]--
+        }
+    }
+}
+
+// ##################### journalEntry methods ##########################
+
+func (e *journalEntry) recordLibertyWord(liberties bitset, word int) {
+    if len(e.liberties) == cap(e.liberties) {
+        newLiberties := make([]libertyChange, len(e.liberties), 2*cap(e.liberties)+8)
+        copy(newLiberties, e.liberties)
+        e.liberties = newLiberties
+    }
+    e.liberties = e.liberties[0:len(e.liberties)+1]
+    e.liberties[len(e.liberties)-1] = libertyChange{ liberties: liberties, word: word, old: liberties[word] }
+}
+
+// ##################### journal helper functions ##########################
+
+func NewUndoError(msg string) Error {
+    return NewError(msg, ErrUndo)
+}
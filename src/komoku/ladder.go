@@ -0,0 +1,182 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * A ladder reader. It decides whether a group with one or two liberties can be captured by
+ * repeatedly putting it in atari. Every variation is read on a copy of the board, so ladder
+ * breakers and counter-captures of the defender are taken into account automatically.
+ */
+
+package komoku
+
+// The results of Board.ReadLadder
+const (
+    LadderNone = iota // the group does not have one or two liberties, or there is no group
+    LadderEscapes
+    LadderCaptured
+)
+
+// ##################### ladder methods of Board ##########################
+
+// Reads the ladder of the group at 'pos'. A group in atari is assumed to be the side to move, i.e. it
+// tries to escape. A group with two liberties is chased, i.e. the opponent moves first. Variations which
+// are longer than paramLadderDepth attacking moves count as an escape.
+func (b *Board) ReadLadder(pos int) int {
+    grp := b.fields[pos]
+    if grp == nil {
+        return LadderNone
+    }
+    depth := paramLadderDepth.Int()
+    switch grp.NumLiberties() {
+        case 1:
+            if b.ladderDefenderLoses(pos, depth) {
+                return LadderCaptured
+            }
+            return LadderEscapes
+        case 2:
+            if b.ladderAttackerWins(pos, depth) {
+                return LadderCaptured
+            }
+            return LadderEscapes
+    }
+    return LadderNone
+}
+
+// The group at 'pos' has two liberties and the opponent is to move. Returns true if the opponent can
+// capture it in a ladder.
+func (b *Board) ladderAttackerWins(pos int, depth int) bool {
+    if depth <= 0 {
+        return false
+    }
+    attacker := !b.fields[pos].Color
+    for _, lib := range b.fields[pos].Liberties() {
+        if !b.IsLegalMove(lib, attacker) {
+            continue
+        }
+        cpy := b.Copy()
+        cpy.playMoveByPos(lib, attacker)
+        if cpy.fields[pos].NumLiberties() == 1 && cpy.ladderDefenderLoses(pos, depth) {
+            return true
+        }
+    }
+    return false
+}
+
+// The group at 'pos' is in atari and its owner is to move. Returns true if it cannot escape, neither
+// by extending nor by capturing adjacent stones.
+func (b *Board) ladderDefenderLoses(pos int, depth int) bool {
+    grp := b.fields[pos]
+    for _, enemy := range b.adjacentEnemyGroups(grp) {
+        if enemy.NumLiberties() == 1 && b.ladderEscapesBy(pos, enemy.FirstLiberty(), depth) {
+            return false
+        }
+    }
+    return !b.ladderEscapesBy(pos, grp.FirstLiberty(), depth)
+}
+
+// The owner of the group at 'pos' plays at 'move'. Returns true if the group escapes afterwards.
+func (b *Board) ladderEscapesBy(pos, move int, depth int) bool {
+    color := b.fields[pos].Color
+    if !b.IsLegalMove(move, color) {
+        return false
+    }
+    cpy := b.Copy()
+    cpy.playMoveByPos(move, color)
+    switch libs := cpy.fields[pos].NumLiberties(); {
+        case libs >= 3:
+            return true
+        case libs == 2:
+            return !cpy.ladderAttackerWins(pos, depth-1)
+    }
+    return false
+}
+
+// Returns true if a move of 'color' at 'pos' extends a group in atari, but the group is captured in
+// a ladder afterwards. 'pos' has to be legal.
+func (b *Board) isDeadLadderExtension(pos int, color Color) bool {
+    _, context := b.getEnvironmentAndContext(pos, color)
+    extends := false
+    for _, grp := range context.adjSameColor {
+        if grp.NumLiberties() == 1 {
+            extends = true
+        }
+    }
+    if !extends {
+        return false
+    }
+    cpy := b.Copy()
+    cpy.playMoveByPos(pos, color)
+    return cpy.fields[pos].NumLiberties() == 2 && cpy.ladderAttackerWins(pos, paramLadderDepth.Int())
+}
+
+// Returns true if a move of 'color' at 'pos' puts an enemy group in atari which cannot escape
+// the ladder. 'pos' has to be legal.
+func (b *Board) isLadderCapture(pos int, color Color) bool {
+    _, context := b.getEnvironmentAndContext(pos, color)
+    candidates := NewGroupSlice()
+    for _, grp := range context.enemiesNotInAtari {
+        if grp.NumLiberties() == 2 {
+            candidates.Push(grp)
+        }
+    }
+    if len(candidates) == 0 {
+        return false
+    }
+    cpy := b.Copy()
+    cpy.playMoveByPos(pos, color)
+    for _, grp := range candidates {
+        stone := grp.FirstStone()
+        if cpy.fields[stone] != nil && cpy.fields[stone].NumLiberties() == 1 &&
+           cpy.ladderDefenderLoses(stone, paramLadderDepth.Int()) {
+            return true
+        }
+    }
+    return false
+}
+
+// Returns a ladder related reply of 'color' to the last move: an extension of a group which has been
+// put in atari, unless this extension is a dead ladder, or an atari which captures the stones of the
+// last move in a ladder. 'found' is false if there is no such move.
+func (b *Board) ladderReply(color Color) (pos int, found bool) {
+    if b.lastMove < 0 || b.fields[b.lastMove] == nil {
+        return 0, false
+    }
+    // escape from an atari of the last move
+    for _, grp := range b.adjacentEnemyGroups(b.fields[b.lastMove]) {
+        if grp.NumLiberties() == 1 {
+            lib := grp.FirstLiberty()
+            if b.IsLegalMove(lib, color) && !b.isDeadLadderExtension(lib, color) {
+                return lib, true
+            }
+        }
+    }
+    // capture the last move in a ladder
+    if last := b.fields[b.lastMove]; last.Color != color && last.NumLiberties() == 2 {
+        for _, lib := range last.Liberties() {
+            if b.IsLegalMove(lib, color) && b.isLadderCapture(lib, color) {
+                return lib, true
+            }
+        }
+    }
+    return 0, false
+}
+
+// Returns the groups of the other color which are adjacent to 'grp'
+func (b *Board) adjacentEnemyGroups(grp *Group) GroupSlice {
+    ret := NewGroupSlice()
+    pos := grp.first
+    for n := 0; n < grp.numStones; n++ {
+        for _, npos := range b.neighboursByPos(pos) {
+            if other := b.fields[npos]; other != nil && other.Color != grp.Color {
+                ret.PushUnique(other)
+            }
+        }
+        pos = b.nextStone[pos]
+    }
+    return ret
+}
@@ -0,0 +1,418 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * A local life-and-death solver based on proof-number search. The moves are restricted to the
+ * empty points of the region which the target group and its surrounding empty points form, bounded
+ * by the stones of the attacker. The target lives if it gets two eyes or if both players pass in a
+ * row (which covers seki), and it dies if it is captured.
+ */
+
+package komoku
+
+import (
+    "fmt"
+)
+
+// The results of the life-and-death solver, seen from the side to move at the root
+const (
+    SolveUnknown = iota // the node budget was exhausted
+    SolveWin
+    SolveLoss
+)
+
+// The outcome of a terminal node
+const (
+    ldOpen = iota // not terminal
+    ldAlive
+    ldDead
+)
+
+const pnsInfinity = 1 << 30
+
+// ################################################################################
+// ########################### pnsNode struct #####################################
+// ################################################################################
+
+// A node of the proof-number search tree. The root player is to move in OR nodes.
+type pnsNode struct {
+    parent *pnsNode
+    children []*pnsNode
+    move int // the pos of the move leading to this node, -1 is a pass
+    isOr bool
+    proof, disproof int
+    depth int
+    passes int // number of passes in a row leading to this node
+    board *Board // the position of the node, until the node is expanded
+    key string // the positionKey of the node
+    cutoff bool // the outcome of the node is only known because of the depth cutoff
+}
+
+// ################################################################################
+// ########################### lifeAndDeathSolver struct ##########################
+// ################################################################################
+
+type lifeAndDeathSolver struct {
+    root *Board
+    target int // a stone of the target group
+    defender Color
+    rootPlayer Color
+    region []int // the empty points in which moves are considered
+    maxDepth int
+    table map[string]int // transposition table: position key -> ldAlive or ldDead
+    nodes int
+}
+
+// ##################### lifeAndDeathSolver methods ##########################
+
+// Searches until the root is solved or 'maxNodes' nodes have been searched. Returns one of
+// Solve{Unknown,Win,Loss} for the root player and the main line of moves.
+func (s *lifeAndDeathSolver) solve(maxNodes int) (result int, line []int) {
+    rootNode := &pnsNode{ move: -1, isOr: true, board: s.root, key: positionKey(s.root, 0) }
+    s.setOutcome(rootNode, s.evaluate(s.root, 0))
+    for rootNode.proof != 0 && rootNode.disproof != 0 && s.nodes < maxNodes {
+        node := s.mostProving(rootNode)
+        s.expand(node)
+        s.update(node)
+    }
+    switch {
+        case rootNode.proof == 0:
+            return SolveWin, s.mainLine(rootNode)
+        case rootNode.disproof == 0:
+            return SolveLoss, s.mainLine(rootNode)
+    }
+    return SolveUnknown, nil
+}
+
+// Evaluates the position 'b' which has been reached after 'passes' passes in a row.
+func (s *lifeAndDeathSolver) evaluate(b *Board, passes int) int {
+    if grp := b.fields[s.target]; grp == nil || grp.Color != s.defender {
+        return ldDead
+    }
+    // Two passes in a row leave the target alive
+    if passes >= 2 {
+        return ldAlive
+    }
+    // The target lives if it has two points in the region on which the attacker cannot play and
+    // which are real eyes of the defender.
+    eyes := 0
+    for _, pos := range s.region {
+        if b.fields[pos] != nil || b.IsLegalMove(pos, !s.defender) {
+            continue
+        }
+        if s.isEye(b, pos) {
+            eyes++
+        }
+    }
+    if eyes >= 2 {
+        return ldAlive
+    }
+    return ldOpen
+}
+
+// Is the empty point 'pos' a real eye of the defender on 'b'? All neighbours have to be stones of the
+// defender. If they belong to more than one group, the eye is false if the attacker holds one diagonal
+// point on the edge, or two of them elsewhere. A false eye is left to the search.
+func (s *lifeAndDeathSolver) isEye(b *Board, pos int) bool {
+    var first *Group
+    oneGroup := true
+    for _, npos := range b.neighboursByPos(pos) {
+        grp := b.fields[npos]
+        if grp == nil || grp.Color != s.defender {
+            return false
+        }
+        if first == nil {
+            first = grp
+        } else if grp != first {
+            oneGroup = false
+        }
+    }
+    if oneGroup {
+        return true
+    }
+    x, y := b.posToXY(pos)
+    diagonals, attacker := 0, 0
+    for _, d := range [4]Point{ Point{-1,-1}, Point{1,-1}, Point{-1,1}, Point{1,1} } {
+        dx, dy := x + d.X, y + d.Y
+        if dx < 0 || dy < 0 || dx >= b.width || dy >= b.height {
+            continue
+        }
+        diagonals++
+        if grp := b.fields[b.xyToPos(dx, dy)]; grp != nil && grp.Color != s.defender {
+            attacker++
+        }
+    }
+    if diagonals < 4 {
+        return attacker == 0
+    }
+    return attacker < 2
+}
+
+// Expands 'node' and initializes the proof and disproof numbers of its children. A child at the maximal
+// depth whose outcome is still open counts as alive, because the attacker could not resolve it in time.
+func (s *lifeAndDeathSolver) expand(node *pnsNode) {
+    b := node.board
+    node.board = nil
+    color := b.ColorOfNextPlay()
+    moves := make([]int, 0, len(s.region)+1)
+    for _, pos := range s.region {
+        // the defender does not fill its own eyes
+        if b.fields[pos] == nil && b.IsLegalMove(pos, color) && !(color == s.defender && b.isEyeFillingMove(pos, color)) {
+            moves = moves[0:len(moves)+1]
+            moves[len(moves)-1] = pos
+        }
+    }
+    moves = moves[0:len(moves)+1]
+    moves[len(moves)-1] = -1
+
+    node.children = make([]*pnsNode, len(moves))
+    for i, pos := range moves {
+        child := &pnsNode{
+            parent: node,
+            move: pos,
+            isOr: !node.isOr,
+            depth: node.depth + 1,
+        }
+        cpy := b.Copy()
+        if pos < 0 {
+            cpy.PlayPass(color)
+            child.passes = node.passes + 1
+        } else {
+            cpy.playMoveByPos(pos, color)
+        }
+        child.board = cpy
+        child.key = positionKey(cpy, child.passes)
+        outcome, known := s.table[child.key]
+        if !known {
+            outcome = s.evaluate(cpy, child.passes)
+        }
+        if outcome == ldOpen && child.depth >= s.maxDepth {
+            outcome = ldAlive
+            child.cutoff = true
+        }
+        if outcome != ldOpen {
+            // a terminal child is never expanded
+            child.board = nil
+        }
+        s.setOutcome(child, outcome)
+        node.children[i] = child
+        s.nodes++
+    }
+}
+
+// Returns the most proving node in the subtree of 'node'.
+func (s *lifeAndDeathSolver) mostProving(node *pnsNode) *pnsNode {
+    for node.children != nil {
+        var best *pnsNode
+        for _, child := range node.children {
+            if best == nil || (node.isOr && child.proof < best.proof) || (!node.isOr && child.disproof < best.disproof) {
+                best = child
+            }
+        }
+        node = best
+    }
+    return node
+}
+
+// Sets the proof and disproof numbers of a node with the outcome 'outcome'.
+func (s *lifeAndDeathSolver) setOutcome(node *pnsNode, outcome int) {
+    switch {
+        case outcome == ldOpen:
+            node.proof, node.disproof = 1, 1
+        case (outcome == ldAlive) == (s.rootPlayer == s.defender):
+            node.proof, node.disproof = 0, pnsInfinity
+        default:
+            node.proof, node.disproof = pnsInfinity, 0
+    }
+}
+
+// Recomputes the proof and disproof numbers of 'node' and its ancestors. Solved nodes are stored in
+// the transposition table, unless their outcome relies on the depth cutoff.
+func (s *lifeAndDeathSolver) update(node *pnsNode) {
+    for ; node != nil; node = node.parent {
+        proof, disproof := 0, 0
+        if node.isOr {
+            proof = pnsInfinity
+        } else {
+            disproof = pnsInfinity
+        }
+        for _, child := range node.children {
+            if node.isOr {
+                proof = minInt(proof, child.proof)
+                disproof = minInt(disproof + child.disproof, pnsInfinity)
+            } else {
+                proof = minInt(proof + child.proof, pnsInfinity)
+                disproof = minInt(disproof, child.disproof)
+            }
+        }
+        node.proof, node.disproof = proof, disproof
+        if proof == 0 || disproof == 0 {
+            node.cutoff = s.reliesOnCutoff(node)
+            if !node.cutoff {
+                s.storeSolved(node)
+            }
+        }
+    }
+}
+
+// Returns true if the outcome of the solved node 'node' relies on the depth cutoff: every child which
+// decides the outcome on its own relies on it, or one of the children which decide it together does.
+func (s *lifeAndDeathSolver) reliesOnCutoff(node *pnsNode) bool {
+    // the player to move at 'node' wins if one child is won, and loses if all children are lost
+    won := (node.isOr && node.proof == 0) || (!node.isOr && node.disproof == 0)
+    for _, child := range node.children {
+        childWon := (node.isOr && child.proof == 0) || (!node.isOr && child.disproof == 0)
+        if won && childWon && !child.cutoff {
+            return false
+        }
+        if !won && child.cutoff {
+            return true
+        }
+    }
+    return won
+}
+
+// Stores the outcome of the solved node 'node' in the transposition table.
+func (s *lifeAndDeathSolver) storeSolved(node *pnsNode) {
+    rootWins := node.proof == 0
+    outcome := ldDead
+    if rootWins == (s.rootPlayer == s.defender) {
+        outcome = ldAlive
+    }
+    s.table[node.key] = outcome
+}
+
+// Follows the best moves from the root of a solved tree.
+func (s *lifeAndDeathSolver) mainLine(root *pnsNode) []int {
+    rootWins := root.proof == 0
+    line := make([]int, 0, 16)
+    for node := root; node.children != nil; {
+        var next *pnsNode
+        for _, child := range node.children {
+            if (rootWins && child.proof == 0) || (!rootWins && child.disproof == 0) {
+                // prefer real moves over passes
+                if next == nil || next.move < 0 {
+                    next = child
+                }
+            }
+        }
+        if next == nil {
+            break
+        }
+        if len(line) == cap(line) {
+            newLine := make([]int, len(line), 2*cap(line))
+            copy(newLine, line)
+            line = newLine
+        }
+        line = line[0:len(line)+1]
+        line[len(line)-1] = next.move
+        node = next
+    }
+    return line
+}
+
+// ##################### life-and-death methods of Board ##########################
+
+// Decides whether the group at 'target' can be killed (if 'toMove' is the opponent of the group) or can
+// live (if 'toMove' owns the group), with 'toMove' to play. At most 'maxNodes' nodes are searched.
+// Returns one of Solve{Unknown,Win,Loss} for 'toMove' and the main line of moves, where -1 denotes a pass.
+func (b *Board) SolveLifeAndDeath(target int, toMove Color, maxNodes int) (result int, line []int, err Error) {
+    grp := b.fields[target]
+    if grp == nil {
+        return SolveUnknown, nil, NewSolverError("there is no group to solve")
+    }
+    root := b.Copy()
+    root.setColorOfNextPlay(toMove)
+    s := &lifeAndDeathSolver{
+        root: root,
+        target: target,
+        defender: grp.Color,
+        rootPlayer: toMove,
+        table: make(map[string]int),
+    }
+    s.region = b.lifeAndDeathRegion(target)
+    if len(s.region) > paramSolverRegion.Int() {
+        return SolveUnknown, nil, NewSolverError(fmt.Sprintf("the region has %d empty points, at most %d are allowed",
+                                                            len(s.region), paramSolverRegion.Int()))
+    }
+    s.maxDepth = 3*len(s.region) + 2
+    result, line = s.solve(maxNodes)
+    return result, line, nil
+}
+
+// Returns the empty points which can be reached from 'target' without crossing stones of the other
+// color, i.e. the region the group lives in.
+func (b *Board) lifeAndDeathRegion(target int) []int {
+    color := b.fields[target].Color
+    size := len(b.fields)
+    visited := make([]bool, size)
+    region := make([]int, 0, size)
+    stack := make([]int, 1, size)
+    stack[0] = target
+    visited[target] = true
+    for len(stack) > 0 {
+        pos := stack[len(stack)-1]
+        stack = stack[0:len(stack)-1]
+        if b.fields[pos] == nil {
+            region = region[0:len(region)+1]
+            region[len(region)-1] = pos
+        }
+        for _, npos := range b.neighboursByPos(pos) {
+            if visited[npos] {
+                continue
+            }
+            if grp := b.fields[npos]; grp == nil || grp.Color == color {
+                visited[npos] = true
+                stack = stack[0:len(stack)+1]
+                stack[len(stack)-1] = npos
+            }
+        }
+    }
+    return region
+}
+
+// ##################### life-and-death helper functions ##########################
+
+// Returns a string which identifies the position on 'b', including the side to move, the ko and the
+// number of passes in a row.
+func positionKey(b *Board, passes int) string {
+    size := len(b.fields)
+    key := make([]byte, size + 3)
+    for pos := 0; pos < size; pos++ {
+        switch grp := b.fields[pos]; {
+            case grp == nil:
+                key[pos] = '.'
+            case grp.Color == Black:
+                key[pos] = 'X'
+            default:
+                key[pos] = 'O'
+        }
+    }
+    key[size] = 'w'
+    if b.colorOfNextPlay == Black {
+        key[size] = 'b'
+    }
+    key[size+1] = byte('0' + passes)
+    key[size+2] = '-'
+    ret := string(key)
+    if b.ko != nil && b.ko.Color == b.colorOfNextPlay {
+        ret += fmt.Sprintf("%d", b.ko.Pos)
+    }
+    return ret
+}
+
+func minInt(a, b int) int {
+    if a < b {
+        return a
+    }
+    return b
+}
+
+func NewSolverError(msg string) Error {
+    return NewError(msg, ErrSolverError)
+}
@@ -0,0 +1,204 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * This file implements the minorization-maximization (MM) algorithm for fitting a generalized
+ * Bradley-Terry model to the moves of game records, as described in Rémi Coulom's "Computing Elo
+ * Ratings of Move Patterns in the Game of Go". Every legal move of a position is a team of
+ * features, and the move which has been played is the winner of the competition between them.
+ */
+
+package komoku
+
+// ################################################################################
+// ########################### MMTrainer struct ###################################
+// ################################################################################
+
+// One competition: the feature teams of all legal moves of a position and the winning team.
+type mmPosition struct {
+    teams [][]int // indices of the features of each legal move
+    winner int // index of the played move in teams
+}
+
+// Collects positions from game records and fits the strengths of their features.
+type MMTrainer struct {
+    index map[uint64]int // maps a feature key onto its index in keys, gammas and wins
+    keys []uint64
+    gammas []float64
+    wins []float64 // how often each feature was part of the winning team
+    positions []*mmPosition
+    numPositions int
+}
+
+// ##################### MMTrainer methods ##########################
+
+// Adds the position on 'b' in which 'color' played at 'played' as a competition. 'played' has to be legal.
+func (m *MMTrainer) AddPosition(b *Board, color Color, played int) {
+    legal := b.listLegalPosses(color)
+    p := &mmPosition{ teams: make([][]int, len(legal)), winner: -1 }
+    for i, pos := range legal {
+        features := b.moveFeatures(pos, color)
+        team := make([]int, len(features))
+        for k, key := range features {
+            team[k] = m.featureIndex(key)
+        }
+        p.teams[i] = team
+        if pos == played {
+            p.winner = i
+        }
+    }
+    if p.winner < 0 {
+        return
+    }
+    for _, f := range p.teams[p.winner] {
+        m.wins[f]++
+    }
+    if m.numPositions == len(m.positions) {
+        newPositions := make([]*mmPosition, m.numPositions, 2*m.numPositions + 16)
+        copy(newPositions, m.positions)
+        m.positions = newPositions
+    }
+    m.positions = m.positions[0:m.numPositions+1]
+    m.positions[m.numPositions] = p
+    m.numPositions++
+}
+
+// Replays the main line of 'game' and adds a competition for every move. Returns the number of
+// positions which have been added. The replay stops at the first move komoku considers illegal.
+func (m *MMTrainer) AddGame(game *SGFGame) int {
+    b := NewBoard(game.BoardSize)
+    // Setup stones are placed by playing them. They are no moves of the game, so they must not
+    // influence the distance features.
+    for _, mv := range game.Setup {
+        b.PlayMove(mv.Vertex.X, mv.Vertex.Y, mv.Color)
+    }
+    b.lastMove, b.secondLastMove = -1, -1
+    added := 0
+    for _, mv := range game.Moves {
+        if mv.Vertex.Pass {
+            b.PlayPass(mv.Color)
+            continue
+        }
+        pos := b.xyToPos(mv.Vertex.X, mv.Vertex.Y)
+        if !b.IsLegalMove(pos, mv.Color) {
+            break
+        }
+        m.AddPosition(b, mv.Color, pos)
+        added++
+        b.playMoveByPos(pos, mv.Color)
+    }
+    return added
+}
+
+// Returns the index of the feature 'key' and registers it if necessary
+func (m *MMTrainer) featureIndex(key uint64) int {
+    if i, ok := m.index[key]; ok {
+        return i
+    }
+    i := len(m.keys)
+    if i == cap(m.keys) {
+        newKeys := make([]uint64, i, 2*i + 16)
+        newGammas := make([]float64, i, 2*i + 16)
+        newWins := make([]float64, i, 2*i + 16)
+        copy(newKeys, m.keys)
+        copy(newGammas, m.gammas)
+        copy(newWins, m.wins)
+        m.keys, m.gammas, m.wins = newKeys, newGammas, newWins
+    }
+    m.keys = m.keys[0:i+1]
+    m.gammas = m.gammas[0:i+1]
+    m.wins = m.wins[0:i+1]
+    m.keys[i] = key
+    m.gammas[i] = 1.0
+    m.wins[i] = 0.0
+    m.index[key] = i
+    return i
+}
+
+// Performs one MM iteration, i.e. updates the strengths of each family once. Features of the
+// same family never appear in the same team, so a whole family can be updated at once.
+// Returns the largest relative change of a strength.
+func (m *MMTrainer) Iterate() float64 {
+    maxChange := 0.0
+    denominators := make([]float64, len(m.gammas))
+    for family := 0; family < numFeatureFamilies; family++ {
+        for i := range denominators {
+            denominators[i] = 0.0
+        }
+        for k := 0; k < m.numPositions; k++ {
+            p := m.positions[k]
+            // the strengths of the teams and their sum
+            strengths := make([]float64, len(p.teams))
+            total := 0.0
+            for t, team := range p.teams {
+                s := 1.0
+                for _, f := range team {
+                    s *= m.gammas[f]
+                }
+                strengths[t] = s
+                total += s
+            }
+            // the team mates of f contribute strengths[t]/gammas[f]
+            for t, team := range p.teams {
+                for _, f := range team {
+                    if m.featureFamily(f) == family {
+                        denominators[f] += strengths[t] / m.gammas[f] / total
+                    }
+                }
+            }
+        }
+        for f := range m.gammas {
+            if m.featureFamily(f) != family {
+                continue
+            }
+            // A prior of one virtual win and one virtual loss against a feature of strength 1
+            // keeps features which are rare or never win away from 0 and infinity.
+            newGamma := (m.wins[f] + 1.0) / (denominators[f] + 2.0/(m.gammas[f] + 1.0))
+            change := newGamma/m.gammas[f] - 1.0
+            if change < 0 {
+                change = -change
+            }
+            if change > maxChange {
+                maxChange = change
+            }
+            m.gammas[f] = newGamma
+        }
+    }
+    return maxChange
+}
+
+func (m *MMTrainer) featureFamily(f int) int {
+    family, _ := splitFeatureKey(m.keys[f])
+    return family
+}
+
+// Returns the number of positions which have been added
+func (m *MMTrainer) NumPositions() int {
+    return m.numPositions
+}
+
+// Returns the current strengths of all features
+func (m *MMTrainer) Weights() *PatternWeights {
+    w := NewPatternWeights()
+    for i, key := range m.keys {
+        w.SetGamma(key, float(m.gammas[i]))
+    }
+    return w
+}
+
+// ##################### MMTrainer helper functions ##########################
+
+func NewMMTrainer() *MMTrainer {
+    return &MMTrainer{
+        index: make(map[uint64]int),
+        keys: make([]uint64, 0, 16),
+        gammas: make([]float64, 0, 16),
+        wins: make([]float64, 0, 16),
+        positions: make([]*mmPosition, 0, 16),
+    }
+}
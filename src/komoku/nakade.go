@@ -0,0 +1,162 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * Recognition of nakade shapes: enclosed eye spaces of three to six points which form only one
+ * eye if the opponent plays on their vital point first. Random playouts hardly ever find these
+ * points, so big dead groups look alive to the search unless the playouts play them on purpose.
+ */
+
+package komoku
+
+import (
+    "sort"
+)
+
+const maxNakadeSize = 6
+
+// A nakade shape is identified by the sorted numbers of neighbours which each point has inside
+// the shape. Among the shapes of up to six points, these numbers are unique for the nakade shapes.
+type nakadeShape struct {
+    name string
+    degrees []int
+    vitalDegree int // the number of neighbours of the vital point, which is unique in the shape
+}
+
+var nakadeShapes = []nakadeShape{
+    nakadeShape{ "straight or bent three", []int{ 1, 1, 2 }, 2 },
+    nakadeShape{ "pyramid four", []int{ 1, 1, 1, 3 }, 3 },
+    nakadeShape{ "bulky five", []int{ 1, 2, 2, 2, 3 }, 3 },
+    nakadeShape{ "crossed five", []int{ 1, 1, 1, 1, 4 }, 4 },
+    nakadeShape{ "rabbity six", []int{ 1, 1, 2, 2, 2, 4 }, 4 },
+}
+
+// ##################### nakade methods of Board ##########################
+
+// Looks for a nakade shape containing the empty point 'pos'. The shape consists of empty points and
+// stones of the attacker, and it is enclosed by stones of its 'owner' and the edge of the board.
+// 'vital' is the vital point of the shape, 'empty' is true if there are no stones inside the shape.
+func (b *Board) nakadeAt(pos int) (vital int, owner Color, empty, found bool) {
+    if b.fields[pos] != nil {
+        return 0, Black, false, false
+    }
+    for _, owner := range []Color{ Black, White } {
+        region, isEmpty, ok := b.nakadeRegion(pos, owner)
+        if !ok {
+            continue
+        }
+        if vital, ok := b.nakadeVitalPoint(region); ok && b.fields[vital] == nil {
+            return vital, owner, isEmpty, true
+        }
+    }
+    return 0, Black, false, false
+}
+
+// Collects the points which can be reached from 'pos' without crossing stones of 'owner'. 'ok' is false if
+// the region is smaller than 3 or larger than maxNakadeSize points, or if it does not touch any stone of
+// 'owner'. 'empty' is true if the region does not contain any stones.
+func (b *Board) nakadeRegion(pos int, owner Color) (region []int, empty, ok bool) {
+    region = make([]int, 1, maxNakadeSize+1)
+    region[0] = pos
+    empty = true
+    touchesOwner := false
+    for i := 0; i < len(region); i++ {
+        for _, npos := range b.neighboursByPos(region[i]) {
+            grp := b.fields[npos]
+            if grp != nil && grp.Color == owner {
+                touchesOwner = true
+                continue
+            }
+            if containsInt(region, npos) {
+                continue
+            }
+            if len(region) == maxNakadeSize {
+                return nil, false, false
+            }
+            if grp != nil {
+                empty = false
+            }
+            region = region[0:len(region)+1]
+            region[len(region)-1] = npos
+        }
+    }
+    return region, empty, len(region) >= 3 && touchesOwner
+}
+
+// Returns the vital point of 'region' if it has a nakade shape.
+func (b *Board) nakadeVitalPoint(region []int) (vital int, ok bool) {
+    degrees := make([]int, len(region))
+    for i, pos := range region {
+        for _, npos := range b.neighboursByPos(pos) {
+            if containsInt(region, npos) {
+                degrees[i]++
+            }
+        }
+    }
+    sorted := make([]int, len(degrees))
+    copy(sorted, degrees)
+    sort.SortInts(sorted)
+    for _, shape := range nakadeShapes {
+        if !equalInts(sorted, shape.degrees) {
+            continue
+        }
+        for i, degree := range degrees {
+            if degree == shape.vitalDegree {
+                return region[i], true
+            }
+        }
+    }
+    return 0, false
+}
+
+// Returns true if 'pos' is the vital point of a nakade shape.
+func (b *Board) isNakadeVitalPoint(pos int) bool {
+    vital, _, _, found := b.nakadeAt(pos)
+    return found && vital == pos
+}
+
+// Returns the vital point of a nakade shape next to the last move if 'color' should play it: the attacker
+// kills by playing there, and the owner of an empty shape makes two eyes. 'found' is false otherwise.
+func (b *Board) nakadeMove(color Color) (pos int, found bool) {
+    if b.lastMove < 0 {
+        return 0, false
+    }
+    for _, npos := range b.neighboursByPos(b.lastMove) {
+        if b.fields[npos] != nil {
+            continue
+        }
+        vital, owner, empty, ok := b.nakadeAt(npos)
+        if ok && (owner != color || empty) && b.IsLegalMove(vital, color) {
+            return vital, true
+        }
+    }
+    return 0, false
+}
+
+// ##################### nakade helper functions ##########################
+
+func containsInt(slice []int, value int) bool {
+    for _, v := range slice {
+        if v == value {
+            return true
+        }
+    }
+    return false
+}
+
+func equalInts(a, b []int) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for i := range a {
+        if a[i] != b[i] {
+            return false
+        }
+    }
+    return true
+}
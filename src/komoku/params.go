@@ -0,0 +1,214 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * The registry of the tunable constants of the search and the playouts. Each parameter has a
+ * name, a type and a range, so that it can be set via GTP, read from a config file or tuned
+ * automatically by the tuner (see tuner.go).
+ */
+
+package komoku
+
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// The types of parameters
+const (
+    ParamInt = iota
+    ParamFloat
+)
+
+// ################################################################################
+// ########################### the registered parameters ##########################
+// ################################################################################
+
+var parameters = make(map[string]*Parameter)
+
+var (
+    // How often Board.PlayRandomMove picks a random empty field before it looks at all legal moves
+    paramRandomTries = registerParameter("playout.randomtries", ParamInt, 4, 1, 32)
+    // The number of simulations a prior derived from pattern weights is worth
+    paramPriorEquivalence = registerParameter("search.priorequivalence", ParamInt, 10, 0, 200)
+    // The number of simulations the prior of a ladder capture or of a dead ladder extension is worth. The
+    // ladders of every new child of a node are read for it, so it is off by default
+    paramLadderPrior = registerParameter("search.ladderprior", ParamInt, 0, 0, 200)
+    // The maximal number of attacking moves the ladder reader reads
+    paramLadderDepth = registerParameter("ladder.depth", ParamInt, 100, 1, 400)
+    // The maximal number of empty points in the region of the life-and-death solver
+    paramSolverRegion = registerParameter("solver.region", ParamInt, 16, 1, 64)
+    // The number of nodes the life-and-death solver may search
+    paramSolverNodes = registerParameter("solver.nodes", ParamInt, 20000, 100, 10000000)
+    // The number of nodes the game solver for tiny boards may search
+    paramGameSolverNodes = registerParameter("gamesolver.nodes", ParamInt, 1000000, 100, 100000000)
+    // The number of recent positions the playouts compare for superko, see Board.SetSuperko
+    paramSuperkoPlayoutWindow = registerParameter("playout.superkowindow", ParamInt, 8, 0, 400)
+    // The move cap factor of playouts on a FastBoard if no move cap is set. A FastBoard ignores superko, so a
+    // playout could otherwise repeat a cycle forever, see AI.playoutMoveCap
+    paramFastPlayoutMoveCap = registerParameter("playout.fastmovecap", ParamFloat, 3, 1, 100)
+    // The number of playouts which decide the status of the stones at the end of the game
+    paramStatusPlayouts = registerParameter("status.playouts", ParamInt, 200, 1, 100000)
+    // A group is dead if the mean ownership of its stones, 1 for its own color and -1 for the opponent, is below this
+    paramStatusDeadThreshold = registerParameter("status.deadthreshold", ParamFloat, 0, -1, 1)
+)
+
+// ################################################################################
+// ########################### Parameter struct ###################################
+// ################################################################################
+
+// A named, typed constant with a range of allowed values.
+type Parameter struct {
+    Name string
+    Kind int // one of Param{Int,Float}
+    Min, Max float // the range of allowed values, inclusive
+    Default float
+    value float
+}
+
+// ##################### Parameter methods ##########################
+
+// Returns the value of an integer parameter
+func (p *Parameter) Int() int {
+    return int(p.value)
+}
+
+// Returns the value of the parameter
+func (p *Parameter) Float() float {
+    return p.value
+}
+
+// Sets the value of the parameter. Values of integer parameters are rounded. Returns an error if 'value'
+// is out of range.
+func (p *Parameter) Set(value float) Error {
+    if p.Kind == ParamInt {
+        value = roundFloat(value)
+    }
+    if value < p.Min || value > p.Max {
+        return NewParameterError(fmt.Sprintf("value %g of %s is out of range [%g, %g]", value, p.Name, p.Min, p.Max))
+    }
+    p.value = value
+    return nil
+}
+
+// Returns the value formatted according to the type of the parameter
+func (p *Parameter) String() string {
+    if p.Kind == ParamInt {
+        return fmt.Sprintf("%d", p.Int())
+    }
+    return fmt.Sprintf("%g", p.value)
+}
+
+// ##################### Parameter helper functions ##########################
+
+// Registers a new parameter with the default value 'value'. This should only be used to
+// initialize package level variables.
+func registerParameter(name string, kind int, value, min, max float) *Parameter {
+    if _, present := parameters[name]; present {
+        panic("\n\nParameter " + name + " registered twice.\n\n")
+    }
+    p := &Parameter{ Name: name, Kind: kind, Min: min, Max: max, Default: value, value: value }
+    parameters[name] = p
+    return p
+}
+
+// Returns the parameter called 'name'
+func GetParameter(name string) (p *Parameter, ok bool) {
+    p, ok = parameters[name]
+    return
+}
+
+// Returns all parameters, sorted by their names
+func ListParameters() []*Parameter {
+    names := make([]string, len(parameters))
+    i := 0
+    for name, _ := range parameters {
+        names[i] = name
+        i++
+    }
+    sort.SortStrings(names)
+    ret := make([]*Parameter, len(names))
+    for i, name := range names {
+        ret[i] = parameters[name]
+    }
+    return ret
+}
+
+// Reads parameter values from 'filename'. Each line has the format "name value", '#' starts a comment.
+// Parameters which are not mentioned keep their values.
+func LoadParameters(filename string) Error {
+    file, er := os.Open(filename, os.O_RDONLY, 0)
+    if er != nil {
+        return NewIOError(er)
+    }
+    defer file.Close()
+    input := bufio.NewReader(file)
+    lineNumber := 0
+    for {
+        line, er := input.ReadString('\n')
+        lineNumber++
+        if hashPos := strings.Index(line, "#"); hashPos != -1 {
+            line = line[0:hashPos]
+        }
+        fields := strings.Fields(line)
+        if len(fields) == 2 {
+            p, ok := GetParameter(fields[0])
+            if !ok {
+                return NewParameterError(fmt.Sprintf("%s:%d: unknown parameter %s", filename, lineNumber, fields[0]))
+            }
+            value, er1 := strconv.Atof(fields[1])
+            if er1 != nil {
+                return NewParameterError(fmt.Sprintf("%s:%d: malformed value", filename, lineNumber))
+            }
+            if err := p.Set(value); err != nil {
+                return err
+            }
+        } else if len(fields) != 0 {
+            return NewParameterError(fmt.Sprintf("%s:%d: expected 2 fields", filename, lineNumber))
+        }
+        if er == os.EOF {
+            break
+        } else if er != nil {
+            return NewIOError(er)
+        }
+    }
+    return nil
+}
+
+// Writes the values of all parameters to 'filename' in the format read by LoadParameters.
+func SaveParameters(filename string) Error {
+    file, er := os.Open(filename, os.O_CREATE | os.O_TRUNC | os.O_WRONLY, 0666)
+    if er != nil {
+        return NewIOError(er)
+    }
+    defer file.Close()
+    out := bufio.NewWriter(file)
+    fmt.Fprintf(out, "# komoku parameters: name value\n")
+    for _, p := range ListParameters() {
+        fmt.Fprintf(out, "%s %s\n", p.Name, p)
+    }
+    if er := out.Flush(); er != nil {
+        return NewIOError(er)
+    }
+    return nil
+}
+
+func roundFloat(f float) float {
+    if f < 0 {
+        return -float(int(-f + 0.5))
+    }
+    return float(int(f + 0.5))
+}
+
+func NewParameterError(msg string) Error {
+    return NewError(msg, ErrParameterError)
+}
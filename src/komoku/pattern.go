@@ -0,0 +1,602 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * This file defines the move features used by the pattern based playout policy and the priors,
+ * and the PatternWeights which assign a strength (a 'gamma' in the Bradley-Terry model) to each
+ * feature. The weights are fitted offline by e/patternlearn, see mm.go.
+ */
+
+package komoku
+
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// ################################################################################
+// ########################### feature families ###################################
+// ################################################################################
+
+// Each feature belongs to exactly one family, and a move has at most one feature of each family.
+const (
+    FeaturePattern3x3 = iota // the 8 neighbours of the move
+    FeaturePatternDiamond // all points with a manhattan distance of at most 2 to the move
+    FeatureCapture // the move captures stones
+    FeatureAtari // the move puts an enemy group in atari
+    FeatureDistPrev // distance to the last move
+    FeatureDistSecondPrev // distance to the move before the last move
+    FeatureLine // distance to the edge of the board
+    numFeatureFamilies
+)
+
+var featureFamilyNames = []string{ "pattern3x3", "diamond", "capture", "atari", "distprev", "distprev2", "line" }
+
+// The states of the points of a pattern
+const (
+    patternEmpty = iota
+    patternOwn
+    patternOther
+    patternOffBoard
+)
+
+const (
+    maxFeatureDistance = 17 // larger distances are not distinguished
+    maxFeatureLine = 5 // lines above this one are not distinguished
+)
+
+// ################################################################################
+// ########################### global variables and initialization ################
+// ################################################################################
+
+// The offsets of the points of the diamond pattern. The first 8 of them form the 3x3 pattern.
+var diamondOffsets = []Point{
+    Point{-1,-1}, Point{0,-1}, Point{1,-1}, Point{-1,0}, Point{1,0}, Point{-1,1}, Point{0,1}, Point{1,1},
+    Point{0,-2}, Point{-2,0}, Point{2,0}, Point{0,2},
+}
+
+// patternSymmetries[s][i] is the index in diamondOffsets of the image of diamondOffsets[i] under
+// the symmetry s of the square.
+var patternSymmetries [8][]int
+
+func init() {
+    for s := 0; s < 8; s++ {
+        patternSymmetries[s] = make([]int, len(diamondOffsets))
+        for i, off := range diamondOffsets {
+            img := transformOffset(off, s)
+            for j, other := range diamondOffsets {
+                if other.X == img.X && other.Y == img.Y {
+                    patternSymmetries[s][i] = j
+                    break
+                }
+            }
+        }
+    }
+}
+
+// Applies the symmetry 's' (0 <= s < 8) of the square to the offset 'p'
+func transformOffset(p Point, s int) Point {
+    x, y := p.X, p.Y
+    if s & 4 != 0 {
+        x, y = y, x
+    }
+    if s & 2 != 0 {
+        x = -x
+    }
+    if s & 1 != 0 {
+        y = -y
+    }
+    return Point{ x, y }
+}
+
+// ################################################################################
+// ########################### feature extraction #################################
+// ################################################################################
+
+// Returns the key of the feature 'value' of the family 'family'.
+func featureKey(family int, value uint32) uint64 {
+    return uint64(family)<<32 | uint64(value)
+}
+
+// Splits a feature key into its family and value.
+func splitFeatureKey(key uint64) (family int, value uint32) {
+    return int(key >> 32), uint32(key & 0xffffffff)
+}
+
+// Returns the features of a move of 'color' at 'pos'. The move has to be legal.
+func (b *Board) moveFeatures(pos int, color Color) []uint64 {
+    ret := make([]uint64, numFeatureFamilies)
+    n := 0
+
+    states := b.patternStates(pos, color)
+    ret[n] = featureKey(FeaturePattern3x3, canonicalPattern(states, 8))
+    n++
+    ret[n] = featureKey(FeaturePatternDiamond, canonicalPattern(states, len(diamondOffsets)))
+    n++
+
+    _, context := b.getEnvironmentAndContext(pos, color)
+    if len(context.enemiesInAtari) > 0 {
+        ret[n] = featureKey(FeatureCapture, 1)
+        n++
+    }
+    for _, grp := range context.enemiesNotInAtari {
+        if grp.NumLiberties() == 2 {
+            ret[n] = featureKey(FeatureAtari, 1)
+            n++
+            break
+        }
+    }
+
+    if b.lastMove >= 0 {
+        ret[n] = featureKey(FeatureDistPrev, uint32(b.moveDistance(pos, b.lastMove)))
+        n++
+    }
+    if b.secondLastMove >= 0 {
+        ret[n] = featureKey(FeatureDistSecondPrev, uint32(b.moveDistance(pos, b.secondLastMove)))
+        n++
+    }
+
+    x, y := b.posToXY(pos)
+    line := x
+    for _, l := range []int{ y, b.width - 1 - x, b.height - 1 - y } {
+        if l < line {
+            line = l
+        }
+    }
+    line++
+    if line > maxFeatureLine {
+        line = maxFeatureLine
+    }
+    ret[n] = featureKey(FeatureLine, uint32(line))
+    n++
+
+    return ret[0:n]
+}
+
+// The distance measure of Coulom's "Computing Elo Ratings of Move Patterns in the Game of Go":
+// dx + dy + max(dx, dy).
+func (b *Board) moveDistance(pos1, pos2 int) int {
+    x1, y1 := b.posToXY(pos1)
+    x2, y2 := b.posToXY(pos2)
+    dx, dy := x1 - x2, y1 - y2
+    if dx < 0 {
+        dx = -dx
+    }
+    if dy < 0 {
+        dy = -dy
+    }
+    d := dx + dy
+    if dx > dy {
+        d += dx
+    } else {
+        d += dy
+    }
+    if d > maxFeatureDistance {
+        d = maxFeatureDistance
+    }
+    return d
+}
+
+// Returns the states of the points around 'pos' in the order of diamondOffsets, seen from the player 'color'.
+func (b *Board) patternStates(pos int, color Color) []int {
+    states := make([]int, len(diamondOffsets))
+    x, y := b.posToXY(pos)
+    for i, off := range diamondOffsets {
+        px, py := x + off.X, y + off.Y
+        if px < 0 || py < 0 || px >= b.width || py >= b.height {
+            states[i] = patternOffBoard
+        } else if grp := b.fields[b.xyToPos(px, py)]; grp == nil {
+            states[i] = patternEmpty
+        } else if grp.Color == color {
+            states[i] = patternOwn
+        } else {
+            states[i] = patternOther
+        }
+    }
+    return states
+}
+
+// Encodes the first 'size' entries of 'states' with two bits per point. Of the encodings under all
+// symmetries, the smallest one is returned, so symmetric patterns share the same code.
+func canonicalPattern(states []int, size int) uint32 {
+    var best uint32 = 0xffffffff
+    for s := 0; s < 8; s++ {
+        var code uint32 = 0
+        for i := 0; i < size; i++ {
+            code |= uint32(states[patternSymmetries[s][i]]) << uint(2*i)
+        }
+        if code < best {
+            best = code
+        }
+    }
+    return best
+}
+
+// ################################################################################
+// ########################### PatternWeights struct ##############################
+// ################################################################################
+
+// Assigns a strength to each feature. The strength of a move is the product of the strengths of
+// its features. Unknown features have the strength 1.
+type PatternWeights struct {
+    gammas map[uint64]float
+}
+
+// ##################### PatternWeights methods ##########################
+
+// Returns the strength of the feature 'key'
+func (w *PatternWeights) Gamma(key uint64) float {
+    if gamma, ok := w.gammas[key]; ok {
+        return gamma
+    }
+    return 1.0
+}
+
+// Returns the strength of a move of 'color' at 'pos' on 'b'. The move has to be legal.
+func (w *PatternWeights) MoveStrength(b *Board, pos int, color Color) float {
+    strength := float(1.0)
+    for _, key := range b.moveFeatures(pos, color) {
+        strength *= w.Gamma(key)
+    }
+    return strength
+}
+
+// Returns the strength of a move of 'color' at 'pos' on 'b' without the strengths of the distances to the
+// last two moves, which change with every move. The move has to be legal.
+func (w *PatternWeights) localStrength(b *Board, pos int, color Color) float {
+    strength := float(1.0)
+    for _, key := range b.moveFeatures(pos, color) {
+        if family, _ := splitFeatureKey(key); family != FeatureDistPrev && family != FeatureDistSecondPrev {
+            strength *= w.Gamma(key)
+        }
+    }
+    return strength
+}
+
+// Returns the number of features with a strength
+func (w *PatternWeights) NumFeatures() int {
+    return len(w.gammas)
+}
+
+// Sets the strength of the feature 'key'
+func (w *PatternWeights) SetGamma(key uint64, gamma float) {
+    w.gammas[key] = gamma
+}
+
+// Writes the weights to 'filename'. Each line has the format "family value gamma".
+func (w *PatternWeights) WriteFile(filename string) Error {
+    file, er := os.Open(filename, os.O_CREATE | os.O_TRUNC | os.O_WRONLY, 0666)
+    if er != nil {
+        return NewIOError(er)
+    }
+    defer file.Close()
+    out := bufio.NewWriter(file)
+    fmt.Fprintf(out, "# komoku pattern weights: family value gamma\n")
+    for key, gamma := range w.gammas {
+        family, value := splitFeatureKey(key)
+        fmt.Fprintf(out, "%s %d %g\n", featureFamilyNames[family], value, gamma)
+    }
+    if er := out.Flush(); er != nil {
+        return NewIOError(er)
+    }
+    return nil
+}
+
+// Plays a move of 'color' on 'b' which is chosen randomly, with probabilities proportional to the move
+// strengths. Moves which only fill own eyes or break a seki are never chosen; if there is no other move,
+// 'color' passes.
+// Returns the played vertex, like Board.PlayRandomMove. A playout should use one patternPolicy for all
+// its moves instead, which does not extract the features of every move again.
+func (w *PatternWeights) PlayMove(b *Board, color Color) Vertex {
+    return newPatternPolicy(w).PlayMove(b, color)
+}
+
+// Returns the average strength of the legal moves of 'color' on 'b' which a playout would consider.
+// Returns 1 if there are no such moves.
+func (w *PatternWeights) meanMoveStrength(b *Board, color Color) float {
+    sum := float(0.0)
+    n := 0
+    for _, pos := range b.listLegalPosses(color) {
+        if !b.isUnwantedPlayoutMove(pos, color) {
+            sum += w.MoveStrength(b, pos, color)
+            n++
+        }
+    }
+    if n == 0 {
+        return 1.0
+    }
+    return sum / float(n)
+}
+
+// ##################### PatternWeights helper functions ##########################
+
+// Creates PatternWeights in which every feature has the strength 1
+func NewPatternWeights() *PatternWeights {
+    return &PatternWeights{ gammas: make(map[uint64]float) }
+}
+
+// Reads the weights written by PatternWeights.WriteFile
+func ReadPatternWeights(filename string) (*PatternWeights, Error) {
+    file, er := os.Open(filename, os.O_RDONLY, 0)
+    if er != nil {
+        return nil, NewIOError(er)
+    }
+    defer file.Close()
+    w := NewPatternWeights()
+    input := bufio.NewReader(file)
+    lineNumber := 0
+    for {
+        line, er := input.ReadString('\n')
+        lineNumber++
+        if hashPos := strings.Index(line, "#"); hashPos != -1 {
+            line = line[0:hashPos]
+        }
+        fields := strings.Fields(line)
+        if len(fields) == 3 {
+            family := -1
+            for i, name := range featureFamilyNames {
+                if name == fields[0] {
+                    family = i
+                }
+            }
+            value, er1 := strconv.Atoui(fields[1])
+            gamma, er2 := strconv.Atof(fields[2])
+            if family < 0 || er1 != nil || er2 != nil {
+                return nil, NewPatternFileError(fmt.Sprintf("%s:%d: malformed line", filename, lineNumber))
+            }
+            w.SetGamma(featureKey(family, uint32(value)), gamma)
+        } else if len(fields) != 0 {
+            return nil, NewPatternFileError(fmt.Sprintf("%s:%d: expected 3 fields", filename, lineNumber))
+        }
+        if er == os.EOF {
+            break
+        } else if er != nil {
+            return nil, NewIOError(er)
+        }
+    }
+    return w, nil
+}
+
+func NewPatternFileError(msg string) Error {
+    return NewError(msg, ErrPatternFileError)
+}
+
+// ################################################################################
+// ########################### patternPolicy struct ###############################
+// ################################################################################
+
+// The playout policy of PatternWeights. It keeps the strength of every move without the distance features
+// (see PatternWeights.localStrength) for the position it has seen last. When it is asked for a move on a
+// position, only the moves around the stones which have changed since then are evaluated again: the points
+// whose diamond pattern contains a changed stone, and the liberties of the groups at and next to the changed
+// stones, whose capture, atari and legality may have changed. The distance features are multiplied in when
+// a move is drawn. The weights must not change while the policy is used.
+type patternPolicy struct {
+    weights *PatternWeights
+    width, height int
+    suicide bool
+    stones []int8 // the position the strengths belong to, see policyStone. nil if there is none yet
+    koPos int // the ko of this position, -1 if there is none
+    koColor Color
+    strength [2][]float // indexed by policyIndex, 0 if the move is illegal (ignoring superko) or unwanted
+    unwanted [2][]bool // the move is legal, but Board.isUnwantedPlayoutMove excludes it
+    changed []int // the stones which have changed since the last move, see sync
+    marked []bool // the points which are evaluated again, see sync
+    markedPosses []int
+    moveWeights []float // the strengths with the distance features of the move which is drawn
+}
+
+// ##################### patternPolicy methods ##########################
+
+// Plays a move of 'color' on 'b' like PatternWeights.PlayMove.
+func (p *patternPolicy) PlayMove(b *Board, color Color) Vertex {
+    p.sync(b)
+    var gammaPrev, gammaSecondPrev [maxFeatureDistance+1]float
+    for d := 0; d <= maxFeatureDistance; d++ {
+        gammaPrev[d] = p.weights.Gamma(featureKey(FeatureDistPrev, uint32(d)))
+        gammaSecondPrev[d] = p.weights.Gamma(featureKey(FeatureDistSecondPrev, uint32(d)))
+    }
+    strength := p.strength[policyIndex(color)]
+    total := float(0.0)
+    for pos, s := range strength {
+        if s > 0 {
+            if b.lastMove >= 0 {
+                s *= gammaPrev[b.moveDistance(pos, b.lastMove)]
+            }
+            if b.secondLastMove >= 0 {
+                s *= gammaSecondPrev[b.moveDistance(pos, b.secondLastMove)]
+            }
+            total += s
+        }
+        p.moveWeights[pos] = s
+    }
+    for total > 0 {
+        r := float(b.rand.Float64()) * total
+        chosen := -1
+        for pos, weight := range p.moveWeights {
+            if weight <= 0 {
+                continue
+            }
+            chosen = pos
+            r -= weight
+            if r < 0 {
+                break
+            }
+        }
+        if chosen < 0 {
+            break
+        }
+        // A far away change may have made the move unwanted, and superko is not part of the strengths.
+        if b.isUnwantedPlayoutMove(chosen, color) || b.violatesSuperko(chosen, color) {
+            total -= p.moveWeights[chosen]
+            p.moveWeights[chosen] = 0
+            continue
+        }
+        if b.playMoveByPos(chosen, color) != nil {
+            return b.PlayRandomMove(color)
+        }
+        x, y := b.posToXY(chosen)
+        return *NewVertexByInts(x,y,false)
+    }
+    b.PlayPass(color)
+    return *NewVertexByInts(0,0,true)
+}
+
+// Brings the strengths up to date with the position on 'b'. 'b' need not be the board of the last call: the
+// strengths only depend on the position, so the stones which differ from the last position are updated.
+func (p *patternPolicy) sync(b *Board) {
+    if p.stones == nil || p.width != b.width || p.height != b.height || p.suicide != b.suicide {
+        p.reset(b)
+        return
+    }
+    p.changed = p.changed[0:0]
+    for pos, grp := range b.fields {
+        if stone := policyStone(grp); stone != p.stones[pos] {
+            p.stones[pos] = stone
+            p.changed = p.changed[0:len(p.changed)+1]
+            p.changed[len(p.changed)-1] = pos
+        }
+    }
+    koPos, koColor := -1, Color(Black)
+    if b.ko != nil {
+        koPos, koColor = b.ko.Pos, b.ko.Color
+    }
+    if len(p.changed) > len(b.fields) / 4 {
+        p.reset(b)
+        return
+    }
+    if koPos != p.koPos || koColor != p.koColor {
+        p.mark(p.koPos)
+        p.mark(koPos)
+        p.koPos, p.koColor = koPos, koColor
+    }
+    for _, pos := range p.changed {
+        x, y := b.posToXY(pos)
+        p.mark(pos)
+        for _, off := range diamondOffsets {
+            if px, py := x + off.X, y + off.Y; px >= 0 && py >= 0 && px < b.width && py < b.height {
+                p.mark(b.xyToPos(px, py))
+            }
+        }
+        p.markLiberties(b.fields[pos])
+        for _, npos := range b.neighboursByPos(pos) {
+            p.markLiberties(b.fields[npos])
+        }
+        // Whether a move fills an eye depends on the nakade shapes up to maxNakadeSize points away.
+        reach := maxNakadeSize + 1
+        for dy := -reach; dy <= reach; dy++ {
+            rowReach := reach - dy
+            if dy < 0 {
+                rowReach = reach + dy
+            }
+            for dx := -rowReach; dx <= rowReach; dx++ {
+                px, py := x + dx, y + dy
+                if px < 0 || py < 0 || px >= b.width || py >= b.height {
+                    continue
+                }
+                if qpos := b.xyToPos(px, py); p.unwanted[0][qpos] || p.unwanted[1][qpos] {
+                    p.mark(qpos)
+                }
+            }
+        }
+    }
+    for _, pos := range p.markedPosses {
+        p.update(b, pos)
+        p.marked[pos] = false
+    }
+    p.markedPosses = p.markedPosses[0:0]
+}
+
+// Evaluates every move on 'b' again.
+func (p *patternPolicy) reset(b *Board) {
+    n := len(b.fields)
+    p.width, p.height, p.suicide = b.width, b.height, b.suicide
+    if len(p.stones) != n {
+        p.stones = make([]int8, n)
+        for i := 0; i < 2; i++ {
+            p.strength[i] = make([]float, n)
+            p.unwanted[i] = make([]bool, n)
+        }
+        p.changed = make([]int, 0, n)
+        p.marked = make([]bool, n)
+        p.markedPosses = make([]int, 0, n)
+        p.moveWeights = make([]float, n)
+    }
+    p.koPos, p.koColor = -1, Black
+    if b.ko != nil {
+        p.koPos, p.koColor = b.ko.Pos, b.ko.Color
+    }
+    for pos, grp := range b.fields {
+        p.stones[pos] = policyStone(grp)
+        p.update(b, pos)
+    }
+}
+
+// Evaluates the moves of both colors at 'pos' on 'b' again.
+func (p *patternPolicy) update(b *Board, pos int) {
+    for _, color := range []Color{ Black, White } {
+        i := policyIndex(color)
+        p.strength[i][pos], p.unwanted[i][pos] = 0, false
+        if b.fields[pos] != nil || !b.isLegalIgnoringSuperko(pos, color) {
+            continue
+        }
+        if b.isUnwantedPlayoutMove(pos, color) {
+            p.unwanted[i][pos] = true
+        } else {
+            p.strength[i][pos] = p.weights.localStrength(b, pos, color)
+        }
+    }
+}
+
+// Marks 'pos' to be evaluated again. Negative positions are ignored.
+func (p *patternPolicy) mark(pos int) {
+    if pos >= 0 && !p.marked[pos] {
+        p.marked[pos] = true
+        p.markedPosses = p.markedPosses[0:len(p.markedPosses)+1]
+        p.markedPosses[len(p.markedPosses)-1] = pos
+    }
+}
+
+// Marks the liberties of 'grp' to be evaluated again. 'grp' may be nil.
+func (p *patternPolicy) markLiberties(grp *Group) {
+    if grp == nil {
+        return
+    }
+    for lib := grp.liberties.next(0); lib >= 0; lib = grp.liberties.next(lib+1) {
+        p.mark(lib)
+    }
+}
+
+// ##################### patternPolicy helper functions ##########################
+
+// Creates a policy for 'w' which has not seen a position yet
+func newPatternPolicy(w *PatternWeights) *patternPolicy {
+    return &patternPolicy{ weights: w, koPos: -1 }
+}
+
+// The index of 'color' in patternPolicy.strength and patternPolicy.unwanted
+func policyIndex(color Color) int {
+    if color == Black {
+        return 0
+    }
+    return 1
+}
+
+// The state of a point in patternPolicy.stones: 0 if it is empty, 1 for a black and 2 for a white stone
+func policyStone(grp *Group) int8 {
+    switch {
+        case grp == nil:
+            return 0
+        case grp.Color == Black:
+            return 1
+    }
+    return 2
+}
@@ -0,0 +1,173 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * Rulesets. The common rules of Go only differ in a few points: the legality of suicide, the ko
+ * rule, the scoring method, the compensation for handicap stones and whether passes hand stones
+ * to the opponent. A Ruleset collects these points, and the Board, the scoring and the AI consult
+ * the Ruleset of the Environment instead of assuming one of them.
+ */
+
+package komoku
+
+import (
+    "fmt"
+)
+
+// ################################################################################
+// ########################### Ruleset ############################################
+// ################################################################################
+
+type ScoringMethod int
+
+const (
+    AreaScoring ScoringMethod = iota // the stones and the surrounded empty points count
+    TerritoryScoring // the surrounded empty points and the prisoners count
+)
+
+type HandicapCompensation int
+
+const (
+    NoHandicapCompensation HandicapCompensation = iota
+    CompensateHandicapStones // white gets one point for each handicap stone
+    CompensateHandicapStonesButOne // white gets one point for each handicap stone but the first one
+)
+
+type Ruleset struct {
+    Name string // the name used by komoku-rules
+    Suicide bool // true if the suicide of more than one stone is legal
+    Superko SuperkoRule
+    Scoring ScoringMethod
+    Handicap HandicapCompensation
+    PassStones bool // true if a player hands one stone to the opponent as a prisoner for each pass
+    SekiTerritory bool // true if the eyes of groups in seki count as territory under territory scoring
+}
+
+var (
+    // The rules komoku has always played by: chinese rules with the simple ko rule instead of superko. These
+    // are the default, superko has to be chosen with one of the other rulesets.
+    DefaultRules = &Ruleset{ Name: "default", Superko: NoSuperko, Scoring: AreaScoring,
+                             Handicap: CompensateHandicapStones }
+    ChineseRules = &Ruleset{ Name: "chinese", Superko: PositionalSuperko, Scoring: AreaScoring,
+                             Handicap: CompensateHandicapStones }
+    JapaneseRules = &Ruleset{ Name: "japanese", Superko: NoSuperko, Scoring: TerritoryScoring,
+                              Handicap: NoHandicapCompensation }
+    // With pass stones, counting the territory gives the same result as counting the area. White gets no
+    // points for the handicap stones, since counting the territory does not count them anyway, the n-1 points
+    // of the AGA rules are only given when counting the area.
+    AGARules = &Ruleset{ Name: "aga", Superko: SituationalSuperko, Scoring: TerritoryScoring,
+                         Handicap: NoHandicapCompensation, PassStones: true, SekiTerritory: true }
+    NewZealandRules = &Ruleset{ Name: "new_zealand", Suicide: true, Superko: SituationalSuperko, Scoring: AreaScoring,
+                                Handicap: NoHandicapCompensation }
+    TrompTaylorRules = &Ruleset{ Name: "tromp-taylor", Suicide: true, Superko: PositionalSuperko, Scoring: AreaScoring,
+                                 Handicap: NoHandicapCompensation }
+)
+
+// All known rulesets, see RulesetByName.
+var rulesets = []*Ruleset{ DefaultRules, ChineseRules, JapaneseRules, AGARules, NewZealandRules, TrompTaylorRules }
+
+// ##################### Ruleset methods ##########################
+
+// Returns the points white gets for 'handicap' handicap stones.
+func (r *Ruleset) HandicapCompensation(handicap int) float {
+    switch r.Handicap {
+        case CompensateHandicapStones:
+            return float(handicap)
+        case CompensateHandicapStonesButOne:
+            if handicap > 1 {
+                return float(handicap - 1)
+            }
+    }
+    return 0
+}
+
+// Returns the score of black minus the score of white on 'b', where white gets 'komi' and the compensation for
+// 'handicap' handicap stones. The stones in 'dead' are removed before counting, all other stones are considered
+// alive. Area scoring counts like Board.AreaScore, territory scoring like Board.TerritoryScore.
+func (r *Ruleset) ScoreDifference(b *Board, komi float, handicap int, dead []int) float {
+    var black, white int
+    if r.Scoring == AreaScoring {
+        stonesBlack, stonesWhite, regionsBlack, regionsWhite := b.countArea(b.deadStoneSet(dead), nil)
+        black, white = stonesBlack + regionsBlack, stonesWhite + regionsWhite
+    } else {
+        black, white = b.TerritoryScore(dead, r.SekiTerritory)
+    }
+    return float(black - white) - komi - r.HandicapCompensation(handicap)
+}
+
+// Like ScoreDifference without dead stones, but works on any GoBoard. Territory scoring counts the area of
+// each color without its stones, plus the prisoners of the opponent.
+func (r *Ruleset) PlayoutScoreDifference(b GoBoard, komi float, handicap int) float {
+    black, white := b.AreaScore()
+    if r.Scoring == TerritoryScoring {
+        stonesBlack, stonesWhite := b.numberOfStones()
+        prisonersBlack, prisonersWhite := b.numberOfPrisoners()
+        black += prisonersWhite - stonesBlack
+        white += prisonersBlack - stonesWhite
+    }
+    return float(black - white) - komi - r.HandicapCompensation(handicap)
+}
+
+func (r *Ruleset) String() string {
+    scoring := "area"
+    if r.Scoring == TerritoryScoring {
+        scoring = "territory"
+    }
+    handicap := "none"
+    switch r.Handicap {
+        case CompensateHandicapStones:
+            handicap = "n"
+        case CompensateHandicapStonesButOne:
+            handicap = "n-1"
+    }
+    return fmt.Sprintf("%s: suicide %v, superko %s, scoring %s, handicap compensation %s, pass stones %v, seki territory %v",
+                       r.Name, r.Suicide, r.Superko, scoring, handicap, r.PassStones, r.SekiTerritory)
+}
+
+// ##################### Ruleset helper functions ##########################
+
+// Returns the ruleset called 'name', which is one of default, chinese, japanese, aga, new_zealand and tromp-taylor.
+func RulesetByName(name string) (r *Ruleset, ok bool) {
+    for _, r = range rulesets {
+        if r.Name == name {
+            return r, true
+        }
+    }
+    return nil, false
+}
+
+// ##################### rules methods of Board ##########################
+
+// Applies the parts of 'r' which concern the moves on 'b': the legality of suicide, the superko rule and
+// the pass stones. The superko window, see SetSuperko, is kept, and so is the journal: Undo computes the
+// legalities again when it takes back a move which has been played under the other suicide rule.
+func (b *Board) SetRules(r *Ruleset) {
+    b.SetSuperko(r.Superko, b.superkoWindow)
+    b.passStones = r.PassStones
+    if b.suicide != r.Suicide {
+        b.suicide = r.Suicide
+        // the cached legalities of all fields are outdated
+        b.currentSequence++
+    }
+}
+
+// Is a stone of 'color' at 'pos' a suicide, i.e. does it neither capture nor leave its group with a
+// liberty? This method assumes that 'pos' is empty.
+func (b *Board) isSuicide(pos int, color Color) bool {
+    for _, npos := range b.neighboursByPos(pos) {
+        grp := b.fields[npos]
+        if grp == nil {
+            return false
+        }
+        if (grp.Color == color) != (grp.NumLiberties() == 1) {
+            // a friendly group with another liberty or an enemy group which is captured
+            return false
+        }
+    }
+    return true
+}
@@ -0,0 +1,95 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * Recognition of the common seki shapes: shared liberties on which neither side can play without
+ * putting itself in atari. Random playouts must not break such a seki, because the side which
+ * approaches first loses its group, which skews the results of the simulations. A self-atari whose
+ * stones leave a dead shape when they are captured is a throw-in which kills, not a seki.
+ */
+
+package komoku
+
+// ##################### seki methods of Board ##########################
+
+// Returns true if 'pos' is an empty liberty shared by groups of both colors and neither color can play at
+// 'pos' without putting its own stones in atari (or committing suicide), a move there would not capture
+// anything, and the stones put in atari would not leave a dead shape for the opponent when they are captured.
+func (b *Board) isSekiPoint(pos int) bool {
+    if b.fields[pos] != nil {
+        return false
+    }
+    nFree, adjBlack, adjWhite := b.GetEnvironment(pos)
+    if len(adjBlack) == 0 || len(adjWhite) == 0 {
+        return false
+    }
+    return b.isSelfAtariOrSuicide(pos, Black, nFree, adjBlack, adjWhite) && !b.isKillingThrowIn(pos, adjBlack) &&
+           b.isSelfAtariOrSuicide(pos, White, nFree, adjWhite, adjBlack) && !b.isKillingThrowIn(pos, adjWhite)
+}
+
+// Returns true if a move of 'color' at 'pos' does not capture and leaves the group of the played stone
+// with at most one liberty. 'nFree', 'adjSameColor' and 'adjOtherColor' describe the environment of 'pos'.
+func (b *Board) isSelfAtariOrSuicide(pos int, color Color, nFree int, adjSameColor, adjOtherColor GroupSlice) bool {
+    for _, grp := range adjOtherColor {
+        if grp.NumLiberties() == 1 {
+            // the move captures
+            return false
+        }
+    }
+    if nFree > 1 {
+        return false
+    }
+    // Count the liberties of the group the stone would belong to, but stop as soon as there are two.
+    liberty := -1
+    for _, npos := range b.neighboursByPos(pos) {
+        if b.fields[npos] == nil {
+            liberty = npos
+        }
+    }
+    for _, grp := range adjSameColor {
+        for lib := grp.liberties.next(0); lib >= 0; lib = grp.liberties.next(lib+1) {
+            if lib == pos || lib == liberty {
+                continue
+            }
+            if liberty >= 0 {
+                return false
+            }
+            liberty = lib
+        }
+    }
+    return true
+}
+
+// Returns true if the stones of a self-atari at 'pos', which joins 'adjSameColor', form a dead shape once the
+// opponent has captured them: one or two points, or a nakade shape. The opponent then only has one eye there.
+func (b *Board) isKillingThrowIn(pos int, adjSameColor GroupSlice) bool {
+    stones := make([]int, 1, maxNakadeSize+1)
+    stones[0] = pos
+    for _, grp := range adjSameColor {
+        if len(stones) + grp.NumStones() > maxNakadeSize {
+            return false
+        }
+        p := grp.first
+        for n := 0; n < grp.numStones; n++ {
+            stones = stones[0:len(stones)+1]
+            stones[len(stones)-1] = p
+            p = b.nextStone[p]
+        }
+    }
+    if len(stones) <= 2 {
+        return true
+    }
+    _, isNakade := b.nakadeVitalPoint(stones)
+    return isNakade
+}
+
+// Returns true if a playout should not play a stone of 'color' at 'pos', because it only fills an own eye,
+// because it would break a seki or because it is a suicide.
+func (b *Board) isUnwantedPlayoutMove(pos int, color Color) bool {
+    return b.isEyeFillingMove(pos, color) || b.isSekiPoint(pos) || (b.suicide && b.isSuicide(pos, color))
+}
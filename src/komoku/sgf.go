@@ -0,0 +1,291 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * A small reader for SGF game records. Only the main line of a game is read, and only
+ * the properties komoku needs (SZ, KM, AB, AW, B and W) are interpreted.
+ */
+
+package komoku
+
+import (
+    "container/vector"
+    "fmt"
+    "io/ioutil"
+    "strconv"
+)
+
+// ################################################################################
+// ########################### SGFGame struct #####################################
+// ################################################################################
+
+// The main line of a game record.
+type SGFGame struct {
+    BoardSize int
+    Komi float
+    Setup []Move // stones placed by AB and AW, e.g. handicap stones
+    Moves []Move // the moves of the main line, in order
+}
+
+// A property with points, which can only be converted once the board size is known.
+type sgfPointProperty struct {
+    ident string
+    values vector.StringVector
+}
+
+// ##################### SGFGame helper functions ##########################
+
+// Parses the SGF game record 'data' and returns its main line.
+func ParseSGF(data string) (game *SGFGame, err Error) {
+    game = &SGFGame{ BoardSize: 19, Komi: 0.0 }
+    // the points are converted after parsing, since SZ may follow them within the root node
+    var pointProperties, setup, moves vector.Vector
+    started := false
+    i := 0
+    length := len(data)
+    for i < length {
+        c := data[i]
+        switch {
+            case c == '(':
+                started = true
+                i++
+            case c == ')':
+                // The main line always comes first, so we are done when its variation ends
+                i = length
+            case c == ';':
+                if !started {
+                    return nil, NewSGFSyntaxError("node before the start of the game tree")
+                }
+                i++
+            case c >= 'A' && c <= 'Z':
+                // a property identifier followed by one or more values
+                start := i
+                for i < length && ((data[i] >= 'A' && data[i] <= 'Z') || (data[i] >= 'a' && data[i] <= 'z')) {
+                    i++
+                }
+                ident := ""
+                for k := start; k < i; k++ {
+                    // lower case letters are allowed in old SGF versions, but have no meaning
+                    if data[k] >= 'A' && data[k] <= 'Z' {
+                        ident += data[k:k+1]
+                    }
+                }
+                var values vector.StringVector
+                for {
+                    for i < length && isSGFWhitespace(data[i]) {
+                        i++
+                    }
+                    if i >= length || data[i] != '[' {
+                        break
+                    }
+                    value, next, ok := readSGFValue(data, i+1)
+                    if !ok {
+                        return nil, NewSGFSyntaxError(fmt.Sprintf("unterminated value of property %s", ident))
+                    }
+                    values.Push(value)
+                    i = next
+                }
+                if values.Len() == 0 {
+                    return nil, NewSGFSyntaxError(fmt.Sprintf("property %s without value", ident))
+                }
+                if perr := game.applyProperty(ident, values, &pointProperties); perr != nil {
+                    return nil, perr
+                }
+            case isSGFWhitespace(c):
+                i++
+            default:
+                return nil, NewSGFSyntaxError(fmt.Sprintf("unexpected character '%c'", c))
+        }
+    }
+    if !started {
+        return nil, NewSGFSyntaxError("no game tree found")
+    }
+    for _, prop := range pointProperties {
+        if perr := game.applyPointProperty(prop.(sgfPointProperty), &setup, &moves); perr != nil {
+            return nil, perr
+        }
+    }
+    game.Setup = movesOfVector(setup)
+    game.Moves = movesOfVector(moves)
+    return game, nil
+}
+
+// Reads the SGF file 'filename' and returns the main line of the game in it.
+func ReadSGFFile(filename string) (*SGFGame, Error) {
+    data, er := ioutil.ReadFile(filename)
+    if er != nil {
+        return nil, NewIOError(er)
+    }
+    return ParseSGF(string(data))
+}
+
+// Interprets the property 'ident' with the values 'values'. The properties with points, i.e. AB, AW, B and W,
+// are pushed to 'pointProperties', see applyPointProperty.
+func (g *SGFGame) applyProperty(ident string, values vector.StringVector, pointProperties *vector.Vector) Error {
+    switch ident {
+        case "SZ":
+            size, er := strconv.Atoi(values.At(0))
+            if er != nil || size < 2 || size > MaxBoardSize {
+                return NewSGFSyntaxError(fmt.Sprintf("invalid board size '%s'", values.At(0)))
+            }
+            g.BoardSize = size
+        case "KM":
+            komi, er := strconv.Atof(values.At(0))
+            if er != nil {
+                return NewSGFSyntaxError(fmt.Sprintf("invalid komi '%s'", values.At(0)))
+            }
+            g.Komi = komi
+        case "AB", "AW", "B", "W":
+            pointProperties.Push(sgfPointProperty{ ident, values })
+    }
+    return nil
+}
+
+// Interprets the property 'prop' with points on a board of g.BoardSize. Setup stones are pushed to 'setup',
+// moves to 'moves'. Points outside the board are an error.
+func (g *SGFGame) applyPointProperty(prop sgfPointProperty, setup, moves *vector.Vector) Error {
+    ident, values := prop.ident, prop.values
+    switch ident {
+        case "AB", "AW":
+            color := Color(Black)
+            if ident == "AW" {
+                color = White
+            }
+            for _, value := range values {
+                points, ok := sgfPointList(value, g.BoardSize)
+                if !ok {
+                    return NewSGFSyntaxError(fmt.Sprintf("invalid point '%s' in %s", value, ident))
+                }
+                for _, p := range points {
+                    setup.Push(*NewMove(p, color, false))
+                }
+            }
+        case "B", "W":
+            color := Color(Black)
+            if ident == "W" {
+                color = White
+            }
+            p, pass, ok := sgfCoordinateToPoint(values.At(0), g.BoardSize)
+            if !ok {
+                return NewSGFSyntaxError(fmt.Sprintf("invalid move '%s'", values.At(0)))
+            }
+            moves.Push(*NewMove(p, color, pass))
+    }
+    return nil
+}
+
+// Converts an SGF coordinate such as "dd" into a Point. SGF counts rows from the top, komoku from
+// the bottom. The empty string and "tt" (on boards up to 19x19) denote a pass.
+func sgfCoordinateToPoint(c string, boardsize int) (p Point, pass, ok bool) {
+    if c == "" || (c == "tt" && boardsize <= 19) {
+        return Point{0,0}, true, true
+    }
+    if len(c) != 2 {
+        return Point{0,0}, false, false
+    }
+    x, okX := sgfCoordinateDigit(c[0])
+    row, okRow := sgfCoordinateDigit(c[1])
+    if !okX || !okRow || x >= boardsize || row >= boardsize {
+        return Point{0,0}, false, false
+    }
+    return Point{ X: x, Y: boardsize - 1 - row }, false, true
+}
+
+// Converts a Point into an SGF coordinate. This is the inverse of sgfCoordinateToPoint.
+func pointToSGFCoordinate(p Point, boardsize int) string {
+    row := boardsize - 1 - p.Y
+    return string([]byte{ sgfCoordinateChars[p.X], sgfCoordinateChars[row] })
+}
+
+// Returns the column or row which is denoted by the SGF letter 'c'.
+func sgfCoordinateDigit(c byte) (digit int, ok bool) {
+    switch {
+        case c >= 'a' && c <= 'z':
+            return int(c - 'a'), true
+        case c >= 'A' && c <= 'Z':
+            return int(c - 'A') + 26, true
+    }
+    return -1, false
+}
+
+// Interprets an SGF point or a compressed point list such as "aa:cc", which denotes the rectangle
+// with the corners "aa" and "cc".
+func sgfPointList(value string, boardsize int) (points []Point, ok bool) {
+    if len(value) == 5 && value[2] == ':' {
+        p1, pass1, ok1 := sgfCoordinateToPoint(value[0:2], boardsize)
+        p2, pass2, ok2 := sgfCoordinateToPoint(value[3:5], boardsize)
+        if !ok1 || !ok2 || pass1 || pass2 {
+            return nil, false
+        }
+        minX, maxX := p1.X, p2.X
+        if minX > maxX {
+            minX, maxX = maxX, minX
+        }
+        minY, maxY := p1.Y, p2.Y
+        if minY > maxY {
+            minY, maxY = maxY, minY
+        }
+        points = make([]Point, (maxX-minX+1)*(maxY-minY+1))
+        index := 0
+        for x := minX; x <= maxX; x++ {
+            for y := minY; y <= maxY; y++ {
+                points[index] = Point{ X: x, Y: y }
+                index++
+            }
+        }
+        return points, true
+    }
+    p, pass, ok := sgfCoordinateToPoint(value, boardsize)
+    if !ok || pass {
+        return nil, false
+    }
+    return []Point{ p }, true
+}
+
+// Reads a property value starting at data[start], i.e. right after the opening bracket. Returns the
+// unescaped value and the index after the closing bracket.
+func readSGFValue(data string, start int) (value string, next int, ok bool) {
+    var buf []byte = make([]byte, 0, 16)
+    for i := start; i < len(data); i++ {
+        c := data[i]
+        if c == ']' {
+            return string(buf), i+1, true
+        }
+        if c == '\\' {
+            i++
+            if i >= len(data) {
+                break
+            }
+            c = data[i]
+        }
+        if len(buf) == cap(buf) {
+            newBuf := make([]byte, len(buf), 2*cap(buf))
+            copy(newBuf, buf)
+            buf = newBuf
+        }
+        buf = buf[0:len(buf)+1]
+        buf[len(buf)-1] = c
+    }
+    return "", len(data), false
+}
+
+func isSGFWhitespace(c byte) bool {
+    return c == ' ' || c == '\n' || c == '\r' || c == '\t'
+}
+
+func movesOfVector(v vector.Vector) []Move {
+    ret := make([]Move, v.Len())
+    for i := 0; i < v.Len(); i++ {
+        ret[i], _ = v.At(i).(Move)
+    }
+    return ret
+}
+
+func NewSGFSyntaxError(msg string) Error {
+    return NewError(msg, ErrSGFSyntaxError)
+}
@@ -0,0 +1,161 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * Instrumentation of the search: how many playouts are run, how they end, how the tree grows and
+ * where the time goes. The counters are updated by the thinkers without locking, so with more than
+ * one thinker they are approximate.
+ */
+
+package komoku
+
+import (
+    "fmt"
+)
+
+// ################################################################################
+// ########################### SearchStatistics struct ############################
+// ################################################################################
+
+// Counts what the search of an AI has done since the AI was created.
+type SearchStatistics struct {
+    playouts int // total number of playouts, including the discarded ones
+    mercyTerminations int // number of playouts stopped by the mercy rule
+    cappedScored int // number of playouts which hit the move cap and were scored
+    cappedDiscarded int // number of playouts which hit the move cap and were discarded
+    expansions int // number of tree nodes created by simulations
+    depthSum int // sum of the depths of all simulations, see simulationTrace
+    maxDepth int
+    selectionNs int64 // time spent selecting moves in the tree, until the first new node is created
+    playoutNs int64 // time spent playing out from there and scoring
+    backpropNs int64 // time spent updating the tree with the results
+    thinkingNs int64 // wall time during which the thinkers were running
+    thinkerNs int64 // thinkingNs times the number of thinkers
+    thinkerBusyNs int64 // time the thinkers spent in simulations
+    thinkingSince int64 // when the thinkers were started, 0 if they are not running
+    treeSize int // number of nodes below the top node, only set in snapshots
+    moveNumber int // number of moves played in the game, only set in snapshots
+}
+
+// ##################### SearchStatistics methods ##########################
+
+// Returns the average depth of a simulation in the tree
+func (s *SearchStatistics) AverageDepth() float {
+    if s.playouts == 0 {
+        return 0.0
+    }
+    return float(s.depthSum) / float(s.playouts)
+}
+
+// Returns the JSON representation of the statistics as a single line
+func (s *SearchStatistics) JSON() string {
+    return fmt.Sprintf("{\"move\": %d, \"playouts\": %d, \"playouts_per_second\": %.1f, \"tree_size\": %d, " +
+                       "\"max_depth\": %d, \"average_depth\": %.2f, \"expansions\": %d, \"selection_seconds\": %.3f, " +
+                       "\"playout_seconds\": %.3f, \"backprop_seconds\": %.3f, \"thinking_seconds\": %.3f, " +
+                       "\"thinker_utilization\": %.3f, \"mercy_terminations\": %d, " +
+                       "\"capped_scored\": %d, \"capped_discarded\": %d}",
+                       s.moveNumber, s.playouts, s.PlayoutsPerSecond(), s.treeSize,
+                       s.maxDepth, s.AverageDepth(), s.expansions, nsToSeconds(s.selectionNs),
+                       nsToSeconds(s.playoutNs), nsToSeconds(s.backpropNs), nsToSeconds(s.thinkingNs), s.ThinkerUtilization(),
+                       s.mercyTerminations, s.cappedScored, s.cappedDiscarded)
+}
+
+// Returns the number of playouts per second of simulation time
+func (s *SearchStatistics) PlayoutsPerSecond() float {
+    simulationNs := s.selectionNs + s.playoutNs + s.backpropNs
+    if simulationNs == 0 {
+        return 0.0
+    }
+    return float(s.playouts) / nsToSeconds(simulationNs)
+}
+
+// Returns a summary of how the playouts ended
+func (s *SearchStatistics) playoutSummary() string {
+    return fmt.Sprintf("playouts: %d, mercy: %d, capped (scored): %d, capped (discarded): %d",
+                       s.playouts, s.mercyTerminations, s.cappedScored, s.cappedDiscarded)
+}
+
+// Records a simulation which took 'selectionNs' nanoseconds in the tree, 'playoutNs' for the playout and
+// 'backpropNs' for updating the tree.
+func (s *SearchStatistics) recordSimulation(trace *simulationTrace, selectionNs, playoutNs, backpropNs int64) {
+    s.expansions += trace.expansions
+    s.depthSum += trace.depth
+    if trace.depth > s.maxDepth {
+        s.maxDepth = trace.depth
+    }
+    s.selectionNs += selectionNs
+    s.playoutNs += playoutNs
+    s.backpropNs += backpropNs
+}
+
+// Records that the thinkers were started at 'now'
+func (s *SearchStatistics) startThinking(now int64) {
+    s.thinkingSince = now
+}
+
+// Records that the thinkers which were started by startThinking were stopped at 'now'
+func (s *SearchStatistics) stopThinking(now int64, numThinkers int) {
+    if s.thinkingSince == 0 {
+        return
+    }
+    s.thinkingNs += now - s.thinkingSince
+    s.thinkerNs += (now - s.thinkingSince) * int64(numThinkers)
+    s.thinkingSince = 0
+}
+
+func (s *SearchStatistics) String() string {
+    return fmt.Sprintf("%s\nplayouts per second: %.1f\ntree size: %d, expansions: %d, depth: %d max, %.2f average\n" +
+                       "time: %.3fs selection, %.3fs playouts, %.3fs backprop, %.3fs thinking, thinker utilization: %.1f%%",
+                       s.playoutSummary(), s.PlayoutsPerSecond(), s.treeSize, s.expansions, s.maxDepth, s.AverageDepth(),
+                       nsToSeconds(s.selectionNs), nsToSeconds(s.playoutNs), nsToSeconds(s.backpropNs),
+                       nsToSeconds(s.thinkingNs), 100.0*s.ThinkerUtilization())
+}
+
+// Returns the fraction of the time in which the thinkers were running that they spent in simulations
+func (s *SearchStatistics) ThinkerUtilization() float {
+    if s.thinkerNs == 0 {
+        return 0.0
+    }
+    return float(s.thinkerBusyNs) / float(s.thinkerNs)
+}
+
+// ################################################################################
+// ########################### simulationTrace struct #############################
+// ################################################################################
+
+// Follows one simulation through the tree. The depth of a simulation is the number of existing
+// nodes it passes before it creates the first new one.
+type simulationTrace struct {
+    depth int
+    expansions int
+    inTree bool
+}
+
+// Returns the child of 'node' at 'pos', which is created if necessary.
+func (t *simulationTrace) step(node *TreeNode, pos int) *TreeNode {
+    child, ok := node.children[pos]
+    if !ok {
+        t.expansions++
+        t.inTree = false
+        return node.ChildNode(pos)
+    }
+    if t.inTree {
+        t.depth++
+    }
+    return child
+}
+
+// ##################### statistics helper functions ##########################
+
+func newSimulationTrace() *simulationTrace {
+    return &simulationTrace{ inTree: true }
+}
+
+func nsToSeconds(ns int64) float {
+    return float(ns) / 1e9
+}
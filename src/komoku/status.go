@@ -0,0 +1,173 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * The status of the stones at the end of the game. Controllers ask for it with final_status_list
+ * and final_score when both players have passed. Komoku estimates it by running playouts from the
+ * final position: a group whose stones belong to the opponent at the end of most playouts is dead,
+ * and a living group whose liberties mostly stay neutral is in seki.
+ */
+
+package komoku
+
+// ################################################################################
+// ########################### StoneStatus ########################################
+// ################################################################################
+
+type StoneStatus int
+
+const (
+    StatusAlive StoneStatus = iota
+    StatusDead
+    StatusSeki
+)
+
+// All stone statuses, see ParseStoneStatus.
+var stoneStatuses = []StoneStatus{ StatusAlive, StatusDead, StatusSeki }
+
+func (s StoneStatus) String() string {
+    switch s {
+        case StatusDead:
+            return "dead"
+        case StatusSeki:
+            return "seki"
+    }
+    return "alive"
+}
+
+// ##################### StoneStatus helper functions ##########################
+
+// Returns the status called 's', which is one of alive, dead and seki.
+func ParseStoneStatus(s string) (status StoneStatus, ok bool) {
+    for _, status = range stoneStatuses {
+        if status.String() == s {
+            return status, true
+        }
+    }
+    return StatusAlive, false
+}
+
+// ##################### status methods of AI ##########################
+
+// Returns the status of the stones of the current position, indexed by the position. The entries of the
+// empty fields are StatusAlive. The estimate is kept until the position changes, so that all questions about
+// one position are answered consistently.
+func (a *AI) FinalStatus() []StoneStatus {
+    defer a.startThinking(a.stopThinking())
+    board := a.environment.Game.Board
+    moves := len(a.environment.Game.sequence)
+    if a.status == nil || a.statusBoard != board || a.statusHash != board.Hash() || a.statusMoves != moves {
+        a.status = a.estimateStatus(paramStatusPlayouts.Int())
+        a.statusBoard, a.statusHash, a.statusMoves = board, board.Hash(), moves
+    }
+    return a.status
+}
+
+// Marks the stones which FinalStatus considers dead as dead stones of the game and returns the score of
+// black minus the score of white, see Environment.FinalScoreDifference.
+func (a *AI) FinalScore() float {
+    status := a.FinalStatus()
+    board := a.environment.Game.Board
+    dead := make([]int, 0, len(status))
+    for pos, s := range status {
+        if s == StatusDead && board.fields[pos] != nil {
+            dead = dead[0:len(dead)+1]
+            dead[len(dead)-1] = pos
+        }
+    }
+    a.environment.Game.SetDeadStones(dead)
+    return a.environment.FinalScoreDifference()
+}
+
+// Runs 'playouts' playouts from the current position and decides the status of each group by the owners
+// of its stones and its liberties at the end of the playouts. The thinkers must not be running.
+func (a *AI) estimateStatus(playouts int) []StoneStatus {
+    board := a.environment.Game.Board
+    size := len(board.fields)
+    // black owned points count +1, white owned points -1
+    ownership := make([]int, size)
+    neutral := make([]int, size)
+    for i := 0; i < playouts; i++ {
+        final := a.playOutToTheEnd(board)
+        for pos := 0; pos < size; pos++ {
+            switch owner, owned := final.pointOwner(pos); {
+                case !owned:
+                    neutral[pos]++
+                case owner == Black:
+                    ownership[pos]++
+                default:
+                    ownership[pos]--
+            }
+        }
+    }
+    threshold := paramStatusDeadThreshold.Float()
+    status := make([]StoneStatus, size)
+    for pos := 0; pos < size; pos++ {
+        grp := board.fields[pos]
+        // each group is decided at its first stone
+        if grp == nil || grp.FirstStone() != pos {
+            continue
+        }
+        own := 0
+        grp.DoStones(func(p int) {
+            own += ownership[p]
+        })
+        if grp.Color == White {
+            own = -own
+        }
+        groupStatus := StatusAlive
+        if float(own) < threshold*float(playouts*grp.NumStones()) {
+            groupStatus = StatusDead
+        } else {
+            // the shared liberties of a seki stay empty and touch both colors
+            grp.DoLiberties(func(lib int) {
+                if 2*neutral[lib] > playouts {
+                    groupStatus = StatusSeki
+                }
+            })
+        }
+        grp.DoStones(func(p int) {
+            status[p] = groupStatus
+        })
+    }
+    return status
+}
+
+// Plays a playout on a copy of 'board' until both players pass in a row, and returns the copy. The playout
+// is stopped after three moves per field, which only happens in long ko fights.
+func (a *AI) playOutToTheEnd(board *Board) *Board {
+    final := board.Copy()
+    final.SetSuperko(final.Superko(), paramSuperkoPlayoutWindow.Int())
+    lastPass := false
+    for i := 0; i < 3*len(final.fields); i++ {
+        v := a.playPlayoutMove(final, final.ColorOfNextPlay())
+        if v.Pass && lastPass {
+            break
+        }
+        lastPass = v.Pass
+    }
+    return final
+}
+
+// ##################### status methods of Board ##########################
+
+// Returns the owner of 'pos' at the end of a playout: the color of the stone at 'pos', or the color of
+// all neighbouring stones if 'pos' is empty. 'owned' is false if the neighbours have both colors.
+func (b *Board) pointOwner(pos int) (owner Color, owned bool) {
+    if grp := b.fields[pos]; grp != nil {
+        return grp.Color, true
+    }
+    _, adjBlack, adjWhite := b.GetEnvironment(pos)
+    switch {
+        case len(adjBlack) > 0 && len(adjWhite) == 0:
+            return Black, true
+        case len(adjWhite) > 0 && len(adjBlack) == 0:
+            return White, true
+    }
+    return Black, false
+}
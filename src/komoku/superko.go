@@ -0,0 +1,126 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * Superko rules. The koLock of the Board only forbids the immediate recapture of a simple ko. The
+ * superko rules forbid every move which repeats an earlier position, which also covers longer cycles
+ * like triple ko or sending-two-returning-one. The Board records the hash of every position of the game
+ * for this.
+ */
+
+package komoku
+
+// ################################################################################
+// ########################### SuperkoRule ########################################
+// ################################################################################
+
+type SuperkoRule int
+
+const (
+    NoSuperko SuperkoRule = iota // only the immediate recapture of a simple ko is forbidden
+    PositionalSuperko // a move must not repeat the stones of an earlier position
+    SituationalSuperko // a move must not repeat an earlier position with the same player to move
+)
+
+func (r SuperkoRule) String() string {
+    switch r {
+        case PositionalSuperko:
+            return "positional"
+        case SituationalSuperko:
+            return "situational"
+    }
+    return "none"
+}
+
+// Parses the names returned by SuperkoRule.String.
+func ParseSuperkoRule(s string) (rule SuperkoRule, ok bool) {
+    for _, rule = range []SuperkoRule{ NoSuperko, PositionalSuperko, SituationalSuperko } {
+        if rule.String() == s {
+            return rule, true
+        }
+    }
+    return NoSuperko, false
+}
+
+// ##################### superko methods of Board ##########################
+
+// Sets the superko rule of 'b'. If 'window' is positive, only the last 'window' positions are compared,
+// which is a cheaper approximation for playouts. A window of 6 still catches triple ko.
+func (b *Board) SetSuperko(rule SuperkoRule, window int) {
+    b.superko = rule
+    b.superkoWindow = window
+}
+
+// Returns the superko rule of 'b', see SetSuperko.
+func (b *Board) Superko() SuperkoRule {
+    return b.superko
+}
+
+// Returns the hash of the stones and the player to move, but without the ko point. This is the hash
+// which is recorded in the position history.
+func (b *Board) positionHash() uint64 {
+    hash := b.hash ^ b.stateHash()
+    if b.colorOfNextPlay == White {
+        hash ^= zobristWhiteToMove
+    }
+    return hash
+}
+
+// Appends the current position to the history.
+func (b *Board) recordPosition() {
+    if len(b.history) == cap(b.history) {
+        newHistory := make([]uint64, len(b.history), 2*cap(b.history)+1)
+        copy(newHistory, b.history)
+        b.history = newHistory
+    }
+    b.history = b.history[0:len(b.history)+1]
+    b.history[len(b.history)-1] = b.positionHash()
+}
+
+// Does a move of 'color' at 'pos' repeat an earlier position according to the superko rule of 'b'?
+// This method assumes that the move is legal otherwise.
+func (b *Board) violatesSuperko(pos int, color Color) bool {
+    if b.superko == NoSuperko {
+        return false
+    }
+    // the stones after the move: the new stone and the captured enemy groups, or without the own groups
+    // which commit suicide
+    stones := b.hash ^ b.stateHash() ^ zobristStone(pos, color)
+    suicide := b.isSuicide(pos, color)
+    if suicide {
+        stones ^= zobristStone(pos, color)
+    }
+    captured := NewGroupSlice()
+    for _, npos := range b.neighboursByPos(pos) {
+        if grp := b.fields[npos]; grp != nil && (grp.Color == color) == suicide && grp.NumLiberties() == 1 {
+            captured.PushUnique(grp)
+        }
+    }
+    for _, grp := range captured {
+        p := grp.first
+        for n := 0; n < grp.numStones; n++ {
+            stones ^= zobristStone(p, grp.Color)
+            p = b.nextStone[p]
+        }
+    }
+    after := stones
+    if color == Black {
+        // white is to move after the move
+        after ^= zobristWhiteToMove
+    }
+    first := 0
+    if b.superkoWindow > 0 && len(b.history) > b.superkoWindow {
+        first = len(b.history) - b.superkoWindow
+    }
+    for _, hash := range b.history[first:] {
+        if hash == after || (b.superko == PositionalSuperko && hash == after ^ zobristWhiteToMove) {
+            return true
+        }
+    }
+    return false
+}
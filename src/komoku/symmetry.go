@@ -0,0 +1,105 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * The symmetries of the board: rotations and reflections. A symmetry is encoded as a number
+ * between 0 and 7. Bit 2 transposes the board, then bit 0 mirrors the x and bit 1 the y coordinate.
+ * 0 is the identity. Rectangular boards only have the four symmetries which do not transpose.
+ */
+
+package komoku
+
+const numSymmetries = 8
+
+// ##################### symmetry methods of Board ##########################
+
+// Returns the canonical representative of the move at 'pos' among its images under 'syms', i.e. the smallest
+// one, and a symmetry of 'syms' which maps 'pos' onto it.
+func (b *Board) canonicalMove(pos int, syms []int) (canonical, sym int) {
+    canonical, sym = pos, 0
+    for _, s := range syms {
+        if image := b.transformPos(pos, s); image < canonical {
+            canonical, sym = image, s
+        }
+    }
+    return
+}
+
+// Returns the number of different canonical representatives of the moves at 'posses' under 'syms', see
+// canonicalMove.
+func (b *Board) numCanonicalMoves(posses []int, syms []int) int {
+    canonicals := make(map[int]bool)
+    for _, pos := range posses {
+        canonical, _ := b.canonicalMove(pos, syms)
+        canonicals[canonical] = true
+    }
+    return len(canonicals)
+}
+
+// Returns true if 'sym' maps the position on 'b' onto itself. Besides the stones, this includes the ko and the
+// last two moves, because the playouts depend on them.
+func (b *Board) isSymmetricUnder(sym int) bool {
+    for _, pos := range []int{ b.lastMove, b.secondLastMove } {
+        if pos >= 0 && b.transformPos(pos, sym) != pos {
+            return false
+        }
+    }
+    if b.ko != nil && b.transformPos(b.ko.Pos, sym) != b.ko.Pos {
+        return false
+    }
+    for pos := 0; pos < len(b.fields); pos++ {
+        grp, image := b.fields[pos], b.fields[b.transformPos(pos, sym)]
+        if (grp == nil) != (image == nil) || (grp != nil && grp.Color != image.Color) {
+            return false
+        }
+    }
+    return true
+}
+
+// Returns the symmetries which map the position on 'b' onto itself, always including the identity.
+func (b *Board) symmetries() []int {
+    syms := make([]int, 1, numSymmetries)
+    for sym := 1; sym < numSymmetries; sym++ {
+        if sym & 4 != 0 && b.width != b.height {
+            // the transposition does not map a rectangular board onto itself
+            continue
+        }
+        if b.isSymmetricUnder(sym) {
+            syms = syms[0:len(syms)+1]
+            syms[len(syms)-1] = sym
+        }
+    }
+    return syms
+}
+
+// Returns the image of 'pos' under the symmetry 'sym'. Passes (-1) are mapped onto themselves.
+func (b *Board) transformPos(pos, sym int) int {
+    if pos < 0 {
+        return pos
+    }
+    x, y := b.posToXY(pos)
+    x, y = transformXY(x, y, b.width, b.height, sym)
+    return b.xyToPos(x, y)
+}
+
+// ##################### symmetry helper functions ##########################
+
+// Returns the image of (x,y) under the symmetry 'sym' of a board with 'width' columns and 'height' rows.
+// Symmetries which transpose require a square board.
+func transformXY(x, y, width, height, sym int) (int, int) {
+    if sym & 4 != 0 {
+        x, y = y, x
+    }
+    if sym & 1 != 0 {
+        x = width - 1 - x
+    }
+    if sym & 2 != 0 {
+        y = height - 1 - y
+    }
+    return x, y
+}
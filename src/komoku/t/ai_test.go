@@ -8,6 +8,7 @@
 package komoku
 
 import (
+    "strings"
     "testing"
 )
 
@@ -32,8 +33,354 @@ func TestRunSimulation(t *testing.T) {
 
 }
 
+// With a threshold of one stone, the mercy rule decides every playout right after the first move.
+func TestMercyRule(t *testing.T) {
+    numTestSimulations := 100
+    ai := NewAI(9)
+    ai.SetMercyRule(1, MercyStoneDifference)
+
+    for i := 0; i < numTestSimulations; i++ {
+        ai.runSimulation()
+    }
+    if ai.stats.mercyTerminations != numTestSimulations {
+        t.Fatalf("expected %d mercy terminations, got %d", numTestSimulations, ai.stats.mercyTerminations)
+    }
+    if ai.topNode.simulations != numTestSimulations {
+        t.Fatalf("AI.topNode has a wrong number of simulations, expected %d, got %d", numTestSimulations, ai.topNode.simulations)
+    }
+    if ai.topNode.wonByBlack + ai.topNode.wonByWhite != numTestSimulations {
+        t.Fatalf("the mercy rule should never produce a jigo")
+    }
+}
+
+// Handicap stones are no lead for the mercy rule, only the stones gained in the playout are.
+func TestMercyRuleHandicap(t *testing.T) {
+    ai := NewAI(9)
+    for _, p := range []Point{ Point{2,2}, Point{6,2}, Point{2,6}, Point{6,6} } {
+        ai.environment.Game.PlayMove(p.X, p.Y, Black)
+    }
+    ai.SetMercyRule(3, MercyStoneDifference)
+    b := ai.environment.Game.Board
+    stonesBlack, stonesWhite := b.numberOfStones()
+    rootDifference := ai.mercyDifference(b, stonesBlack, stonesWhite)
+    if _, decided := ai.mercyWinner(b, stonesBlack, stonesWhite, rootDifference); decided {
+        t.Fatalf("the mercy rule decides the handicap position")
+    }
+    if winner, decided := ai.mercyWinner(b, stonesBlack+3, stonesWhite, rootDifference); !decided || winner != Black {
+        t.Fatalf("the mercy rule does not decide a lead of 3 stones gained in the playout")
+    }
+}
+
+// Discarded playouts must neither be scored nor leave nodes in the tree.
+func TestMoveCapDiscard(t *testing.T) {
+    numTestSimulations := 100
+    ai := NewAI(9)
+    ai.SetMoveCap(0.01, true)
+
+    for i := 0; i < numTestSimulations; i++ {
+        ai.runSimulation()
+    }
+    if ai.stats.cappedDiscarded != numTestSimulations {
+        t.Fatalf("expected %d discarded playouts, got %d", numTestSimulations, ai.stats.cappedDiscarded)
+    }
+    if ai.topNode.simulations != 0 {
+        t.Fatalf("discarded playouts have been scored, AI.topNode has %d simulations", ai.topNode.simulations)
+    }
+    if len(ai.topNode.children) != 0 {
+        t.Fatalf("discarded playouts left %d nodes in the tree", len(ai.topNode.children))
+    }
+}
+
+// The move cap counts from the start of the playout, so the playouts of a game which is already longer than
+// the cap are not discarded.
+func TestMoveCapLongGame(t *testing.T) {
+    numTestSimulations := 100
+    ai := NewAI(9)
+    ai.SetFastPlayouts(true)
+    ai.SetMoveCap(0, true)
+    color := Color(Black)
+    for i := 0; i <= ai.playoutMoveCap(); i++ {
+        ai.environment.Game.PlayPass(color)
+        color = !color
+    }
+    for i := 0; i < numTestSimulations; i++ {
+        ai.runSimulation()
+    }
+    if ai.stats.cappedDiscarded == numTestSimulations || ai.topNode.simulations == 0 {
+        t.Fatalf("all %d playouts of a long game have been discarded", numTestSimulations)
+    }
+}
+
+func TestSearchStatistics(t *testing.T) {
+    numTestSimulations := 50
+    ai := NewAI(9)
+    for i := 0; i < numTestSimulations; i++ {
+        ai.runSimulation()
+    }
+    stats := ai.Statistics()
+    if stats.playouts != numTestSimulations {
+        t.Fatalf("expected %d playouts, got %d", numTestSimulations, stats.playouts)
+    }
+    // every node below the top node has been created by exactly one simulation
+    if stats.treeSize != stats.expansions || stats.treeSize != ai.topNode.Size() - 1 {
+        t.Fatalf("tree size %d, expansions %d, but the tree has %d nodes", stats.treeSize, stats.expansions, ai.topNode.Size() - 1)
+    }
+    // with 50 playouts on 81 points, some of them share their first move
+    if stats.maxDepth == 0 || stats.AverageDepth() > float(stats.maxDepth) {
+        t.Fatalf("inconsistent depths: %d max, %f average", stats.maxDepth, stats.AverageDepth())
+    }
+    if strings.Index(stats.JSON(), "\"playouts\": 50,") == -1 {
+        t.Fatalf("the JSON output lacks the number of playouts: %s", stats.JSON())
+    }
+    // every simulation leaves the tree, so the time of each one is split among the three phases
+    if stats.selectionNs < 0 || stats.playoutNs <= 0 || stats.backpropNs < 0 {
+        t.Fatalf("inconsistent times: %dns selection, %dns playouts, %dns backprop",
+                 stats.selectionNs, stats.playoutNs, stats.backpropNs)
+    }
+    if strings.Index(stats.JSON(), "\"selection_seconds\": ") == -1 {
+        t.Fatalf("the JSON output lacks the selection time: %s", stats.JSON())
+    }
+}
+
+// Black owns the whole 5x5 board except for two eyes, so white can only pass and the game ends with
+// the next pass of black. The first playout proves this.
+func TestProvenEndOfGame(t *testing.T) {
+    ai := NewAI(5)
+    board := ai.environment.Game.Board
+    for pos := 0; pos < 25; pos++ {
+        if x, y := board.posToXY(pos); (x != 0 || y != 0) && (x != 4 || y != 4) {
+            board.PlayMove(x, y, Black)
+        }
+    }
+    board.setColorOfNextPlay(White)
+    for i := 0; i < 10; i++ {
+        ai.runSimulation()
+    }
+    pass, ok := ai.topNode.children[-1]
+    if !ok || !pass.proven || pass.provenWinner != Black {
+        t.Fatalf("the pass of white is not proven to be won by black")
+    }
+    if !ai.topNode.proven || ai.topNode.provenWinner != Black {
+        t.Fatalf("the position is not proven to be won by black")
+    }
+}
+
+// findBestMove prefers proven wins to good statistics and avoids proven losses.
+func TestFindBestMoveProven(t *testing.T) {
+    ai := NewAI(9)
+    board := ai.environment.Game.Board
+    good := ai.topNode.ChildNode(board.xyToPos(4, 4))
+    good.IncrementScore(10, 9, 1, 0)
+    won := ai.topNode.ChildNode(board.xyToPos(2, 2))
+    won.IncrementScore(10, 1, 9, 0)
+    won.setProven(Black)
+    if pos, _ := ai.findBestMove(Black); pos != board.xyToPos(2, 2) {
+        t.Fatalf("findBestMove does not choose the proven win")
+    }
+    won.setProven(White)
+    lost := ai.topNode.ChildNode(board.xyToPos(6, 6))
+    lost.IncrementScore(10, 10, 0, 0)
+    lost.setProven(White)
+    if pos, _ := ai.findBestMove(Black); pos != board.xyToPos(4, 4) {
+        t.Fatalf("findBestMove chooses a proven loss")
+    }
+}
+
+// Simulations are not spent on moves which are proven to lose: all moves of black but one are proven losses,
+// so every simulation has to start with the remaining move.
+func TestSimulationsAvoidProvenMoves(t *testing.T) {
+    ai := NewAI(9)
+    board := ai.environment.Game.Board
+    // break the symmetry of the empty board, so that the moves are the keys of the children
+    board.PlayMove(0, 1, White)
+    board.setColorOfNextPlay(Black)
+    open := board.xyToPos(4, 4)
+    ai.topNode.ChildNode(-1).setProven(White)
+    for _, pos := range board.listLegalPosses(Black) {
+        if pos != open {
+            ai.topNode.ChildNode(pos).setProven(White)
+        }
+    }
+    for i := 0; i < 20; i++ {
+        ai.runSimulation()
+    }
+    if simulations := ai.topNode.children[open].simulations; simulations != 20 {
+        t.Fatalf("only %d of 20 simulations start with the move which is not proven", simulations)
+    }
+}
+
+func TestMoveSelection(t *testing.T) {
+    ai := NewAI(9)
+    board := ai.environment.Game.Board
+    // moves which are not symmetric to each other, the top node only keeps canonical moves, see canonicalizeTopNode
+    best, second, bad := board.xyToPos(4, 4), board.xyToPos(2, 2), board.xyToPos(3, 2)
+    ai.topNode.ChildNode(best).IncrementScore(100, 60, 40, 0)
+    ai.topNode.ChildNode(second).IncrementScore(100, 58, 42, 0)
+    ai.topNode.ChildNode(bad).IncrementScore(100, 10, 90, 0)
+    if pos := ai.selectMove(Black); pos != best {
+        t.Fatalf("without randomization, selectMove does not choose the best move")
+    }
+
+    // within epsilon, both good moves are chosen, but never the bad one
+    ai.SetMoveSelection(0, 0, 0.05)
+    counts := make(map[int]int)
+    for i := 0; i < 200; i++ {
+        counts[ai.selectMove(Black)]++
+    }
+    if counts[bad] > 0 || counts[best] == 0 || counts[second] == 0 {
+        t.Fatalf("unexpected choices with epsilon 0.05: %v", counts)
+    }
+
+    // all moves have the same number of simulations, so a temperature draws all of them
+    ai.SetMoveSelection(1.0, 10, 0)
+    counts = make(map[int]int)
+    for i := 0; i < 300; i++ {
+        counts[ai.selectMove(Black)]++
+    }
+    if counts[bad] == 0 || counts[best] == 0 || counts[second] == 0 {
+        t.Fatalf("unexpected choices with temperature 1: %v", counts)
+    }
+
+    // an epsilon restricts the moves which are drawn with the temperature
+    ai.SetMoveSelection(1.0, 10, 0.05)
+    counts = make(map[int]int)
+    for i := 0; i < 300; i++ {
+        counts[ai.selectMove(Black)]++
+    }
+    if counts[bad] > 0 || counts[best] == 0 || counts[second] == 0 {
+        t.Fatalf("unexpected choices with temperature 1 and epsilon 0.05: %v", counts)
+    }
+}
+
+// A very low temperature nearly always draws the most simulated move, and must not overflow.
+func TestMoveSelectionLowTemperature(t *testing.T) {
+    ai := NewAI(9)
+    board := ai.environment.Game.Board
+    best, second := board.xyToPos(4, 4), board.xyToPos(2, 2)
+    ai.topNode.ChildNode(best).IncrementScore(5000, 3000, 2000, 0)
+    ai.topNode.ChildNode(second).IncrementScore(4500, 2700, 1800, 0)
+    ai.SetMoveSelection(0.01, 10, 0)
+    for i := 0; i < 100; i++ {
+        if pos := ai.selectMove(Black); pos != best {
+            t.Fatalf("temperature 0.01 chooses %d instead of the most simulated move %d", pos, best)
+        }
+    }
+}
+
+// On the empty board, the top node only gets children for the 15 canonical moves and the pass.
+func TestSymmetricChildren(t *testing.T) {
+    ai := NewAI(9)
+    for i := 0; i < 200; i++ {
+        ai.runSimulation()
+    }
+    if n := len(ai.topNode.children); n > 16 {
+        t.Fatalf("the top node has %d children on the empty board", n)
+    }
+    for pos, _ := range ai.topNode.children {
+        if c, _ := ai.environment.Game.Board.canonicalMove(pos, ai.rootSymmetries()); c != pos {
+            t.Fatalf("the top node has a child for the non-canonical move %d", pos)
+        }
+    }
+}
+
+// On the empty board the top node has one child per canonical move, so it is proven once these and the pass
+// are proven.
+func TestSymmetricChildrenProven(t *testing.T) {
+    ai := NewAI(9)
+    board := ai.environment.Game.Board
+    syms := ai.rootSymmetries()
+    pass := ai.topNode.ChildNode(-1)
+    pass.setProven(White)
+    for _, pos := range board.listLegalPosses(Black) {
+        c, _ := board.canonicalMove(pos, syms)
+        ai.topNode.ChildNode(c).setProven(White)
+    }
+    ai.propagateProof(pass, []int{ -1 })
+    if !ai.topNode.proven || ai.topNode.provenWinner != White {
+        t.Fatalf("the top node is not proven although all its %d children are", len(ai.topNode.children))
+    }
+}
+
+// A move played on the game directly, as GTP play does, changes the symmetries of the position although the
+// top node stays the same.
+func TestRootSymmetriesGameMove(t *testing.T) {
+    ai := NewAI(9)
+    ai.PlayMove(4, 4, Black)
+    if n := len(ai.rootSymmetries()); n != 8 {
+        t.Fatalf("the position after the move at the center has %d symmetries instead of 8", n)
+    }
+    ai.environment.Game.PlayMove(2, 3, White)
+    if n := len(ai.rootSymmetries()); n != 1 {
+        t.Fatalf("the position after a move played on the game has %d cached symmetries instead of 1", n)
+    }
+}
+
+// An inner node keeps the children of all moves and counts all of them. Once it is the top node of a symmetric
+// position, the duplicates are dropped and the canonical moves are counted.
+func TestSymmetricChildrenNewTopNode(t *testing.T) {
+    ai := NewAI(9)
+    board := ai.environment.Game.Board
+    inner := ai.topNode.ChildNode(board.xyToPos(4, 4))
+    inner.ChildNode(board.xyToPos(2, 2)).setProven(Black)
+    inner.ChildNode(board.xyToPos(6, 6)).setProven(Black)
+    inner.numMoves = 81
+    ai.PlayMove(4, 4, Black)
+    syms := ai.rootSymmetries()
+    for pos, _ := range ai.topNode.children {
+        if c, _ := board.canonicalMove(pos, syms); c != pos {
+            t.Fatalf("the top node keeps the child of the non-canonical move %d", pos)
+        }
+    }
+    pass := ai.topNode.ChildNode(-1)
+    pass.setProven(Black)
+    for _, pos := range board.listLegalPosses(White) {
+        c, _ := board.canonicalMove(pos, syms)
+        ai.topNode.ChildNode(c).setProven(Black)
+    }
+    ai.propagateProof(pass, []int{ -1 })
+    if !ai.topNode.proven || ai.topNode.provenWinner != Black {
+        t.Fatalf("the top node is not proven although all its %d children are", len(ai.topNode.children))
+    }
+}
+
+// Playouts on a FastBoard are scored in the tree like playouts on a Board.
+func TestFastPlayouts(t *testing.T) {
+    ai := NewAI(9)
+    ai.SetFastPlayouts(true)
+    for i := 0; i < 200; i++ {
+        ai.runSimulation()
+    }
+    if ai.topNode.simulations != 200 {
+        t.Fatalf("the top node has %d simulations instead of 200", ai.topNode.simulations)
+    }
+    // the playouts reuse one FastBoard
+    if first := ai.playoutBoard(); ai.playoutBoard() != first {
+        t.Fatalf("every fast playout gets a new FastBoard")
+    }
+    // a FastBoard ignores superko, so its playouts are capped even without a move cap
+    ai.SetMoveCap(0, false)
+    if ai.playoutMoveCap() <= 0 {
+        t.Fatalf("playouts on a FastBoard are not capped")
+    }
+}
+
 func Testsuite() []testing.Test {
     return []testing.Test {
         testing.Test{"TestRunSimulation", TestRunSimulation},
+        testing.Test{"TestMercyRule", TestMercyRule},
+        testing.Test{"TestMercyRuleHandicap", TestMercyRuleHandicap},
+        testing.Test{"TestMoveCapDiscard", TestMoveCapDiscard},
+        testing.Test{"TestMoveCapLongGame", TestMoveCapLongGame},
+        testing.Test{"TestSearchStatistics", TestSearchStatistics},
+        testing.Test{"TestProvenEndOfGame", TestProvenEndOfGame},
+        testing.Test{"TestFindBestMoveProven", TestFindBestMoveProven},
+        testing.Test{"TestSimulationsAvoidProvenMoves", TestSimulationsAvoidProvenMoves},
+        testing.Test{"TestMoveSelection", TestMoveSelection},
+        testing.Test{"TestMoveSelectionLowTemperature", TestMoveSelectionLowTemperature},
+        testing.Test{"TestSymmetricChildren", TestSymmetricChildren},
+        testing.Test{"TestSymmetricChildrenProven", TestSymmetricChildrenProven},
+        testing.Test{"TestRootSymmetriesGameMove", TestRootSymmetriesGameMove},
+        testing.Test{"TestSymmetricChildrenNewTopNode", TestSymmetricChildrenNewTopNode},
+        testing.Test{"TestFastPlayouts", TestFastPlayouts},
     }
 }
@@ -13,6 +13,7 @@ import (
     "rand"
     "time"
     "fmt"
+    "strings"
 )
 
 func TestCreateGroup(t *testing.T) {
@@ -26,15 +27,14 @@ func TestCreateGroup(t *testing.T) {
                 t.Fatalf("Field empty after CreateGroup created a group on it")
             }
             nbours := b.neighboursByPos(pos)
-            if len(nbours) != g.Liberties.Length() {
-                t.Fatalf("Different number of liberties (%d) and neighbours (%d)", g.Liberties.Length(), len(nbours))
+            if len(nbours) != g.NumLiberties() {
+                t.Fatalf("Different number of liberties (%d) and neighbours (%d)", g.NumLiberties(), len(nbours))
             }
             // are the neighbours of (x,y) exactly the liberties of the new group?
-            last := g.Liberties.Last()
-            for it := g.Liberties.First(); it != last; it = it.Next() {
+            for _, lib := range g.Liberties() {
                 found := false
                 for _, npos := range nbours {
-                    if npos == it.Value() {
+                    if npos == lib {
                         found = true
                         break
                     }
@@ -66,8 +66,8 @@ func TestUpdateGroupLiberties(t *testing.T) {
     b.CreateGroup(pos, Black)
     g := b.GetGroupByPoint(p.X, p.Y)
     b.updateGroupLiberties(g)
-    if g.Liberties.Length() != 4 {
-        t.Fatalf("Wrong # of liberties, got %d, want 4", g.Liberties.Length())
+    if g.NumLiberties() != 4 {
+        t.Fatalf("Wrong # of liberties, got %d, want 4", g.NumLiberties())
     }
     // two stones in a row
     p2 := NewPoint(1,2)
@@ -75,8 +75,8 @@ func TestUpdateGroupLiberties(t *testing.T) {
     b.CreateGroup(pos2, Black)
     b.joinGroups(b.fields[pos], b.fields[pos2])
     b.updateGroupLiberties(g)
-    if g.Liberties.Length() != 6 {
-        t.Fatalf("(2 stones): Wrong # of liberties, got %d, want 6", g.Liberties.Length())
+    if g.NumLiberties() != 6 {
+        t.Fatalf("(2 stones): Wrong # of liberties, got %d, want 6", g.NumLiberties())
     }
     // empty triangle - ewww!
     p3 := NewPoint(2,2)
@@ -84,8 +84,8 @@ func TestUpdateGroupLiberties(t *testing.T) {
     b.CreateGroup(pos3, Black)
     b.joinGroups(b.fields[pos], b.fields[pos3])
     b.updateGroupLiberties(g)
-    if g.Liberties.Length() != 7 {
-        t.Fatalf("(empty triangle): Wrong # of liberties, got %d, want 7", g.Liberties.Length())
+    if g.NumLiberties() != 7 {
+        t.Fatalf("(empty triangle): Wrong # of liberties, got %d, want 7", g.NumLiberties())
     }
 }
 
@@ -133,8 +133,8 @@ func TestJoinGroups(t *testing.T) {
     }
     g := b.GetGroupByPoint(t1[0].X, t1[0].Y)
     b.updateGroupLiberties(g)
-    if g.Liberties.Length() != 11 {
-        t.Fatalf("Wrong number of liberties, got %d, wanted 11", g.Liberties.Length())
+    if g.NumLiberties() != 11 {
+        t.Fatalf("Wrong number of liberties, got %d, wanted 11", g.NumLiberties())
     }
 }
 
@@ -226,14 +226,19 @@ func TestPosToXY(t *testing.T) {
 }
 
 func TestNeighbours(t *testing.T) {
-    b := NewBoard(DefaultBoardSize)
-    for row := 0; row < b.BoardSize(); row++ {
-        for col := 0; col < b.BoardSize(); col++ {
+    for _, b := range []*Board{ NewBoard(DefaultBoardSize), NewRectangularBoard(7, 13) } {
+        testNeighboursOn(b, t)
+    }
+}
+
+func testNeighboursOn(b *Board, t *testing.T) {
+    for row := 0; row < b.Height(); row++ {
+        for col := 0; col < b.Width(); col++ {
             expectedLen := 4
-            if row == 0 || row == b.BoardSize()-1 {
+            if row == 0 || row == b.Height()-1 {
                 expectedLen--
             }
-            if col == 0 || col == b.BoardSize()-1 {
+            if col == 0 || col == b.Width()-1 {
                 expectedLen--
             }
             pos := b.xyToPos(col, row)
@@ -333,6 +338,136 @@ func TestNumStones(t *testing.T) {
     }
 }
 
+// The walls of black stones at x=3 and white stones at x=5 on a 9x9 board: black owns the four columns on
+// the left, white the four columns on the right, and the column between the walls is neutral.
+func TestAreaScore(t *testing.T) {
+    b := NewBoard(9)
+    if black, white := b.AreaScore(); black != 0 || white != 0 {
+        t.Fatalf("the empty board counts (b%d,w%d) instead of (b0,w0)", black, white)
+    }
+    b.PlayMove(4, 4, Black)
+    if black, white := b.AreaScore(); black != 81 || white != 0 {
+        t.Fatalf("a single stone counts (b%d,w%d) instead of (b81,w0)", black, white)
+    }
+    b = NewBoard(9)
+    for y := 0; y < 9; y++ {
+        b.PlayMove(3, y, Black)
+        b.PlayMove(5, y, White)
+    }
+    if black, white := b.AreaScore(); black != 36 || white != 36 {
+        t.Fatalf("the walls count (b%d,w%d) instead of (b36,w36)", black, white)
+    }
+    // a white stone inside the black region makes it neutral, and the column between the walls stays neutral
+    b.PlayMove(0, 0, White)
+    if black, white := b.AreaScore(); black != 9 || white != 37 {
+        t.Fatalf("the walls with a white invader count (b%d,w%d) instead of (b9,w37)", black, white)
+    }
+}
+
+// A capture on the right edge of a board with 9 columns and 5 rows.
+//
+//   4 . . . . . . . . .
+//   3 . . . . . . . . .
+//   2 . . . . . . . X .
+//   1 . . . . . . X O X
+//   0 . . . . . . . X .
+//     0 1 2 3 4 5 6 7 8
+func TestRectangularBoard(t *testing.T) {
+    b := NewRectangularBoard(9, 5)
+    if b.Width() != 9 || b.Height() != 5 || len(b.ListEmptyFields()) != 45 {
+        t.Fatalf("the board has %dx%d fields and %d empty ones instead of 9x5 and 45",
+                 b.Width(), b.Height(), len(b.ListEmptyFields()))
+    }
+    if b.PlayMove(5, 8, Black) == nil || b.PlayMove(9, 0, Black) == nil {
+        t.Fatalf("a move outside of the board is played")
+    }
+    b.PlayMove(7, 1, White)
+    for _, p := range []Point{ Point{6,1}, Point{7,0}, Point{7,2}, Point{8,1} } {
+        b.PlayMove(p.X, p.Y, Black)
+    }
+    if b.fields[b.xyToPos(7, 1)] != nil {
+        t.Fatalf("the white stone is not captured")
+    }
+    if black, white := b.AreaScore(); black != 45 || white != 0 {
+        t.Fatalf("the area is (b%d,w%d) instead of (b45,w0)", black, white)
+    }
+    cpy := b.Copy()
+    if cpy.Width() != 9 || cpy.Height() != 5 || !cpy.IsLegalMove(cpy.xyToPos(8, 4), White) {
+        t.Fatalf("the copy has lost the dimensions of the board")
+    }
+    if rows := strings.Split(printBoardPrimitive(b, "", -1, -1, nil), "\n", -1); len(rows) != 5+3 {
+        t.Fatalf("the board is printed with %d lines instead of 7", len(rows)-1)
+    }
+}
+
+// Boards which are only one field wide or high: captures along the line, the 1x1 board without legal moves
+// and random games.
+func TestOneWideBoard(t *testing.T) {
+    b := NewRectangularBoard(1, 5)
+    b.PlayMove(0, 0, White)
+    b.PlayMove(0, 1, Black)
+    if b.fields[b.xyToPos(0, 0)] != nil {
+        t.Fatalf("the white stone at the end of the 1x5 board is not captured")
+    }
+    b = NewRectangularBoard(5, 1)
+    b.PlayMove(2, 0, White)
+    b.PlayMove(1, 0, Black)
+    b.PlayMove(3, 0, Black)
+    if b.fields[b.xyToPos(2, 0)] != nil {
+        t.Fatalf("the white stone in the middle of the 5x1 board is not captured")
+    }
+    if NewRectangularBoard(1, 1).IsLegalMove(0, Black) {
+        t.Fatalf("the only field of the 1x1 board, which has no liberty, is a legal move")
+    }
+    for _, size := range []Point{ Point{1, 5}, Point{3, 1}, Point{1, 1} } {
+        b = NewRectangularBoard(size.X, size.Y)
+        for i := 0; i < 50; i++ {
+            b.PlayRandomMove(b.ColorOfNextPlay())
+        }
+        if b.Hash() != b.computeHash() {
+            t.Fatalf("the hash is not maintained on the %dx%d board", size.X, size.Y)
+        }
+    }
+}
+
+// The largest board: a capture in the far corner, the hash and the column labels beyond Z.
+func TestLargestBoard(t *testing.T) {
+    b := NewBoard(MaxBoardSize)
+    last := MaxBoardSize - 1
+    b.PlayMove(last, last, White)
+    b.PlayMove(last - 1, last, Black)
+    b.PlayMove(last, last - 1, Black)
+    if b.fields[b.xyToPos(last, last)] != nil {
+        t.Fatalf("the white stone in the corner is not captured")
+    }
+    if b.Hash() != b.computeHash() {
+        t.Fatalf("the hash is not maintained on the largest board")
+    }
+    header := strings.Split(printBoardPrimitive(b, "", -1, -1, nil), "\n", -1)[0]
+    if !strings.HasSuffix(header, " Y Z") {
+        t.Fatalf("the columns of the largest board are labeled as %s", header)
+    }
+}
+
+// Boards larger than MaxBoardSize or without fields are rejected by the constructor.
+func TestUnsupportedBoardSizes(t *testing.T) {
+    for _, size := range []Point{ Point{MaxBoardSize+1, MaxBoardSize+1}, Point{9, MaxBoardSize+1}, Point{0, 5} } {
+        rejected := func() (rejected bool) {
+            defer func() {
+                if e := recover(); e != nil {
+                    err, ok := e.(Error)
+                    rejected = ok && err.Errno() == ErrUnacceptableBoardSize
+                }
+            }()
+            NewRectangularBoard(size.X, size.Y)
+            return false
+        }()
+        if !rejected {
+            t.Fatalf("a board with %dx%d fields is not rejected", size.X, size.Y)
+        }
+    }
+}
+
 type testGroupGeometryCase struct {
     seqBlack, seqWhite []Point
     blackGroupPivot, whiteGroupPivot *Point
@@ -364,18 +499,17 @@ func TestGroupGeometry(t *testing.T) {
             }
         }
         grp := b.GetGroupByPoint(setPoints[0].X, setPoints[0].Y)
-        if len(setPoints) != grp.Fields.Length() {
-            t.Fatalf("Failed testcase %d (%s), group has wrong number of stones, got %d, wanted %d", number, color, grp.Fields.Length(), len(setPoints))
+        if len(setPoints) != grp.NumStones() {
+            t.Fatalf("Failed testcase %d (%s), group has wrong number of stones, got %d, wanted %d", number, color, grp.NumStones(), len(setPoints))
         }
         all := make(map[int]bool)
         for _, p := range setPoints {
             pos := b.xyToPos(p.X, p.Y)
             all[pos] = true
         }
-        last := grp.Fields.Last()
-        for it := grp.Fields.First(); it != last; it = it.Next() {
-            all[it.Value()] = false, false
-        }
+        grp.DoStones(func(pos int) {
+            all[pos] = false, false
+        })
         if len(all) != 0 {
             t.Fatalf("Failed testcase %d (%s), expected black fields and white fields seem to differ", number, color)
         }
@@ -454,6 +588,10 @@ func TestKo(t *testing.T) {
     if !legalBlack {
         t.Fatalf("after tennuki: expected that a black move at (%d,%d) is now illegal", 4,5)
     }
+    // the cached legality has to follow
+    if !game.Board.IsLegalMove(pos, Black) {
+        t.Fatalf("after tennuki: the cached legality still forbids a black move at (%d,%d)", 4,5)
+    }
 
 }
 
@@ -606,9 +744,9 @@ func gameStateCheck(game *Game,
     }
     // check that no groups have 0 liberties
     gmEach := func(grp *Group) {
-        if grp.Liberties.Length() == 0 {
+        if grp.NumLiberties() == 0 {
             failMessage := fmt.Sprintf("in game %d, there were groups with 0 liberties after %d moves.\nSeq dumped into %s", nGame, nMove, dumpFile)
-            gX, gY := game.Board.posToXY(grp.Fields.First().Value())
+            gX, gY := game.Board.posToXY(grp.FirstStone())
             vertex, _ := pointToGTPVertex(*NewPoint(gX, gY))
             infoString := fmt.Sprintf("# the group with 0 libs is around %s", vertex)
             fileFail(failMessage, infoString, dumpFile, game, t)
@@ -801,9 +939,9 @@ func TestFinalPosition(t *testing.T) {
                 lastPass = false
             }
         }
-        // game finished
+        // game finished. Only eyes and the shared liberties of sekis may remain empty.
         for pos := 0; pos < boardSize*boardSize; pos++ {
-            if game.Board.fields[pos] == nil {
+            if game.Board.fields[pos] == nil && !game.Board.isSekiPoint(pos) {
                 nFree, adjBlack, adjWhite := game.Board.GetEnvironment(pos)
                 _ = adjBlack
                 _ = adjWhite
@@ -929,6 +1067,11 @@ func Testsuite() []testing.Test {
         testing.Test{"TestNeighbours", TestNeighbours},
         testing.Test{"TestNumGroups", TestNumGroups},
         testing.Test{"TestNumStones", TestNumStones},
+        testing.Test{"TestAreaScore", TestAreaScore},
+        testing.Test{"TestRectangularBoard", TestRectangularBoard},
+        testing.Test{"TestOneWideBoard", TestOneWideBoard},
+        testing.Test{"TestLargestBoard", TestLargestBoard},
+        testing.Test{"TestUnsupportedBoardSizes", TestUnsupportedBoardSizes},
         testing.Test{"TestGroupGeometry", TestGroupGeometry},
         testing.Test{"TestKo", TestKo},
         testing.Test{"TestDoubleKo", TestDoubleKo},
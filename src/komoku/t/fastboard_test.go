@@ -0,0 +1,166 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "rand"
+    "testing"
+)
+
+// The rules of New Zealand without superko, which FastBoard does not check.
+var suicideRules = &Ruleset{ Name: "suicide", Suicide: true, Superko: NoSuperko, Scoring: AreaScoring }
+
+func equalIntSlices(a, c []int) bool {
+    if len(a) != len(c) {
+        return false
+    }
+    for i := range a {
+        if a[i] != c[i] {
+            return false
+        }
+    }
+    return true
+}
+
+// Compares 'fast' with 'b' and returns a description of the first difference, "" if there is none.
+func fastBoardDifference(b *Board, fast *FastBoard) string {
+    for pos := 0; pos < len(b.fields); pos++ {
+        grp := b.fields[pos]
+        if (grp != nil && grp.Color == Black) != fast.black.has(pos) || (grp != nil && grp.Color == White) != fast.white.has(pos) {
+            return "stones"
+        }
+    }
+    prisonersBlack, prisonersWhite := b.numberOfPrisoners()
+    fastBlack, fastWhite := fast.numberOfPrisoners()
+    if prisonersBlack != fastBlack || prisonersWhite != fastWhite {
+        return "prisoners"
+    }
+    if b.ColorOfNextPlay() != fast.ColorOfNextPlay() || b.Hash() != fast.Hash() {
+        return "color of next play or hash"
+    }
+    if !equalIntSlices(b.listLegalPosses(Black), fast.listLegalPosses(Black)) {
+        return "legal moves of black"
+    }
+    if !equalIntSlices(b.listLegalPosses(White), fast.listLegalPosses(White)) {
+        return "legal moves of white"
+    }
+    areaBlack, areaWhite := b.AreaScore()
+    fastBlack, fastWhite = fast.AreaScore()
+    if areaBlack != fastBlack || areaWhite != fastWhite {
+        return "area"
+    }
+    return ""
+}
+
+// Plays random games on a Board and a FastBoard at once and compares them after each move. The moves are drawn
+// from all legal moves, so eyes are filled and groups commit suicide. Every 50 moves, the FastBoard is created
+// again from the Board, and another one is filled again, see FastBoard.fill.
+func testFastBoardRandomGames(t *testing.T, width, height int, rules *Ruleset) {
+    r := rand.New(rand.NewSource(int64(width*height)))
+    for game := 0; game < 10; game++ {
+        b := NewRectangularBoard(width, height)
+        b.SetRules(rules)
+        fast := NewFastBoard(b)
+        refilled := NewFastBoard(b)
+        for move := 0; move < 300; move++ {
+            color := b.ColorOfNextPlay()
+            legal := b.listLegalPosses(color)
+            if len(legal) == 0 || r.Intn(50) == 0 {
+                b.PlayPass(color)
+                fast.PlayPass(color)
+            } else {
+                pos := legal[r.Intn(len(legal))]
+                if err := b.playMoveByPos(pos, color); err != nil {
+                    t.Fatalf("the Board refuses a legal move: %s", err.String())
+                }
+                if err := fast.playMoveByPos(pos, color); err != nil {
+                    t.Fatalf("the FastBoard refuses a legal move: %s", err.String())
+                }
+            }
+            if diff := fastBoardDifference(b, fast); diff != "" {
+                t.Fatalf("the %s differ after move %d on %dx%d under %s rules", diff, move, width, height, rules.Name)
+            }
+            if move % 50 == 0 {
+                fast = NewFastBoard(b)
+                if diff := fastBoardDifference(b, fast); diff != "" {
+                    t.Fatalf("the %s differ after creating the FastBoard after move %d", diff, move)
+                }
+                // a FastBoard which has played its own moves is filled again
+                for i := 0; i < 20; i++ {
+                    refilled.PlayRandomMove(refilled.ColorOfNextPlay())
+                }
+                refilled.fill(b)
+                if diff := fastBoardDifference(b, refilled); diff != "" {
+                    t.Fatalf("the %s differ after filling the FastBoard again after move %d", diff, move)
+                }
+            }
+        }
+    }
+}
+
+func TestFastBoardRandomGames(t *testing.T) {
+    testFastBoardRandomGames(t, 9, 9, JapaneseRules)
+    testFastBoardRandomGames(t, 9, 9, suicideRules)
+    testFastBoardRandomGames(t, 7, 13, JapaneseRules)
+    testFastBoardRandomGames(t, 5, 5, suicideRules)
+}
+
+// The playouts of a FastBoard have to be legal on a Board too.
+func TestFastBoardPlayouts(t *testing.T) {
+    for game := 0; game < 20; game++ {
+        b := NewBoard(9)
+        b.SetRules(JapaneseRules)
+        fast := NewFastBoard(b)
+        lastPass := false
+        for move := 0; move < 500; move++ {
+            color := fast.ColorOfNextPlay()
+            v := fast.PlayRandomMove(color)
+            if v.Pass {
+                b.PlayPass(color)
+                if lastPass {
+                    break
+                }
+            } else if err := b.PlayMove(v.X, v.Y, color); err != nil {
+                t.Fatalf("the FastBoard plays an illegal move: %s", err.String())
+            }
+            lastPass = v.Pass
+            if diff := fastBoardDifference(b, fast); diff != "" {
+                t.Fatalf("the %s differ after move %d of a playout", diff, move)
+            }
+        }
+    }
+}
+
+// A copy has to be independent of the original.
+func TestFastBoardCopy(t *testing.T) {
+    b := NewBoard(9)
+    fast := NewFastBoard(b)
+    for i := 0; i < 40; i++ {
+        v := fast.PlayRandomMove(fast.ColorOfNextPlay())
+        if v.Pass {
+            b.PlayPass(b.ColorOfNextPlay())
+        } else {
+            b.PlayMove(v.X, v.Y, b.ColorOfNextPlay())
+        }
+    }
+    cpy := fast.Copy()
+    for i := 0; i < 40; i++ {
+        cpy.PlayRandomMove(cpy.ColorOfNextPlay())
+    }
+    if diff := fastBoardDifference(b, fast); diff != "" {
+        t.Fatalf("the %s of the original changed by playing on the copy", diff)
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestFastBoardRandomGames", TestFastBoardRandomGames},
+        testing.Test{"TestFastBoardPlayouts", TestFastBoardPlayouts},
+        testing.Test{"TestFastBoardCopy", TestFastBoardCopy},
+    }
+}
@@ -0,0 +1,85 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "testing"
+)
+
+func TestAreaScoreDifference(t *testing.T) {
+    // 2 . O .
+    // 1 X O .
+    // 0 . X .
+    //   0 1 2
+    b := NewBoard(3)
+    b.PlayMove(0, 1, Black)
+    b.PlayMove(1, 0, Black)
+    b.PlayMove(1, 1, White)
+    b.PlayMove(1, 2, White)
+    // black: 2 stones and A1, white: 2 stones. A3 and the right column touch both colors.
+    if score := areaScoreDifference(b); score != 3 - 2 {
+        t.Fatalf("areaScoreDifference returns %d instead of 1", score)
+    }
+}
+
+// A black wall in the middle of a 3x3 board: white cannot live on either side.
+func TestSolveGameWall(t *testing.T) {
+    for _, toMove := range []Color{ Black, White } {
+        b := NewBoard(3)
+        for y := 0; y < 3; y++ {
+            b.PlayMove(1, y, Black)
+        }
+        score, _, solved, err := b.SolveGame(toMove, ChineseRules, 0.5, 2, 1000000)
+        if err != nil {
+            t.Fatalf("SolveGame returns an error: %s", err)
+        }
+        if !solved {
+            t.Fatalf("SolveGame did not solve the position with %s to move", toMove)
+        }
+        // 9 points of area minus the komi and the compensation for 2 handicap stones
+        if score != 6.5 {
+            t.Fatalf("SolveGame returns %g with %s to move instead of 6.5", score, toMove)
+        }
+    }
+}
+
+func TestSolveGameLimits(t *testing.T) {
+    b := NewBoard(6)
+    if _, _, _, err := b.SolveGame(Black, ChineseRules, 0, 0, 1000); err == nil {
+        t.Fatalf("SolveGame accepts a 6x6 board")
+    }
+    b = NewBoard(5)
+    if _, _, solved, err := b.SolveGame(Black, ChineseRules, 0, 0, 10); err != nil || solved {
+        t.Fatalf("SolveGame solves the empty 5x5 board with 10 nodes")
+    }
+}
+
+// The positions of the game before the solved position count for superko.
+func TestSolveGameHistory(t *testing.T) {
+    game := NewBoard(3)
+    game.PlayMove(0, 0, Black)
+    for _, rules := range []*Ruleset{ ChineseRules, AGARules } {
+        s := &gameSolver{ rules: rules, superko: rules.Superko, history: make(map[uint64]int) }
+        s.pushGameHistory(game)
+        var reached gsFilter
+        for _, child := range s.children(NewBoard(3), Black, &reached) {
+            if child.pos == game.xyToPos(0, 0) {
+                t.Fatalf("%s: the move which repeats a position of the game is searched", rules.Name)
+            }
+        }
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestAreaScoreDifference", TestAreaScoreDifference},
+        testing.Test{"TestSolveGameWall", TestSolveGameWall},
+        testing.Test{"TestSolveGameLimits", TestSolveGameLimits},
+        testing.Test{"TestSolveGameHistory", TestSolveGameHistory},
+    }
+}
@@ -48,9 +48,59 @@ func TestGroupSlicePushUnique(t *testing.T) {
     }
 }
 
+// The liberties of a group are kept in a bitset, which has to iterate over its positions across words.
+func TestGroupLiberties(t *testing.T) {
+    g := NewGroup(Black)
+    g.liberties = newBitset(200)
+    libs := []int{ 0, 5, 63, 64, 130, 199 }
+    for _, lib := range libs {
+        g.addLiberty(lib)
+        g.addLiberty(lib)
+    }
+    if g.NumLiberties() != len(libs) || g.liberties.count() != len(libs) {
+        t.Fatalf("the group has %d liberties instead of %d", g.NumLiberties(), len(libs))
+    }
+    for i, lib := range g.Liberties() {
+        if lib != libs[i] {
+            t.Fatalf("liberty %d is %d instead of %d", i, lib, libs[i])
+        }
+    }
+    g.removeLiberty(0)
+    g.removeLiberty(1)
+    if g.FirstLiberty() != 5 || g.NumLiberties() != len(libs) - 1 {
+        t.Fatalf("the first liberty is %d after removing 0", g.FirstLiberty())
+    }
+    if g.liberties.next(131) != 199 || g.liberties.next(200) != -1 {
+        t.Fatalf("the bitset finds the wrong next position")
+    }
+}
+
+// A copy keeps its own liberty bitset, which is reused when copying into the same group again.
+func TestGroupCopy(t *testing.T) {
+    g := NewGroup(Black)
+    g.liberties = newBitset(81)
+    g.addLiberty(3)
+    var cpy Group
+    g.Copy(&cpy)
+    libs := cpy.liberties
+    g.addLiberty(7)
+    if cpy.NumLiberties() != 1 || cpy.liberties.has(7) {
+        t.Fatalf("the copy shares the liberties of the group")
+    }
+    g.Copy(&cpy)
+    if &cpy.liberties[0] != &libs[0] {
+        t.Fatalf("the liberty bitset of the copy is allocated again")
+    }
+    if cpy.NumLiberties() != 2 || !cpy.liberties.has(3) || !cpy.liberties.has(7) {
+        t.Fatalf("the copy has the wrong liberties %v", cpy.Liberties())
+    }
+}
+
 func Testsuite() []testing.Test {
     return []testing.Test {
         testing.Test{"TestGroupSlicePush", TestGroupSlicePush},
         testing.Test{"TestGroupSlicePushUnique", TestGroupSlicePushUnique},
+        testing.Test{"TestGroupLiberties", TestGroupLiberties},
+        testing.Test{"TestGroupCopy", TestGroupCopy},
     }
 }
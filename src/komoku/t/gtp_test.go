@@ -9,6 +9,7 @@ package komoku
 
 import (
     "testing"
+    "strings"
     //"fmt"
 )
 
@@ -127,7 +128,106 @@ func TestParseLine(t *testing.T) {
     return
 }
 
+// boardsize accepts the number of columns and rows of a rectangular board as a second argument.
+func TestRectangularBoardsize(t *testing.T) {
+    obj := NewGTPObject()
+    if result, _, _ := obj.ExecuteCommand("boardsize 9 13"); !strings.HasPrefix(result, "=") {
+        t.Fatalf("boardsize 9 13 fails: %s", result)
+    }
+    if b := obj.ai.environment.Game.Board; b.Width() != 9 || b.Height() != 13 {
+        t.Fatalf("the board has %dx%d fields instead of 9x13", b.Width(), b.Height())
+    }
+    if result, _, _ := obj.ExecuteCommand("gogui-rules_board_size"); result != "= 9 13\n\n" {
+        t.Fatalf("gogui-rules_board_size prints %q for the 9x13 board", result)
+    }
+    if result, _, _ := obj.ExecuteCommand("boardsize 9 13 7"); !strings.HasPrefix(result, "?") {
+        t.Fatalf("boardsize accepts three arguments")
+    }
+    if result, _, _ := obj.ExecuteCommand("boardsize 7"); !strings.HasPrefix(result, "=") {
+        t.Fatalf("boardsize 7 fails: %s", result)
+    }
+    if b := obj.ai.environment.Game.Board; b.Width() != 7 || b.Height() != 7 {
+        t.Fatalf("the board has %dx%d fields instead of 7x7", b.Width(), b.Height())
+    }
+}
+
+// undo takes back a generated move while the engine ponders, and the next genmove searches the position
+// after the undo.
+func TestUndoGenmove(t *testing.T) {
+    obj := NewGTPObject()
+    for _, cmd := range []string{ "boardsize 5", "clear_board", "play b c3", "genmove w", "undo" } {
+        if result, _, _ := obj.ExecuteCommand(cmd); !strings.HasPrefix(result, "=") {
+            t.Fatalf("%s fails: %s", cmd, result)
+        }
+    }
+    obj.ai.stopThinking()
+    if obj.ai.topNode.parent != nil {
+        t.Fatalf("the search tree still belongs to the position before the undo")
+    }
+    if black, white := obj.ai.environment.Game.Board.numberOfStones(); black != 1 || white != 0 {
+        t.Fatalf("%d black and %d white stones are on the board after the undo", black, white)
+    }
+    if result, _, _ := obj.ExecuteCommand("genmove w"); !strings.HasPrefix(result, "=") || result == "= C3\n\n" {
+        t.Fatalf("genmove after the undo fails: %s", result)
+    }
+    obj.ai.stopThinking()
+    if black, white := obj.ai.environment.Game.Board.numberOfStones(); black != 1 || white != 1 {
+        t.Fatalf("%d black and %d white stones are on the board after genmove", black, white)
+    }
+}
+
+// clear_board starts a new game: the moves of the old game do not count for the temperature of the move
+// selection, and the search tree starts afresh.
+func TestClearBoardGenmove(t *testing.T) {
+    obj := NewGTPObject()
+    for _, cmd := range []string{ "boardsize 5", "komoku-moveselection 1 2 0", "play b c3", "play w c2", "clear_board" } {
+        if result, _, _ := obj.ExecuteCommand(cmd); !strings.HasPrefix(result, "=") {
+            t.Fatalf("%s fails: %s", cmd, result)
+        }
+    }
+    if n := len(obj.ai.environment.Game.sequence); n != 0 {
+        t.Fatalf("the new game has %d moves after clear_board", n)
+    }
+    if obj.ai.topNode.parent != nil || len(obj.ai.topNode.children) != 0 {
+        t.Fatalf("the search tree still belongs to the old game")
+    }
+    if result, _, _ := obj.ExecuteCommand("genmove b"); !strings.HasPrefix(result, "=") {
+        t.Fatalf("genmove after clear_board fails: %s", result)
+    }
+    obj.ai.stopThinking()
+    if n := len(obj.ai.environment.Game.sequence); n != 1 {
+        t.Fatalf("the new game has %d moves after genmove instead of 1", n)
+    }
+    if black, white := obj.ai.environment.Game.Board.numberOfStones(); black + white > 1 {
+        t.Fatalf("%d black and %d white stones are on the board after genmove", black, white)
+    }
+}
+
+// Commands which read the board stop the pondering thinkers while they run and let them go on afterwards.
+func TestCommandsWhilePondering(t *testing.T) {
+    obj := NewGTPObject()
+    for _, cmd := range []string{ "boardsize 5", "komoku-moveselection 1 2 0", "genmove b" } {
+        if result, _, _ := obj.ExecuteCommand(cmd); !strings.HasPrefix(result, "=") {
+            t.Fatalf("%s fails: %s", cmd, result)
+        }
+    }
+    for i := 0; i < 20; i++ {
+        result, _, _ := obj.ExecuteCommand("gogui-rules_legal_moves")
+        if !strings.HasPrefix(result, "=") {
+            t.Fatalf("gogui-rules_legal_moves fails: %s", result)
+        }
+        if !obj.ai.runThinkers {
+            t.Fatalf("the engine does not ponder after gogui-rules_legal_moves")
+        }
+    }
+    obj.ai.stopThinking()
+}
+
 func Testsuite() []testing.Test {
     return []testing.Test { testing.Test{"TestParseLine", TestParseLine},
+                            testing.Test{"TestRectangularBoardsize", TestRectangularBoardsize},
+                            testing.Test{"TestUndoGenmove", TestUndoGenmove},
+                            testing.Test{"TestClearBoardGenmove", TestClearBoardGenmove},
+                            testing.Test{"TestCommandsWhilePondering", TestCommandsWhilePondering},
                           }
 }
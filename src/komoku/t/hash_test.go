@@ -0,0 +1,68 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "testing"
+)
+
+// The incremental hash has to equal the hash computed from scratch during whole random games,
+// which contain captures, kos and passes.
+func TestIncrementalHash(t *testing.T) {
+    for _, size := range []int{ 5, 9 } {
+        b := NewBoard(size)
+        lastPass := false
+        for i := 0; i < 500; i++ {
+            v := b.PlayRandomMove(b.ColorOfNextPlay())
+            if b.Hash() != b.computeHash() {
+                t.Fatalf("the incremental hash differs from the computed one after move %d on %dx%d", i, size, size)
+            }
+            if cpy := b.Copy(); cpy.Hash() != b.Hash() {
+                t.Fatalf("the copy of the board has a different hash")
+            }
+            if v.Pass && lastPass {
+                break
+            }
+            lastPass = v.Pass
+        }
+        b.Reset()
+        if b.Hash() != NewBoard(size).Hash() {
+            t.Fatalf("the hash of a reset board differs from the hash of a new board")
+        }
+    }
+}
+
+func TestHashTransposition(t *testing.T) {
+    a := NewBoard(9)
+    a.PlayMove(2, 2, Black)
+    a.PlayMove(6, 6, White)
+    a.PlayMove(2, 6, Black)
+    b := NewBoard(9)
+    b.PlayMove(2, 6, Black)
+    b.PlayMove(6, 6, White)
+    b.PlayMove(2, 2, Black)
+    if a.Hash() != b.Hash() {
+        t.Fatalf("the same position reached by different move orders has different hashes")
+    }
+    // the side to move is part of the position
+    b.PlayPass(White)
+    if a.Hash() == b.Hash() {
+        t.Fatalf("the hash does not depend on the side to move")
+    }
+    b.PlayPass(Black)
+    if a.Hash() != b.Hash() {
+        t.Fatalf("two passes change the hash")
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestIncrementalHash", TestIncrementalHash},
+        testing.Test{"TestHashTransposition", TestHashTransposition},
+    }
+}
@@ -0,0 +1,299 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "testing"
+)
+
+// Returns the stones of 'g' as a set.
+func stoneSet(g *Group) map[int]bool {
+    set := make(map[int]bool)
+    g.DoStones(func(pos int) {
+        set[pos] = true
+    })
+    return set
+}
+
+// Returns the liberties of 'g' as a set.
+func libertySet(g *Group) map[int]bool {
+    set := make(map[int]bool)
+    g.DoLiberties(func(pos int) {
+        set[pos] = true
+    })
+    return set
+}
+
+func equalIntSets(a, c map[int]bool) bool {
+    if len(a) != len(c) {
+        return false
+    }
+    for v, _ := range a {
+        if !c[v] {
+            return false
+        }
+    }
+    return true
+}
+
+// Compares the state of 'a' and 'c' and returns a description of the first difference, "" if there is none.
+func boardDifference(a, c *Board) string {
+    if a.ko == nil != (c.ko == nil) || (a.ko != nil && *a.ko != *c.ko) {
+        return "ko"
+    }
+    if a.colorOfNextPlay != c.colorOfNextPlay || a.currentSequence != c.currentSequence || a.hash != c.hash {
+        return "color of next play, sequence or hash"
+    }
+    if a.prisonersBlack != c.prisonersBlack || a.prisonersWhite != c.prisonersWhite {
+        return "prisoners"
+    }
+    if a.lastMove != c.lastMove || a.secondLastMove != c.secondLastMove {
+        return "last moves"
+    }
+    if len(a.history) != len(c.history) || a.history[len(a.history)-1] != c.history[len(c.history)-1] {
+        return "position history"
+    }
+    for pos := 0; pos < len(a.fields); pos++ {
+        ga, gc := a.fields[pos], c.fields[pos]
+        if ga == nil != (gc == nil) {
+            return "occupation of a field"
+        }
+        if ga != nil {
+            if ga.Color != gc.Color || !equalIntSets(stoneSet(ga), stoneSet(gc)) {
+                return "stones of a group"
+            }
+            if !equalIntSets(libertySet(ga), libertySet(gc)) {
+                return "liberties of a group"
+            }
+        }
+        if a.fieldSequencesBlack[pos] != c.fieldSequencesBlack[pos] || a.fieldSequencesWhite[pos] != c.fieldSequencesWhite[pos] {
+            return "legality sequences"
+        }
+        if a.actionOnNextBlackMove[pos] == nil != (c.actionOnNextBlackMove[pos] == nil) ||
+           a.actionOnNextWhiteMove[pos] == nil != (c.actionOnNextWhiteMove[pos] == nil) {
+            return "cached legalities"
+        }
+    }
+    return ""
+}
+
+// Computes the legalities of all empty fields for both colors. Legalities which are computed lazily before a
+// move belong to the position before the move and are not taken back with it, so the state is compared after
+// computing them.
+func updateAllLegalities(b *Board) {
+    b.listLegalPosses(Black)
+    b.listLegalPosses(White)
+}
+
+// Plays random games, takes all moves back and compares the states with copies made before each move.
+// The legalities are computed again after each move, so that the lazily computed legalities are taken back too.
+func testUndoRandomGames(t *testing.T, rules *Ruleset) {
+    for game := 0; game < 10; game++ {
+        b := NewBoard(9)
+        b.SetRules(rules)
+        copies := make([]*Board, 0, 400)
+        lastPass := false
+        for len(copies) < cap(copies) {
+            updateAllLegalities(b)
+            copies = copies[0:len(copies)+1]
+            copies[len(copies)-1] = b.Copy()
+            v := b.PlayRandomMove(b.ColorOfNextPlay())
+            if v.Pass && lastPass {
+                break
+            }
+            lastPass = v.Pass
+        }
+        updateAllLegalities(b)
+        if b.UndoableMoves() != len(copies) {
+            t.Fatalf("%d moves are undoable instead of %d", b.UndoableMoves(), len(copies))
+        }
+        for i := len(copies)-1; i >= 0; i-- {
+            if err := b.Undo(); err != nil {
+                t.Fatalf("undo failed: %s", err.String())
+            }
+            if diff := boardDifference(b, copies[i]); diff != "" {
+                t.Fatalf("the %s differs after taking back move %d under %s rules", diff, i, rules.Name)
+            }
+        }
+        if b.Undo() == nil {
+            t.Fatalf("a move is taken back on the empty board")
+        }
+    }
+}
+
+func TestUndoRandomGames(t *testing.T) {
+    testUndoRandomGames(t, ChineseRules)
+    testUndoRandomGames(t, NewZealandRules)
+}
+
+// Taking back a move and playing it again has to give the same state as playing it once.
+func TestUndoAndReplay(t *testing.T) {
+    b := NewBoard(9)
+    for i := 0; i < 60; i++ {
+        b.PlayRandomMove(b.ColorOfNextPlay())
+    }
+    updateAllLegalities(b)
+    before := b.Copy()
+    v := b.PlayRandomMove(b.ColorOfNextPlay())
+    after := b.Copy()
+    b.Undo()
+    if diff := boardDifference(b, before); diff != "" {
+        t.Fatalf("the %s differs after taking back the move", diff)
+    }
+    if v.Pass {
+        b.PlayPass(b.ColorOfNextPlay())
+    } else {
+        b.PlayMove(v.X, v.Y, b.ColorOfNextPlay())
+    }
+    if diff := boardDifference(b, after); diff != "" {
+        t.Fatalf("the %s differs after playing the move again", diff)
+    }
+}
+
+// Copies share the cached actions of the board they are copied from. Plays a random game, copies the board after
+// each move and replays the rest of the game on early copies, and on copies of them, after the original board has
+// changed. The states have to be the same as the ones of the copies made during the game.
+func TestReplayOnCopies(t *testing.T) {
+    b := NewBoard(9)
+    moves := make([]Vertex, 0, 200)
+    copies := make([]*Board, 0, 201)
+    updateAllLegalities(b)
+    copies = copies[0:1]
+    copies[0] = b.Copy()
+    for len(moves) < cap(moves) {
+        v := b.PlayRandomMove(b.ColorOfNextPlay())
+        moves = moves[0:len(moves)+1]
+        moves[len(moves)-1] = v
+        updateAllLegalities(b)
+        copies = copies[0:len(copies)+1]
+        copies[len(copies)-1] = b.Copy()
+    }
+    for start := 0; start < len(moves); start += 40 {
+        cpy := copies[start].Copy()
+        for i := start; i < len(moves); i++ {
+            if moves[i].Pass {
+                cpy.PlayPass(cpy.ColorOfNextPlay())
+            } else {
+                cpy.PlayMove(moves[i].X, moves[i].Y, cpy.ColorOfNextPlay())
+            }
+            updateAllLegalities(cpy)
+            if diff := boardDifference(cpy, copies[i+1]); diff != "" {
+                t.Fatalf("the %s differs after replaying move %d from move %d", diff, i, start)
+            }
+            if i % 10 == 0 {
+                // continue on a copy of the copy
+                cpy = cpy.Copy()
+            }
+        }
+    }
+}
+
+// A board which is filled again by copyInto, as the playouts do, has to behave like a copy. Fills one board with
+// the positions of a random game, each time after it has played on, and plays the same moves on it and on a copy.
+func TestCopyInto(t *testing.T) {
+    b := NewBoard(9)
+    playout := b.Copy()
+    for n := 0; n < 150; n++ {
+        updateAllLegalities(b)
+        b.copyInto(playout)
+        cpy := b.Copy()
+        if diff := boardDifference(playout, cpy); diff != "" {
+            t.Fatalf("the %s differs after filling the board with move %d", diff, n)
+        }
+        for i := 0; i < 5; i++ {
+            v := cpy.PlayRandomMove(cpy.ColorOfNextPlay())
+            if v.Pass {
+                playout.PlayPass(playout.ColorOfNextPlay())
+            } else {
+                playout.PlayMove(v.X, v.Y, playout.ColorOfNextPlay())
+            }
+            updateAllLegalities(cpy)
+            updateAllLegalities(playout)
+            if diff := boardDifference(playout, cpy); diff != "" {
+                t.Fatalf("the %s differs after playing %d moves on the board filled with move %d", diff, i+1, n)
+            }
+        }
+        b.PlayRandomMove(b.ColorOfNextPlay())
+    }
+}
+
+// Changing the suicide rule keeps the journal, and the moves which are taken back afterwards get the
+// legalities of the current rule. A black move at (1,0) commits suicide with two stones.
+//
+//   2 . . . . .
+//   1 O O . . .
+//   0 X . O . X
+//     0 1 2 3 4
+func TestUndoAfterRuleChange(t *testing.T) {
+    b := NewBoard(5)
+    b.PlayMove(0, 0, Black)
+    b.PlayMove(0, 1, White)
+    b.PlayMove(1, 1, White)
+    b.PlayMove(2, 0, White)
+    pos := b.xyToPos(1, 0)
+    if b.IsLegalMove(pos, Black) {
+        t.Fatalf("the suicide is legal by default")
+    }
+    b.PlayMove(4, 0, Black)
+    b.SetRules(NewZealandRules)
+    if b.UndoableMoves() != 5 {
+        t.Fatalf("%d moves are undoable after changing the rules instead of 5", b.UndoableMoves())
+    }
+    if err := b.Undo(); err != nil {
+        t.Fatalf("undo failed: %s", err.String())
+    }
+    if !b.IsLegalMove(pos, Black) {
+        t.Fatalf("the suicide is illegal under new zealand rules after taking back a move of the old rules")
+    }
+    b.SetRules(DefaultRules)
+    if err := b.Undo(); err != nil {
+        t.Fatalf("undo failed: %s", err.String())
+    }
+    if b.fields[b.xyToPos(2, 0)] != nil || !b.IsLegalMove(pos, Black) {
+        t.Fatalf("the white stone at (2,0) is not taken back correctly")
+    }
+}
+
+// A group lives at its first stone, so a stone which is played where a captured stone was gets the place of
+// the captured group. Taking both back has to restore the captured group. White captures at (0,0) by playing
+// at (0,1), black captures at (1,0) by playing at (0,0) again, which gives the new stone another liberty.
+//
+//   1 O X . . .
+//   0 X O X . .
+//     0 1 2 3 4
+func TestUndoCaptureAndReplay(t *testing.T) {
+    b := NewBoard(5)
+    b.PlayMove(0, 0, Black)
+    b.PlayMove(1, 0, White)
+    b.PlayMove(2, 0, Black)
+    updateAllLegalities(b)
+    before := b.Copy()
+    b.PlayMove(0, 1, White)
+    b.PlayMove(1, 1, Black)
+    b.PlayMove(4, 4, White)
+    if err := b.PlayMove(0, 0, Black); err != nil || b.fields[b.xyToPos(1, 0)] != nil {
+        t.Fatalf("black does not capture at (1,0)")
+    }
+    for i := 0; i < 4; i++ {
+        b.Undo()
+    }
+    if diff := boardDifference(b, before); diff != "" {
+        t.Fatalf("the %s differs after taking back the captures", diff)
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestUndoRandomGames", TestUndoRandomGames},
+        testing.Test{"TestUndoAndReplay", TestUndoAndReplay},
+        testing.Test{"TestReplayOnCopies", TestReplayOnCopies},
+        testing.Test{"TestCopyInto", TestCopyInto},
+        testing.Test{"TestUndoAfterRuleChange", TestUndoAfterRuleChange},
+        testing.Test{"TestUndoCaptureAndReplay", TestUndoCaptureAndReplay},
+    }
+}
@@ -0,0 +1,74 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "testing"
+)
+
+// A white stone in the center of a 9x9 board which is chased towards the lower right corner.
+func newLadderBoard() *Board {
+    b := NewBoard(9)
+    b.PlayMove(4, 4, White)
+    b.PlayMove(3, 4, Black)
+    b.PlayMove(4, 5, Black)
+    b.PlayMove(5, 5, Black)
+    return b
+}
+
+func TestLadderCaptured(t *testing.T) {
+    b := newLadderBoard()
+    pos := b.xyToPos(4, 4)
+    if status := b.ReadLadder(pos); status != LadderCaptured {
+        t.Fatalf("two liberties: expected LadderCaptured, got %d", status)
+    }
+    b.PlayMove(4, 3, Black)
+    if status := b.ReadLadder(pos); status != LadderCaptured {
+        t.Fatalf("atari: expected LadderCaptured, got %d", status)
+    }
+    // extending from the atari is a dead ladder, the atari was a ladder capture
+    if !b.isDeadLadderExtension(b.xyToPos(5, 4), White) {
+        t.Fatalf("extension not recognized as dead ladder")
+    }
+}
+
+func TestLadderBreaker(t *testing.T) {
+    b := newLadderBoard()
+    b.PlayMove(7, 1, White)
+    pos := b.xyToPos(4, 4)
+    if status := b.ReadLadder(pos); status != LadderEscapes {
+        t.Fatalf("two liberties: expected LadderEscapes, got %d", status)
+    }
+    b.PlayMove(4, 3, Black)
+    if status := b.ReadLadder(pos); status != LadderEscapes {
+        t.Fatalf("atari: expected LadderEscapes, got %d", status)
+    }
+    if b.isDeadLadderExtension(b.xyToPos(5, 4), White) {
+        t.Fatalf("extension recognized as dead ladder despite the ladder breaker")
+    }
+}
+
+func TestLadderDepth(t *testing.T) {
+    b := newLadderBoard()
+    defer paramLadderDepth.Set(paramLadderDepth.Default)
+    paramLadderDepth.Set(3)
+    if status := b.ReadLadder(b.xyToPos(4, 4)); status != LadderEscapes {
+        t.Fatalf("ladders longer than the depth limit have to count as escapes, got %d", status)
+    }
+    if status := b.ReadLadder(b.xyToPos(0, 0)); status != LadderNone {
+        t.Fatalf("empty field: expected LadderNone, got %d", status)
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestLadderCaptured", TestLadderCaptured},
+        testing.Test{"TestLadderBreaker", TestLadderBreaker},
+        testing.Test{"TestLadderDepth", TestLadderDepth},
+    }
+}
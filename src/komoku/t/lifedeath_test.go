@@ -0,0 +1,122 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "testing"
+)
+
+// A white group in the lower left corner with a straight three eye space at A1, B1 and C1,
+// surrounded by a black wall.
+func newStraightThreeBoard() *Board {
+    b := NewBoard(9)
+    for _, p := range []Point{ Point{0,1}, Point{1,1}, Point{2,1}, Point{3,1}, Point{3,0} } {
+        b.PlayMove(p.X, p.Y, White)
+    }
+    for _, p := range []Point{ Point{0,2}, Point{1,2}, Point{2,2}, Point{3,2}, Point{4,2}, Point{4,1}, Point{4,0} } {
+        b.PlayMove(p.X, p.Y, Black)
+    }
+    return b
+}
+
+func TestSolveStraightThree(t *testing.T) {
+    b := newStraightThreeBoard()
+    target := b.xyToPos(1, 1)
+    vital := b.xyToPos(1, 0)
+    for _, color := range []Color{ Black, White } {
+        result, line, err := b.SolveLifeAndDeath(target, color, 100000)
+        if err != nil {
+            t.Fatalf("%s to move: unexpected error %s", color, err)
+        }
+        if result != SolveWin {
+            t.Fatalf("%s to move: expected SolveWin, got %d", color, result)
+        }
+        if len(line) == 0 || line[0] != vital {
+            t.Fatalf("%s to move: the main line has to start at the vital point, got %v", color, line)
+        }
+    }
+    // the solver must not change the board
+    if b.fields[vital] != nil {
+        t.Fatalf("the board has been changed by the solver")
+    }
+}
+
+func TestSolveRegionTooLarge(t *testing.T) {
+    b := NewBoard(9)
+    b.PlayMove(4, 4, White)
+    if _, _, err := b.SolveLifeAndDeath(b.xyToPos(4, 4), Black, 1000); err == nil {
+        t.Fatalf("a group on an empty board must not be solved")
+    }
+}
+
+// With a depth limit of one move the attacker cannot kill, but this result must not be stored as a proof.
+func TestSolverCutoffNotStored(t *testing.T) {
+    b := newStraightThreeBoard()
+    target := b.xyToPos(1, 1)
+    s := &lifeAndDeathSolver{
+        root: b.Copy(),
+        target: target,
+        defender: White,
+        rootPlayer: Black,
+        region: b.lifeAndDeathRegion(target),
+        maxDepth: 1,
+        table: make(map[string]int),
+    }
+    s.root.setColorOfNextPlay(Black)
+    if result, _ := s.solve(100000); result != SolveLoss {
+        t.Fatalf("expected SolveLoss with a depth limit of 1, got %d", result)
+    }
+    if len(s.table) != 0 {
+        t.Fatalf("%d results which rely on the depth cutoff have been stored", len(s.table))
+    }
+}
+
+// A white group with a real eye at A1 and a false eye at C1. The white stones at D1 and E1 are cut off
+// by the black stone at D2 and have a single outside liberty at F1.
+func newFalseEyeBoard() *Board {
+    b := NewBoard(9)
+    for _, p := range []Point{ Point{0,1}, Point{1,1}, Point{2,1}, Point{1,0}, Point{3,0}, Point{4,0} } {
+        b.PlayMove(p.X, p.Y, White)
+    }
+    for _, p := range []Point{ Point{0,2}, Point{1,2}, Point{2,2}, Point{3,1}, Point{4,1}, Point{5,1}, Point{6,0} } {
+        b.PlayMove(p.X, p.Y, Black)
+    }
+    return b
+}
+
+// Black kills by filling F1 and capturing at C1. The false eye must not count as the second eye.
+func TestSolveFalseEye(t *testing.T) {
+    b := newFalseEyeBoard()
+    target := b.xyToPos(1, 1)
+    s := &lifeAndDeathSolver{
+        root: b,
+        target: target,
+        defender: White,
+        rootPlayer: Black,
+        region: b.lifeAndDeathRegion(target),
+    }
+    if outcome := s.evaluate(b, 0); outcome != ldOpen {
+        t.Fatalf("the group with a false eye is evaluated as %d before any search", outcome)
+    }
+    result, _, err := b.SolveLifeAndDeath(target, Black, 100000)
+    if err != nil {
+        t.Fatalf("unexpected error %s", err)
+    }
+    if result != SolveWin {
+        t.Fatalf("expected SolveWin for Black, got %d", result)
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestSolveStraightThree", TestSolveStraightThree},
+        testing.Test{"TestSolveRegionTooLarge", TestSolveRegionTooLarge},
+        testing.Test{"TestSolverCutoffNotStored", TestSolverCutoffNotStored},
+        testing.Test{"TestSolveFalseEye", TestSolveFalseEye},
+    }
+}
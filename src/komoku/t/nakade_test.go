@@ -0,0 +1,114 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "testing"
+)
+
+type nakadeCase struct {
+    name string
+    shape []Point
+    vital Point
+    isNakade bool
+}
+
+var nakadeCases = []nakadeCase{
+    nakadeCase{ "straight three", []Point{ Point{3,4}, Point{4,4}, Point{5,4} }, Point{4,4}, true },
+    nakadeCase{ "bent three", []Point{ Point{3,4}, Point{4,4}, Point{4,5} }, Point{4,4}, true },
+    nakadeCase{ "pyramid four", []Point{ Point{3,4}, Point{4,4}, Point{5,4}, Point{4,5} }, Point{4,4}, true },
+    nakadeCase{ "bulky five", []Point{ Point{3,4}, Point{4,4}, Point{3,5}, Point{4,5}, Point{5,4} }, Point{4,4}, true },
+    nakadeCase{ "crossed five", []Point{ Point{4,4}, Point{3,4}, Point{5,4}, Point{4,3}, Point{4,5} }, Point{4,4}, true },
+    nakadeCase{ "rabbity six", []Point{ Point{4,4}, Point{3,4}, Point{5,4}, Point{4,3}, Point{4,5}, Point{5,5} }, Point{4,4}, true },
+    nakadeCase{ "straight four", []Point{ Point{2,4}, Point{3,4}, Point{4,4}, Point{5,4} }, Point{0,0}, false },
+    nakadeCase{ "square four", []Point{ Point{3,4}, Point{4,4}, Point{3,5}, Point{4,5} }, Point{0,0}, false },
+    nakadeCase{ "two points", []Point{ Point{3,4}, Point{4,4} }, Point{0,0}, false },
+}
+
+// Returns a 9x9 board on which white stones enclose the empty points 'shape'. The last move is a
+// white stone next to the shape.
+func newNakadeBoard(shape []Point) *Board {
+    b := NewBoard(9)
+    inShape := make(map[int]bool)
+    for _, p := range shape {
+        inShape[b.xyToPos(p.X, p.Y)] = true
+    }
+    for _, p := range shape {
+        for _, npos := range b.neighboursByPos(b.xyToPos(p.X, p.Y)) {
+            if !inShape[npos] && b.fields[npos] == nil {
+                b.playMoveByPos(npos, White)
+            }
+        }
+    }
+    return b
+}
+
+func TestNakadeShapes(t *testing.T) {
+    for _, c := range nakadeCases {
+        b := newNakadeBoard(c.shape)
+        for _, p := range c.shape {
+            vital, owner, empty, found := b.nakadeAt(b.xyToPos(p.X, p.Y))
+            if found != c.isNakade {
+                t.Fatalf("%s: nakadeAt(%d,%d) returns found == %v", c.name, p.X, p.Y, found)
+            }
+            if !found {
+                continue
+            }
+            if vital != b.xyToPos(c.vital.X, c.vital.Y) || owner != White || !empty {
+                x, y := b.posToXY(vital)
+                t.Fatalf("%s: nakadeAt(%d,%d) returns (%d,%d) owned by %s, empty == %v", c.name, p.X, p.Y, x, y, owner, empty)
+            }
+        }
+    }
+}
+
+// Both players play the vital point in playouts: black kills, white makes two eyes.
+func TestNakadePlayout(t *testing.T) {
+    for _, c := range nakadeCases {
+        if !c.isNakade {
+            continue
+        }
+        for _, color := range []Color{ Black, White } {
+            b := newNakadeBoard(c.shape)
+            v := b.PlayRandomMove(color)
+            if v.Pass || v.X != c.vital.X || v.Y != c.vital.Y {
+                t.Fatalf("%s: %s plays (%d,%d) instead of the vital point", c.name, color, v.X, v.Y)
+            }
+        }
+    }
+}
+
+// The vital point of a shape which already contains stones of the attacker is still played by the
+// attacker and never counts as an eye.
+func TestNakadeWithStones(t *testing.T) {
+    c := nakadeCases[3] // bulky five
+    b := newNakadeBoard(c.shape)
+    b.PlayMove(3, 5, Black)
+    b.PlayMove(3, 4, Black)
+    vital := b.xyToPos(c.vital.X, c.vital.Y)
+    if !b.isNakadeVitalPoint(vital) {
+        t.Fatalf("the vital point of the bulky five is not recognized")
+    }
+    if pos, found := b.nakadeMove(Black); !found || pos != vital {
+        t.Fatalf("black does not play the vital point of the bulky five")
+    }
+    if _, found := b.nakadeMove(White); found {
+        t.Fatalf("white plays inside its shape although it contains black stones")
+    }
+    if b.isEyeFillingMove(vital, White) || b.isEyeFillingMove(vital, Black) {
+        t.Fatalf("the vital point of the bulky five is considered to be an eye")
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestNakadeShapes", TestNakadeShapes},
+        testing.Test{"TestNakadePlayout", TestNakadePlayout},
+        testing.Test{"TestNakadeWithStones", TestNakadeWithStones},
+    }
+}
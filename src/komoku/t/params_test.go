@@ -0,0 +1,53 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "os"
+    "testing"
+)
+
+func TestParameterSet(t *testing.T) {
+    p := registerParameter("test.int", ParamInt, 3, 1, 10)
+    if p.Int() != 3 {
+        t.Fatalf("wrong default value %d", p.Int())
+    }
+    if err := p.Set(4.6); err != nil || p.Int() != 5 {
+        t.Fatalf("integer parameters have to be rounded, got %d (%v)", p.Int(), err)
+    }
+    if err := p.Set(11); err == nil || p.Int() != 5 {
+        t.Fatalf("out of range value accepted")
+    }
+    if q, ok := GetParameter("test.int"); !ok || q != p {
+        t.Fatalf("GetParameter does not find registered parameter")
+    }
+}
+
+func TestSaveLoadParameters(t *testing.T) {
+    filename := "params_test.tmp"
+    defer os.Remove(filename)
+    p := registerParameter("test.float", ParamFloat, 0.5, 0, 1)
+    p.Set(0.25)
+    if err := SaveParameters(filename); err != nil {
+        t.Fatalf("SaveParameters failed: %s", err)
+    }
+    p.Set(0.75)
+    if err := LoadParameters(filename); err != nil {
+        t.Fatalf("LoadParameters failed: %s", err)
+    }
+    if p.Float() != 0.25 {
+        t.Fatalf("expected 0.25 after loading, got %f", p.Float())
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestParameterSet", TestParameterSet},
+        testing.Test{"TestSaveLoadParameters", TestSaveLoadParameters},
+    }
+}
@@ -0,0 +1,181 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "testing"
+)
+
+const testSGF = "(;GM[1]FF[4]SZ[9]KM[6.5]AB[cc:cd]AW[gg];W[ee];B[dg]\n(;W[]C[a comment with \\] in it];B[tt])(;W[aa]))"
+
+func TestParseSGF(t *testing.T) {
+    game, err := ParseSGF(testSGF)
+    if err != nil {
+        t.Fatalf("ParseSGF failed: %s", err)
+    }
+    if game.BoardSize != 9 || game.Komi != 6.5 {
+        t.Fatalf("wrong board size or komi: %d, %f", game.BoardSize, game.Komi)
+    }
+    if len(game.Setup) != 3 {
+        t.Fatalf("expected 3 setup stones, got %d", len(game.Setup))
+    }
+    // only the main line is read
+    if len(game.Moves) != 4 {
+        t.Fatalf("expected 4 moves, got %d", len(game.Moves))
+    }
+    // "ee" is the center, "dg" is D3
+    if m := game.Moves[0]; m.Color != White || m.Vertex.Pass || m.Vertex.X != 4 || m.Vertex.Y != 4 {
+        t.Fatalf("wrong first move: %v", m)
+    }
+    if m := game.Moves[1]; m.Color != Black || m.Vertex.X != 3 || m.Vertex.Y != 2 {
+        t.Fatalf("wrong second move: %v", m)
+    }
+    if !game.Moves[2].Vertex.Pass || !game.Moves[3].Vertex.Pass {
+        t.Fatalf("empty value and 'tt' have to be passes")
+    }
+    if _, err := ParseSGF("(;SZ[9];B[ee"); err == nil {
+        t.Fatalf("unterminated value not detected")
+    }
+}
+
+// Boards larger than 26x26 use the capital letters for the columns and rows beyond z.
+func TestParseLargeSGF(t *testing.T) {
+    game, err := ParseSGF("(;SZ[52];B[aA];W[ZZ])")
+    if err != nil {
+        t.Fatalf("ParseSGF failed: %s", err)
+    }
+    if m := game.Moves[0]; m.Vertex.X != 0 || m.Vertex.Y != 25 {
+        t.Fatalf("'aA' is read as (%d,%d) instead of (0,25)", m.Vertex.X, m.Vertex.Y)
+    }
+    if m := game.Moves[1]; m.Vertex.X != 51 || m.Vertex.Y != 0 {
+        t.Fatalf("'ZZ' is read as (%d,%d) instead of (51,0)", m.Vertex.X, m.Vertex.Y)
+    }
+    if c := pointToSGFCoordinate(Point{ 27, 30 }, 52); c != "Bv" {
+        t.Fatalf("(27,30) is written as '%s' instead of 'Bv'", c)
+    }
+    if _, err := ParseSGF("(;SZ[53];B[aa])"); err == nil {
+        t.Fatalf("a board larger than MaxBoardSize is accepted")
+    }
+}
+
+// The properties of a node may come in any order, so the points before SZ are on the board given by SZ.
+func TestParseSGFSizeAfterPoints(t *testing.T) {
+    game, err := ParseSGF("(;AB[aa]B[ii]SZ[9];W[tt])")
+    if err != nil {
+        t.Fatalf("ParseSGF failed: %s", err)
+    }
+    if p := game.Setup[0].Vertex; p.X != 0 || p.Y != 8 {
+        t.Fatalf("'aa' before SZ[9] is read as (%d,%d) instead of (0,8)", p.X, p.Y)
+    }
+    if p := game.Moves[0].Vertex; p.Pass || p.X != 8 || p.Y != 0 {
+        t.Fatalf("'ii' before SZ[9] is read as (%d,%d) instead of (8,0)", p.X, p.Y)
+    }
+    if !game.Moves[1].Vertex.Pass {
+        t.Fatalf("'tt' is not read as a pass on the 9x9 board")
+    }
+    if _, err := ParseSGF("(;AB[kk]SZ[9])"); err == nil {
+        t.Fatalf("a setup stone outside the board is accepted")
+    }
+    if _, err := ParseSGF("(;B[ss]SZ[9])"); err == nil {
+        t.Fatalf("a move outside the board is accepted")
+    }
+}
+
+// Symmetric positions have to yield the same pattern features.
+func TestCanonicalPattern(t *testing.T) {
+    b1 := NewBoard(9)
+    b1.PlayMove(2, 3, Black)
+    b1.PlayMove(3, 4, White)
+    b2 := NewBoard(9)
+    // b1 rotated by 90 degrees around the move
+    b2.PlayMove(8-3, 2, Black)
+    b2.PlayMove(8-4, 3, White)
+    f1 := b1.moveFeatures(b1.xyToPos(3, 3), Black)
+    f2 := b2.moveFeatures(b2.xyToPos(8-3, 3), Black)
+    if len(f1) != len(f2) {
+        t.Fatalf("different numbers of features: %d and %d", len(f1), len(f2))
+    }
+    for i := 0; i < 2; i++ {
+        if f1[i] != f2[i] {
+            t.Fatalf("pattern %d differs: %x and %x", i, f1[i], f2[i])
+        }
+    }
+    // a different pattern has to yield a different code
+    f3 := b1.moveFeatures(b1.xyToPos(5, 5), Black)
+    if f3[0] == f1[0] {
+        t.Fatalf("empty and non-empty 3x3 patterns share a code")
+    }
+}
+
+// A feature which is always played has to become stronger than a feature which never is.
+func TestMMTrainer(t *testing.T) {
+    game, err := ParseSGF("(;SZ[5];B[aa];W[ee];B[ae];W[ea])")
+    if err != nil {
+        t.Fatalf("ParseSGF failed: %s", err)
+    }
+    trainer := NewMMTrainer()
+    if added := trainer.AddGame(game); added != 4 {
+        t.Fatalf("expected 4 positions, got %d", added)
+    }
+    for i := 0; i < 10; i++ {
+        trainer.Iterate()
+    }
+    weights := trainer.Weights()
+    corner := weights.Gamma(featureKey(FeatureLine, 1))
+    center := weights.Gamma(featureKey(FeatureLine, 3))
+    if corner <= center {
+        t.Fatalf("first line should be stronger than the third line: %f <= %f", corner, center)
+    }
+}
+
+// The strengths which the playout policy keeps from one move to the next have to be those of the current
+// position, also if it has not seen some of the moves or is handed a different board.
+func TestPatternPolicy(t *testing.T) {
+    w := NewPatternWeights()
+    w.SetGamma(featureKey(FeatureCapture, 1), 30)
+    w.SetGamma(featureKey(FeatureAtari, 1), 5)
+    w.SetGamma(featureKey(FeatureLine, 1), 0.2)
+    w.SetGamma(featureKey(FeatureDistPrev, 2), 8)
+    policy := newPatternPolicy(w)
+    b := NewBoard(9)
+    for i := 0; i < 200; i++ {
+        color := b.ColorOfNextPlay()
+        if i % 7 == 3 {
+            b.PlayRandomMove(color)
+            b.PlayRandomMove(!color)
+            b = b.Copy()
+        } else {
+            policy.PlayMove(b, color)
+        }
+        policy.sync(b)
+        for pos, grp := range b.fields {
+            for _, c := range []Color{ Black, White } {
+                strength := float(0.0)
+                if grp == nil && b.isLegalIgnoringSuperko(pos, c) && !b.isUnwantedPlayoutMove(pos, c) {
+                    strength = w.localStrength(b, pos, c)
+                }
+                if got := policy.strength[policyIndex(c)][pos]; got != strength {
+                    x, y := b.posToXY(pos)
+                    PrintBoard(b)
+                    t.Fatalf("move %d: strength of %s at (%d,%d) is %f instead of %f", i, c, x, y, got, strength)
+                }
+            }
+        }
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestParseSGF", TestParseSGF},
+        testing.Test{"TestParseLargeSGF", TestParseLargeSGF},
+        testing.Test{"TestParseSGFSizeAfterPoints", TestParseSGFSizeAfterPoints},
+        testing.Test{"TestCanonicalPattern", TestCanonicalPattern},
+        testing.Test{"TestMMTrainer", TestMMTrainer},
+        testing.Test{"TestPatternPolicy", TestPatternPolicy},
+    }
+}
@@ -0,0 +1,219 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "testing"
+)
+
+// Black has captured one white stone at (0,0), white has played a stone into its own area at (4,0).
+//
+//   4 . X . O .
+//   3 . X . O .
+//   2 . X . O .
+//   1 X X . O .
+//   0 . X . O O
+//     0 1 2 3 4
+func newRulesBoard() *Board {
+    b := NewBoard(5)
+    for y := 0; y < 5; y++ {
+        b.PlayMove(1, y, Black)
+        b.PlayMove(3, y, White)
+    }
+    b.PlayMove(0, 0, White)
+    b.PlayMove(0, 1, Black)
+    b.PlayMove(4, 0, White)
+    return b
+}
+
+func TestRulesetByName(t *testing.T) {
+    for _, name := range []string{ "chinese", "japanese", "aga", "new_zealand", "tromp-taylor" } {
+        r, ok := RulesetByName(name)
+        if !ok || r.Name != name {
+            t.Fatalf("the ruleset %s is not found", name)
+        }
+    }
+    if _, ok := RulesetByName("ing"); ok {
+        t.Fatalf("an unknown ruleset is found")
+    }
+    if c := ChineseRules.HandicapCompensation(3); c != 3 {
+        t.Fatalf("chinese rules compensate 3 handicap stones with %g points instead of 3", c)
+    }
+    if c := AGARules.HandicapCompensation(3); c != 0 {
+        t.Fatalf("aga rules compensate 3 handicap stones with %g points instead of 0", c)
+    }
+    if c := JapaneseRules.HandicapCompensation(3); c != 0 {
+        t.Fatalf("japanese rules compensate 3 handicap stones with %g points instead of 0", c)
+    }
+}
+
+func TestScoreDifference(t *testing.T) {
+    b := newRulesBoard()
+    // 10 points each by area, 4+1 for black and 4 for white by territory
+    if score := ChineseRules.ScoreDifference(b, 0.5, 0, nil); score != -0.5 {
+        t.Fatalf("the area score is %g instead of -0.5", score)
+    }
+    if score := ChineseRules.ScoreDifference(b, 0.5, 3, nil); score != -3.5 {
+        t.Fatalf("the area score with 3 handicap stones is %g instead of -3.5", score)
+    }
+    if score := JapaneseRules.ScoreDifference(b, 0.5, 0, nil); score != 0.5 {
+        t.Fatalf("the territory score is %g instead of 0.5", score)
+    }
+    // the pass stone makes counting the territory equal to counting the area
+    b.SetRules(AGARules)
+    b.PlayPass(Black)
+    if score := AGARules.ScoreDifference(b, 0.5, 0, nil); score != -0.5 {
+        t.Fatalf("the territory score with a pass stone is %g instead of -0.5", score)
+    }
+    // the handicap stones are not counted by territory, so black must not be charged for them
+    if score := AGARules.ScoreDifference(b, 0.5, 3, nil); score != -0.5 {
+        t.Fatalf("the territory score with a pass stone and 3 handicap stones is %g instead of -0.5", score)
+    }
+}
+
+// A white stone at (0,3) has invaded the black area of newRulesBoard. Marked as dead, it is removed and
+// counts as a prisoner for black.
+func TestDeadStones(t *testing.T) {
+    b := newRulesBoard()
+    b.PlayMove(0, 3, White)
+    // only (0,0) is black territory while the invader lives
+    if black, white := b.TerritoryScore(nil, false); black != 1+1 || white != 4 {
+        t.Fatalf("the territory score is (b%d,w%d) instead of (b2,w4)", black, white)
+    }
+    dead := []int{ b.xyToPos(0, 3), b.xyToPos(2, 2) }
+    if black, white := b.TerritoryScore(dead, false); black != 4+1+1 || white != 4 {
+        t.Fatalf("the territory score with a dead stone is (b%d,w%d) instead of (b6,w4)", black, white)
+    }
+    e := NewEnvironment(5)
+    e.SetBoard(b)
+    e.SetKomi(0.5)
+    e.Game.SetDeadStones(dead)
+    if score := e.FinalScoreDifference(); score != -0.5 {
+        t.Fatalf("the final area score is %g instead of -0.5", score)
+    }
+    e.SetRules(JapaneseRules)
+    if score := e.FinalScoreDifference(); score != 1.5 {
+        t.Fatalf("the final territory score is %g instead of 1.5", score)
+    }
+    e.Game.PlayPass(Black)
+    if len(e.Game.DeadStones()) != 0 {
+        t.Fatalf("the dead stones are kept after a pass")
+    }
+    e.Game.SetDeadStones(dead)
+    e.Game.Undo()
+    if len(e.Game.DeadStones()) != 0 {
+        t.Fatalf("the dead stones are kept after an undo")
+    }
+    e.Game.SetDeadStones(dead)
+    e.SetBoard(NewBoard(5))
+    if len(e.Game.DeadStones()) != 0 {
+        t.Fatalf("the dead stones are kept for a new board")
+    }
+}
+
+// A seki on the whole board: each group has a one point eye and they share the liberty (2,2). The eyes
+// only count as territory if the ruleset says so.
+//
+//   4 X X X O O
+//   3 X X X O O
+//   2 X X . O O
+//   1 X X X O O
+//   0 . X X O .
+//     0 1 2 3 4
+func TestSekiTerritory(t *testing.T) {
+    b := NewBoard(5)
+    for y := 0; y < 5; y++ {
+        for x := 0; x < 5; x++ {
+            switch {
+                case (x == 0 && y == 0) || (x == 4 && y == 0) || (x == 2 && y == 2):
+                case x < 3:
+                    b.PlayMove(x, y, Black)
+                default:
+                    b.PlayMove(x, y, White)
+            }
+        }
+    }
+    if !b.isSekiPoint(b.xyToPos(2, 2)) {
+        t.Fatalf("the seki is not recognized")
+    }
+    if black, white := b.TerritoryScore(nil, false); black != 0 || white != 0 {
+        t.Fatalf("the territory score without seki territory is (b%d,w%d) instead of (b0,w0)", black, white)
+    }
+    if black, white := b.TerritoryScore(nil, true); black != 1 || white != 1 {
+        t.Fatalf("the territory score with seki territory is (b%d,w%d) instead of (b1,w1)", black, white)
+    }
+    if black, white := b.AreaScore(); black != 13+1 || white != 9+1 {
+        t.Fatalf("the area score is (b%d,w%d) instead of (b14,w10)", black, white)
+    }
+}
+
+func TestSetRules(t *testing.T) {
+    e := NewEnvironment(9)
+    if e.Game.Board.Superko() != NoSuperko {
+        t.Fatalf("the default rules do not use the simple ko rule")
+    }
+    e.SetRules(ChineseRules)
+    if e.Game.Board.Superko() != PositionalSuperko {
+        t.Fatalf("the superko rule of the chinese rules is not applied to the board")
+    }
+    e.SetBoard(NewBoard(9))
+    if e.Game.Board.Superko() != PositionalSuperko {
+        t.Fatalf("the rules are not applied to a new board")
+    }
+}
+
+// A black move at (1,0) commits suicide with two stones.
+//
+//   2 . . . . .
+//   1 O O . . .
+//   0 X . O . .
+//     0 1 2 3 4
+func TestMultiStoneSuicide(t *testing.T) {
+    b := NewBoard(5)
+    b.PlayMove(0, 0, Black)
+    b.PlayMove(0, 1, White)
+    b.PlayMove(1, 1, White)
+    b.PlayMove(2, 0, White)
+    pos := b.xyToPos(1, 0)
+    if b.IsLegalMove(pos, Black) {
+        t.Fatalf("the suicide is legal by default")
+    }
+    b.SetRules(NewZealandRules)
+    if !b.IsLegalMove(pos, Black) {
+        t.Fatalf("the suicide is illegal under new zealand rules")
+    }
+    if err := b.PlayMove(1, 0, Black); err != nil {
+        t.Fatalf("the suicide is not played: %s", err.String())
+    }
+    if b.fields[b.xyToPos(0, 0)] != nil || b.fields[pos] != nil {
+        t.Fatalf("the suicided stones are not removed")
+    }
+    if prisonersBlack, _ := b.numberOfPrisoners(); prisonersBlack != 2 {
+        t.Fatalf("%d black prisoners instead of 2", prisonersBlack)
+    }
+    if b.Hash() != b.computeHash() {
+        t.Fatalf("the hash is not updated by the suicide")
+    }
+    if b.fields[b.xyToPos(0, 1)].NumLiberties() != 5 {
+        t.Fatalf("the liberties of the white group are not updated by the suicide")
+    }
+    if !b.IsLegalMove(b.xyToPos(0, 0), White) || !b.IsLegalMove(b.xyToPos(0, 0), Black) {
+        t.Fatalf("the fields of the suicided stones are not legal again")
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestRulesetByName", TestRulesetByName},
+        testing.Test{"TestScoreDifference", TestScoreDifference},
+        testing.Test{"TestDeadStones", TestDeadStones},
+        testing.Test{"TestSekiTerritory", TestSekiTerritory},
+        testing.Test{"TestSetRules", TestSetRules},
+        testing.Test{"TestMultiStoneSuicide", TestMultiStoneSuicide},
+    }
+}
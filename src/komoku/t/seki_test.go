@@ -0,0 +1,135 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "testing"
+)
+
+// A seki in the lower left corner: the black group and the three white stones inside share the
+// liberties A0 and D1. A self-atari of white on either point leaves black a living eye of four points,
+// a self-atari of black gives away the whole group. The outer white group lives with the eyes B5 and E5.
+//
+//   5 O . O O . O
+//   4 O O O O O O
+//   3 O O O O O O
+//   2 X X X X X O
+//   1 O O O . X O
+//   0 . X X X X O
+//     0 1 2 3 4 5
+func newSekiBoard() *Board {
+    b := NewBoard(6)
+    for _, p := range []Point{ Point{0,2}, Point{1,2}, Point{2,2}, Point{3,2}, Point{4,2}, Point{4,1},
+                               Point{1,0}, Point{2,0}, Point{3,0}, Point{4,0} } {
+        b.PlayMove(p.X, p.Y, Black)
+    }
+    for _, p := range []Point{ Point{0,1}, Point{1,1}, Point{2,1}, Point{5,0}, Point{5,1}, Point{5,2} } {
+        b.PlayMove(p.X, p.Y, White)
+    }
+    for y := 3; y < 6; y++ {
+        for x := 0; x < 6; x++ {
+            if y != 5 || (x != 1 && x != 4) {
+                b.PlayMove(x, y, White)
+            }
+        }
+    }
+    return b
+}
+
+func TestSekiPoints(t *testing.T) {
+    b := newSekiBoard()
+    for _, p := range []Point{ Point{0,0}, Point{3,1} } {
+        pos := b.xyToPos(p.X, p.Y)
+        if !b.isSekiPoint(pos) {
+            t.Fatalf("(%d,%d) is not recognized as a seki point", p.X, p.Y)
+        }
+        if !b.isUnwantedPlayoutMove(pos, Black) || !b.isUnwantedPlayoutMove(pos, White) {
+            t.Fatalf("playouts would break the seki at (%d,%d)", p.X, p.Y)
+        }
+    }
+    for _, p := range []Point{ Point{1,5}, Point{4,5} } {
+        if b.isSekiPoint(b.xyToPos(p.X, p.Y)) {
+            t.Fatalf("(%d,%d) is recognized as a seki point", p.X, p.Y)
+        }
+    }
+}
+
+// A white group with a straight two eye in the corner, enclosed by black, is dead: a move of either color
+// in the eye is a self-atari, but black kills with it.
+//
+//   2 X X X X .
+//   1 O O O X .
+//   0 . . O X .
+//     0 1 2 3 4
+func TestStraightTwoIsNoSeki(t *testing.T) {
+    b := NewBoard(5)
+    for _, p := range []Point{ Point{0,2}, Point{1,2}, Point{2,2}, Point{3,2}, Point{3,1}, Point{3,0} } {
+        b.PlayMove(p.X, p.Y, Black)
+    }
+    for _, p := range []Point{ Point{0,1}, Point{1,1}, Point{2,1}, Point{2,0} } {
+        b.PlayMove(p.X, p.Y, White)
+    }
+    for _, p := range []Point{ Point{0,0}, Point{1,0} } {
+        if b.isSekiPoint(b.xyToPos(p.X, p.Y)) {
+            t.Fatalf("the point (%d,%d) of a straight two eye is recognized as a seki point", p.X, p.Y)
+        }
+    }
+}
+
+// After black has taken the vital point of a bent three eye, the remaining points are no seki points
+// either, because the two black stones leave a straight two eye when white captures them.
+//
+//   3 X X X . .
+//   2 O O X . .
+//   1 . O O X .
+//   0 X . O X .
+//     0 1 2 3 4
+func TestBentThreeIsNoSeki(t *testing.T) {
+    b := NewBoard(5)
+    for _, p := range []Point{ Point{0,3}, Point{1,3}, Point{2,3}, Point{2,2}, Point{3,1}, Point{3,0} } {
+        b.PlayMove(p.X, p.Y, Black)
+    }
+    for _, p := range []Point{ Point{0,2}, Point{1,2}, Point{1,1}, Point{2,1}, Point{2,0} } {
+        b.PlayMove(p.X, p.Y, White)
+    }
+    b.PlayMove(0, 0, Black)
+    for _, p := range []Point{ Point{0,1}, Point{1,0} } {
+        if b.isSekiPoint(b.xyToPos(p.X, p.Y)) {
+            t.Fatalf("the point (%d,%d) of a bent three eye is recognized as a seki point", p.X, p.Y)
+        }
+    }
+}
+
+// Playouts have to leave the seki alone and the shared liberties count for nobody.
+func TestSekiPlayout(t *testing.T) {
+    b := newSekiBoard()
+    lastPass := false
+    for i := 0; i < 20; i++ {
+        v := b.PlayRandomMove(b.ColorOfNextPlay())
+        if v.Pass && lastPass {
+            break
+        }
+        lastPass = v.Pass
+    }
+    if b.fields[b.xyToPos(0,0)] != nil || b.fields[b.xyToPos(3,1)] != nil {
+        t.Fatalf("the playout has broken the seki")
+    }
+    black, _ := b.AreaScore()
+    if stonesBlack, _ := b.numberOfStones(); black != stonesBlack {
+        t.Fatalf("black has no territory in seki, but AreaScore returns %d for %d stones", black, stonesBlack)
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestSekiPoints", TestSekiPoints},
+        testing.Test{"TestStraightTwoIsNoSeki", TestStraightTwoIsNoSeki},
+        testing.Test{"TestBentThreeIsNoSeki", TestBentThreeIsNoSeki},
+        testing.Test{"TestSekiPlayout", TestSekiPlayout},
+    }
+}
@@ -0,0 +1,94 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "testing"
+)
+
+// A black wall at x=2 and a white wall at x=3 on a 9x9 board. The white stone at (0,4) is dead inside the
+// black area.
+func TestDeadStoneStatus(t *testing.T) {
+    ai := NewAI(9)
+    board := ai.environment.Game.Board
+    for y := 0; y < 9; y++ {
+        board.PlayMove(2, y, Black)
+        board.PlayMove(3, y, White)
+    }
+    board.PlayMove(0, 4, White)
+    status := ai.FinalStatus()
+    if s := status[board.xyToPos(0, 4)]; s != StatusDead {
+        t.Fatalf("the white stone inside the black area is %s", s)
+    }
+    if status[board.xyToPos(2, 0)] != StatusAlive || status[board.xyToPos(3, 0)] != StatusAlive {
+        t.Fatalf("the walls are not alive")
+    }
+    // black: 9 stones and 18 points, white: 9 stones and 45 points
+    if score := ai.FinalScore(); score != 27 - 54 - ai.environment.komi {
+        t.Fatalf("the final score is %g instead of %g", score, 27 - 54 - ai.environment.komi)
+    }
+    if dead := ai.environment.Game.DeadStones(); len(dead) != 1 || dead[0] != board.xyToPos(0, 4) {
+        t.Fatalf("the dead stone is not marked for the game")
+    }
+}
+
+// A seki in the lower left corner, where the outer white group has two eyes.
+//
+//   5 O . O O . O
+//   4 O O O O O O
+//   3 O O O O O O
+//   2 X X X X X O
+//   1 O O O . X O
+//   0 . X X X X O
+//     0 1 2 3 4 5
+func TestSekiStatus(t *testing.T) {
+    ai := NewAI(6)
+    board := ai.environment.Game.Board
+    for _, p := range []Point{ Point{0,2}, Point{1,2}, Point{2,2}, Point{3,2}, Point{4,2}, Point{4,1},
+                               Point{1,0}, Point{2,0}, Point{3,0}, Point{4,0} } {
+        board.PlayMove(p.X, p.Y, Black)
+    }
+    for _, p := range []Point{ Point{0,1}, Point{1,1}, Point{2,1}, Point{5,0}, Point{5,1}, Point{5,2} } {
+        board.PlayMove(p.X, p.Y, White)
+    }
+    for y := 3; y < 6; y++ {
+        for x := 0; x < 6; x++ {
+            if y != 5 || (x != 1 && x != 4) {
+                board.PlayMove(x, y, White)
+            }
+        }
+    }
+    status := ai.FinalStatus()
+    for _, p := range []Point{ Point{2,2}, Point{1,1}, Point{0,1} } {
+        if s := status[board.xyToPos(p.X, p.Y)]; s != StatusSeki {
+            t.Fatalf("the stone at (%d,%d) is %s instead of seki", p.X, p.Y, s)
+        }
+    }
+    if s := status[board.xyToPos(3, 3)]; s != StatusAlive {
+        t.Fatalf("the outer white group is %s instead of alive", s)
+    }
+}
+
+func TestParseStoneStatus(t *testing.T) {
+    for _, s := range []StoneStatus{ StatusAlive, StatusDead, StatusSeki } {
+        if parsed, ok := ParseStoneStatus(s.String()); !ok || parsed != s {
+            t.Fatalf("the status %s is not parsed", s)
+        }
+    }
+    if _, ok := ParseStoneStatus("unknown"); ok {
+        t.Fatalf("an unknown status is parsed")
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestDeadStoneStatus", TestDeadStoneStatus},
+        testing.Test{"TestSekiStatus", TestSekiStatus},
+        testing.Test{"TestParseStoneStatus", TestParseStoneStatus},
+    }
+}
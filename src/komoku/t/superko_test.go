@@ -0,0 +1,118 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "testing"
+)
+
+// Three kos on the left side of a 9x9 board. In each ko, black may capture at (3,y) if a white stone
+// is at (2,y), and white may capture at (2,y) if a black stone is at (3,y).
+//
+//   8 . . X O . . . . .
+//   7 . X . X O . . . .
+//   6 . . X O . . . . .
+//   5 . . X O . . . . .
+//   4 . X O . O . . . .
+//   3 . . X O . . . . .
+//   2 . . X O . . . . .
+//   1 . X . X O . . . .
+//   0 . . X O . . . . .
+//     0 1 2 3 4 5 6 7 8
+//
+// The kos are played through a cycle with a pass in it, which repeats the stones of the initial
+// position with black instead of white to move. Returns the board before the last move of the cycle,
+// which is the white capture at (2,7). The diagram shows this board.
+func newTripleKoBoard(rule SuperkoRule, window int) *Board {
+    b := NewBoard(9)
+    b.SetSuperko(rule, window)
+    for _, y := range []int{ 1, 4, 7 } {
+        for _, p := range []Point{ Point{2,y-1}, Point{1,y}, Point{2,y+1} } {
+            b.PlayMove(p.X, p.Y, Black)
+        }
+        for _, p := range []Point{ Point{3,y-1}, Point{4,y}, Point{3,y+1} } {
+            b.PlayMove(p.X, p.Y, White)
+        }
+    }
+    b.PlayMove(2, 4, White)
+    b.PlayMove(2, 7, White)
+    b.PlayMove(3, 1, Black)
+    // white is to move
+    b.PlayMove(2, 1, White)
+    b.PlayMove(3, 4, Black)
+    b.PlayPass(White)
+    b.PlayMove(3, 7, Black)
+    b.PlayMove(2, 4, White)
+    b.PlayMove(3, 1, Black)
+    return b
+}
+
+func TestSuperkoRules(t *testing.T) {
+    pos := NewBoard(9).xyToPos(2, 7)
+    if !newTripleKoBoard(NoSuperko, 0).IsLegalMove(pos, White) {
+        t.Fatalf("without superko, the capture repeating the position is illegal")
+    }
+    if newTripleKoBoard(PositionalSuperko, 0).IsLegalMove(pos, White) {
+        t.Fatalf("positional superko allows the capture repeating the position")
+    }
+    if !newTripleKoBoard(SituationalSuperko, 0).IsLegalMove(pos, White) {
+        t.Fatalf("situational superko forbids the capture although the player to move differs")
+    }
+    if !newTripleKoBoard(PositionalSuperko, 0).IsLegalMove(NewBoard(9).xyToPos(7, 7), White) {
+        t.Fatalf("positional superko forbids a move which does not repeat a position")
+    }
+}
+
+func TestSuperkoWindowAndCopy(t *testing.T) {
+    pos := NewBoard(9).xyToPos(2, 7)
+    b := newTripleKoBoard(PositionalSuperko, 0)
+    cpy := b.Copy()
+    if cpy.IsLegalMove(pos, White) {
+        t.Fatalf("the copy of the board has lost the position history")
+    }
+    // the repeated position is seven positions back
+    cpy.SetSuperko(PositionalSuperko, 6)
+    if !cpy.IsLegalMove(pos, White) {
+        t.Fatalf("a window of 6 positions still finds the repeated position")
+    }
+    if err := cpy.PlayMove(2, 7, White); err != nil {
+        t.Fatalf("the capture is not played: %s", err.String())
+    }
+    if err := b.PlayMove(2, 7, White); err == nil {
+        t.Fatalf("the capture is played despite the positional superko")
+    }
+}
+
+// The lists of legal moves and the playouts leave out the moves which repeat a position.
+func TestSuperkoLegalMoves(t *testing.T) {
+    b := newTripleKoBoard(PositionalSuperko, 0)
+    pos := b.xyToPos(2, 7)
+    for _, p := range b.ListLegalPoints(White) {
+        if p.X == 2 && p.Y == 7 {
+            t.Fatalf("the capture repeating the position is listed as legal")
+        }
+    }
+    for i := 0; i < 50; i++ {
+        cpy := b.Copy()
+        v := cpy.PlayRandomMove(White)
+        if cpy.ColorOfNextPlay() != Black {
+            t.Fatalf("the random move (%d,%d) has not been played", v.X, v.Y)
+        }
+        if !v.Pass && cpy.xyToPos(v.X, v.Y) == pos {
+            t.Fatalf("a playout plays the capture repeating the position")
+        }
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestSuperkoRules", TestSuperkoRules},
+        testing.Test{"TestSuperkoWindowAndCopy", TestSuperkoWindowAndCopy},
+        testing.Test{"TestSuperkoLegalMoves", TestSuperkoLegalMoves},
+    }
+}
@@ -0,0 +1,90 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "testing"
+)
+
+func TestTransformXY(t *testing.T) {
+    // the images of (1,0) on a 9x9 board
+    images := []Point{ Point{1,0}, Point{7,0}, Point{1,8}, Point{7,8}, Point{0,1}, Point{8,1}, Point{0,7}, Point{8,7} }
+    for sym, p := range images {
+        if x, y := transformXY(1, 0, 9, 9, sym); x != p.X || y != p.Y {
+            t.Fatalf("symmetry %d maps (1,0) onto (%d,%d) instead of (%d,%d)", sym, x, y, p.X, p.Y)
+        }
+    }
+}
+
+func TestSymmetries(t *testing.T) {
+    b := NewBoard(9)
+    if syms := b.symmetries(); len(syms) != 8 {
+        t.Fatalf("the empty board has %d symmetries instead of 8", len(syms))
+    }
+    b.PlayMove(4, 4, Black)
+    if syms := b.symmetries(); len(syms) != 8 {
+        t.Fatalf("the board with a stone on tengen has %d symmetries instead of 8", len(syms))
+    }
+    // a stone on the diagonal leaves only the reflection along the diagonal
+    b = NewBoard(9)
+    b.PlayMove(2, 2, Black)
+    if syms := b.symmetries(); len(syms) != 2 || syms[1] != 4 {
+        t.Fatalf("the board with a stone on (2,2) has the symmetries %v instead of [0 4]", syms)
+    }
+    // the last move breaks the symmetry of symmetric stones
+    b = NewBoard(9)
+    b.PlayMove(2, 3, Black)
+    b.PlayMove(3, 2, Black)
+    if syms := b.symmetries(); len(syms) != 1 {
+        t.Fatalf("the board with the last move on (3,2) has the symmetries %v instead of [0]", syms)
+    }
+}
+
+// On the empty board, the canonical moves are the 15 points of one eighth of the board.
+func TestCanonicalMove(t *testing.T) {
+    b := NewBoard(9)
+    syms := b.symmetries()
+    canonical := make(map[int]bool)
+    for pos := 0; pos < 81; pos++ {
+        c, sym := b.canonicalMove(pos, syms)
+        if b.transformPos(pos, sym) != c {
+            t.Fatalf("the symmetry of canonicalMove does not map %d onto %d", pos, c)
+        }
+        canonical[c] = true
+    }
+    if len(canonical) != 15 {
+        t.Fatalf("there are %d canonical moves instead of 15", len(canonical))
+    }
+}
+
+// A rectangular board only has the symmetries which do not transpose. On the empty board with 7 columns
+// and 5 rows, the canonical moves are the 4x3 points of one quarter of the board.
+func TestRectangularSymmetries(t *testing.T) {
+    b := NewRectangularBoard(7, 5)
+    syms := b.symmetries()
+    if len(syms) != 4 {
+        t.Fatalf("the empty 7x5 board has the symmetries %v instead of [0 1 2 3]", syms)
+    }
+    canonical := make(map[int]bool)
+    for pos := 0; pos < 35; pos++ {
+        c, _ := b.canonicalMove(pos, syms)
+        canonical[c] = true
+    }
+    if len(canonical) != 12 {
+        t.Fatalf("there are %d canonical moves instead of 12", len(canonical))
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestTransformXY", TestTransformXY},
+        testing.Test{"TestSymmetries", TestSymmetries},
+        testing.Test{"TestCanonicalMove", TestCanonicalMove},
+        testing.Test{"TestRectangularSymmetries", TestRectangularSymmetries},
+    }
+}
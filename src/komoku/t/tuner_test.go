@@ -0,0 +1,49 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+package komoku
+
+import (
+    "rand"
+    "testing"
+)
+
+// The parameter starts at 2 in [0, 10] and the set closer to 7 wins every game. The tuner has to end up near 7.
+func TestTunerFindsOptimum(t *testing.T) {
+    p := &Parameter{ Name: "test.tuner", Kind: ParamFloat, Min: 0, Max: 10, Default: 2, value: 2 }
+    tuner := NewTuner([]*Parameter{ p })
+    tuner.rand = rand.New(rand.NewSource(1))
+    distance := func(values []float) float {
+        if values[0] > 0.7 {
+            return values[0] - 0.7
+        }
+        return 0.7 - values[0]
+    }
+    tuner.game = func(black, white []float) Color {
+        if distance(black) < distance(white) {
+            return Black
+        }
+        return White
+    }
+    // the first step moves by A of the range, not from one end of the range to the other
+    tuner.Iterate()
+    if p.Float() < 2.5 || p.Float() > 3.5 {
+        t.Fatalf("the first iteration moved the parameter from 2 to %g instead of about 3", p.Float())
+    }
+    for i := 1; i < 200; i++ {
+        tuner.Iterate()
+    }
+    if p.Float() < 6 || p.Float() > 8 {
+        t.Fatalf("the parameter is %g after 200 iterations instead of about 7", p.Float())
+    }
+}
+
+func Testsuite() []testing.Test {
+    return []testing.Test {
+        testing.Test{"TestTunerFindsOptimum", TestTunerFindsOptimum},
+    }
+}
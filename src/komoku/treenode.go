@@ -20,6 +20,10 @@ type NodeInfo struct {
     simulations int // total number of simulations that begin with this move
     wonByBlack, wonByWhite int // number of games won by {black,white}
     jigo int // number of jigos
+    proven bool // true iff the result of the game after this move is known, see AI.propagateProof
+    provenWinner Color // the winner if proven is true
+    numMoves int // number of legal moves (including the pass) in the position after this move, 0 if not known yet
+    provenChildren int // number of children which are proven
 }
 
 /*
@@ -55,6 +59,38 @@ func (t *TreeNode) Clear() {
     }
 }
 
+// Returns true if every move of the position after t has been tried and is proven to be won by 'winner'.
+// t.numMoves has to be known.
+func (t *TreeNode) allChildrenProvenFor(winner Color) bool {
+    if len(t.children) < t.numMoves {
+        return false
+    }
+    for _, child := range t.children {
+        if !child.proven || child.provenWinner != winner {
+            return false
+        }
+    }
+    return true
+}
+
+// Returns the number of nodes in the subtree of t, including t
+func (t *TreeNode) Size() int {
+    size := 1
+    for _, child := range t.children {
+        size += child.Size()
+    }
+    return size
+}
+
+// Marks t as proven to be won by 'winner'
+func (t *TreeNode) setProven(winner Color) {
+    if !t.NodeInfo.proven && t.parent != nil {
+        t.parent.NodeInfo.provenChildren++
+    }
+    t.NodeInfo.proven = true
+    t.NodeInfo.provenWinner = winner
+}
+
 // Increments the denoted scores
 func (t *TreeNode) IncrementScore(simuls, wonBlack, wonWhite, jigo int) {
     t.NodeInfo.simulations += simuls
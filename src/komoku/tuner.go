@@ -0,0 +1,183 @@
+/* 
+ * (c) 2010 by David Nies (nies.david@googlemail.com)
+ *     http://www.twitter.com/Sh4pe
+ *
+ * Use of this source code is governed by a license 
+ * that can be found in the LICENSE file.
+ */
+
+/*
+ * An SPSA (simultaneous perturbation stochastic approximation) tuner for the parameters of
+ * params.go. In every iteration, all parameters are perturbed at once in a random direction,
+ * and the two resulting parameter sets play a few fast self-play games against each other.
+ * The parameters are then moved towards the set which won more games.
+ */
+
+package komoku
+
+import (
+    "fmt"
+    "math"
+    "os"
+    "rand"
+)
+
+// ################################################################################
+// ########################### Tuner struct #######################################
+// ################################################################################
+
+type Tuner struct {
+    params []*Parameter // the parameters which are tuned
+    theta []float // the current estimate of each parameter, scaled to [0,1] over its range
+    // The SPSA gain sequences are a_k = 2 A C ((1+Stability)/(k+1+Stability))^Alpha and c_k = C/(k+1)^Gamma.
+    // A is thus the largest step of a parameter in the first iteration, as a fraction of its range, and C the
+    // perturbation, also as a fraction of the range.
+    A, C, Stability, Alpha, Gamma float
+    GamesPerIteration int // number of games between the two perturbed sets per iteration, should be even
+    Simulations int // simulations per move in the self-play games
+    BoardSize int
+    Komi float
+    game func(black, white []float) Color // plays a game between two parameter sets, see playGame
+    iteration int
+    rand *rand.Rand
+}
+
+// ##################### Tuner methods ##########################
+
+// Performs one SPSA iteration and returns the score of the positively perturbed set in [-1, 1],
+// i.e. (its wins - its losses) / games.
+func (t *Tuner) Iterate() float {
+    k := float(t.iteration)
+    ak := 2*t.A*t.C * float(math.Pow(float64((1 + t.Stability)/(k + 1 + t.Stability)), float64(t.Alpha)))
+    ck := t.C / float(math.Pow(float64(k + 1), float64(t.Gamma)))
+
+    delta := make([]float, len(t.params))
+    plus := make([]float, len(t.params))
+    minus := make([]float, len(t.params))
+    for i := range t.params {
+        delta[i] = 1.0
+        if t.rand.Intn(2) == 0 {
+            delta[i] = -1.0
+        }
+        plus[i] = clampUnit(t.theta[i] + ck*delta[i])
+        minus[i] = clampUnit(t.theta[i] - ck*delta[i])
+    }
+
+    score := 0
+    for g := 0; g < t.GamesPerIteration; g++ {
+        // the sets alternate colors
+        plusIsBlack := g % 2 == 0
+        var winner Color
+        if plusIsBlack {
+            winner = t.game(plus, minus)
+        } else {
+            winner = t.game(minus, plus)
+        }
+        if (winner == Black) == plusIsBlack {
+            score++
+        } else {
+            score--
+        }
+    }
+    result := float(score) / float(t.GamesPerIteration)
+
+    // the gradient estimate of the win rate is result / (2 ck delta_i); we go uphill
+    for i := range t.params {
+        t.theta[i] = clampUnit(t.theta[i] + ak*result/(2*ck*delta[i]))
+    }
+    t.iteration++
+    t.apply(t.theta)
+    return result
+}
+
+// Runs 'iterations' iterations and prints the progress to stdout.
+func (t *Tuner) Run(iterations int) {
+    for i := 0; i < iterations; i++ {
+        result := t.Iterate()
+        fmt.Printf("iteration %3d: score %+.2f,", t.iteration, result)
+        for _, p := range t.params {
+            fmt.Printf(" %s=%s", p.Name, p)
+        }
+        fmt.Println()
+    }
+}
+
+// Sets the parameters to the scaled values 'values'.
+func (t *Tuner) apply(values []float) {
+    for i, p := range t.params {
+        p.Set(p.Min + values[i]*(p.Max - p.Min))
+    }
+}
+
+// Plays a self-play game in which black uses the parameter set 'black' and white uses 'white' and
+// returns the winner. A jigo counts as a win for white.
+func (t *Tuner) playGame(black, white []float) Color {
+    ai := NewAI(t.BoardSize)
+    ai.environment.SetKomi(t.Komi)
+    board := ai.environment.Game.Board
+    maxMoves := 2*expectedGameLength(t.BoardSize, t.BoardSize)
+    lastPass := false
+    for moves := 0; moves < maxMoves; moves++ {
+        color := board.ColorOfNextPlay()
+        // The parameters are global, so the set of the player to move is applied before each move.
+        if color == Black {
+            t.apply(black)
+        } else {
+            t.apply(white)
+        }
+        v := ai.genMoveBySimulations(color, t.Simulations)
+        if v.Pass {
+            if lastPass {
+                break
+            }
+            lastPass = true
+        } else {
+            lastPass = false
+        }
+    }
+    t.apply(t.theta)
+    if wonBlack, _, _ := ai.scoreFinalPosition(board); wonBlack == 1 {
+        return Black
+    }
+    return White
+}
+
+// ##################### Tuner helper functions ##########################
+
+// Creates a tuner for 'params', starting from their current values. By default, a parameter moves by at most
+// a tenth of its range in the first iteration, and eight games are played per iteration, since the score of
+// fewer games is mostly noise. The defaults can be changed before the first iteration.
+func NewTuner(params []*Parameter) *Tuner {
+    sec, nsec, _ := os.Time()
+    t := &Tuner{
+        params: params,
+        theta: make([]float, len(params)),
+        A: 0.1,
+        C: 0.1,
+        Stability: 10.0,
+        Alpha: 0.602,
+        Gamma: 0.101,
+        GamesPerIteration: 8,
+        Simulations: 200,
+        BoardSize: DefaultBoardSize,
+        Komi: DefaultKomi,
+        rand: rand.New(rand.NewSource(sec+nsec)),
+    }
+    t.game = func(black, white []float) Color { return t.playGame(black, white) }
+    for i, p := range params {
+        if p.Max > p.Min {
+            t.theta[i] = (p.Float() - p.Min) / (p.Max - p.Min)
+        }
+    }
+    return t
+}
+
+func clampUnit(f float) float {
+    if f < 0 {
+        return 0
+    }
+    if f > 1 {
+        return 1
+    }
+    return f
+}
@@ -47,11 +47,11 @@ func printBoardPrimitive(b *Board,
     // print coordinates at the header
     line := leftOffset
     line += " "
-    if b.BoardSize() > 9 {
+    if b.Height() > 9 {
         line += " "
     }
-    for i := 0; i < b.BoardSize(); i++ {
-        char, err := DigitToChar(i)
+    for i := 0; i < b.Width(); i++ {
+        char, err := columnLabel(i, b.Width())
         if err != nil {
             fmt.Printf("Error in printBoardPrimitive. Error:\n%s\n", err)
             return
@@ -60,15 +60,15 @@ func printBoardPrimitive(b *Board,
     }
     s += line + "\n"
     // print the board
-    //for y := 0; y < b.BoardSize(); y++ {
-    for y := b.BoardSize()-1; y >= 0; y-- {
+    //for y := 0; y < b.Height(); y++ {
+    for y := b.Height()-1; y >= 0; y-- {
         lineNumber := fmt.Sprintf("%d", y+1)
-        if b.BoardSize() > 9 {
+        if b.Height() > 9 {
             lineNumber = fmt.Sprintf("%2d", y+1)
         }
         line = leftOffset + lineNumber
         rightSpace := ""
-        for x := 0; x < b.BoardSize(); x++ {
+        for x := 0; x < b.Width(); x++ {
             //fmt.Printf("(%d,%d)\n",x,y)
             //field := b.GetField(x,y)
             group := b.GetGroupByPoint(x,y)
@@ -84,7 +84,7 @@ func printBoardPrimitive(b *Board,
             if inMarks {
                 fieldChar = "!"
             } else if group == nil {
-                if isHoshi(x,y, b.BoardSize()) {
+                if b.Width() == b.Height() && isHoshi(x,y, b.Width()) {
                     fieldChar = "+"
                 } else {
                     fieldChar = "."
@@ -116,11 +116,11 @@ func printBoardPrimitive(b *Board,
     // and print the footer
     line = leftOffset
     line += " "
-    if b.BoardSize() > 9 {
+    if b.Height() > 9 {
         line += " "
     }
-    for i := 0; i < b.BoardSize(); i++ {
-        char, err := DigitToChar(i)
+    for i := 0; i < b.Width(); i++ {
+        char, err := columnLabel(i, b.Width())
         if err != nil {
             fmt.Printf("Error in printBoardPrimitive. Error:\n%s\n", err)
             return
@@ -131,6 +131,18 @@ func printBoardPrimitive(b *Board,
     return s
 }
 
+// Returns the label of the column 'x' on a board with 'width' columns: the letter of the GTP vertex, or the
+// SGF letter on boards which are too wide for GTP.
+func columnLabel(x, width int) (label string, err Error) {
+    if width <= len(coordinateChars) {
+        return DigitToChar(x)
+    }
+    if x < 0 || x >= len(sgfCoordinateChars) {
+        return "", NewInvalidCoordinateDigitError(x)
+    }
+    return sgfCoordinateChars[x:x+1], nil
+}
+
 // ###############################################################################
 // ####################### The interactive mode ##################################
 // ###############################################################################
@@ -8,12 +8,19 @@
 package main
 
 import (
+    "flag"
     "fmt"
+    "os"
     "runtime"
     "./komoku/komoku"
     //"time"
 )
 
+var temperature = flag.Float("temperature", 0.0, "draw the first moves with probability simulations^(1/temperature), 0 disables this")
+var temperatureMoves = flag.Int("tempmoves", 0, "the number of moves which are drawn with the temperature")
+var epsilon = flag.Float("epsilon", 0.0, "draw moves only among those within epsilon of the best winning percentage, 0 disables this")
+var rules = flag.String("rules", "default", "the ruleset: default (simple ko), chinese, japanese, aga, new_zealand or tromp-taylor")
+
 func testMain() {
     fmt.Printf("runtime.GOMAXPROCS: %d\n", runtime.GOMAXPROCS(0))
 
@@ -26,10 +33,22 @@ func testMain() {
 }
 
 func normalMain() {
-    komoku.RunGTPMode()
+    ai := komoku.NewAI(komoku.DefaultBoardSize)
+    ruleset, ok := komoku.RulesetByName(*rules)
+    if !ok {
+        fmt.Fprintf(os.Stderr, "unknown ruleset %s\n", *rules)
+        os.Exit(1)
+    }
+    ai.SetRules(ruleset)
+    if *temperature < 0 || *temperatureMoves < 0 || *epsilon < 0 {
+        fmt.Fprintf(os.Stderr, "the temperature, the number of temperature moves and epsilon must not be negative\n")
+        os.Exit(1)
+    }
+    ai.SetMoveSelection(*temperature, *temperatureMoves, *epsilon)
+    komoku.RunGTPMode(ai)
 }
 
 func main() {
-    testMain()
-    //komoku.RunGTPMode()
+    flag.Parse()
+    normalMain()
 }